@@ -1,16 +1,30 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
 	db "github.com/leoomi/simplebank/db/sqlc"
 	"github.com/leoomi/simplebank/token"
-	"github.com/lib/pq"
 )
 
+// accountResponse adds decimal renderings of the amounts of an account, so
+// that clients don't need to know the minor unit of its currency.
+type accountResponse struct {
+	db.Account
+	BalanceDecimal        string `json:"balanceDecimal"`
+	OverdraftLimitDecimal string `json:"overdraftLimitDecimal"`
+}
+
+func (s *Server) newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		Account:               account,
+		BalanceDecimal:        s.formatAmount(account.Balance, account.Currency),
+		OverdraftLimitDecimal: s.formatAmount(account.OverdraftLimit, account.Currency),
+	}
+}
+
 type createAccountRequest struct {
 	Currency string `json:"currency" binding:"required,currency"`
 }
@@ -18,30 +32,28 @@ type createAccountRequest struct {
 func (s *Server) createAccount(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !s.requireVerifiedEmail(ctx, authPayload.Username) {
+		return
+	}
+
 	arg := db.CreateAccountParams{
 		Owner:    authPayload.Username,
 		Currency: req.Currency,
 		Balance:  0,
 	}
 
-	account, err := s.store.CreateAccount(ctx, arg)
+	account, err := s.store.CreateAccountTx(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, s.newAccountResponse(account))
 }
 
 type getAccountRequest struct {
@@ -51,77 +63,128 @@ type getAccountRequest struct {
 func (s *Server) getAccount(ctx *gin.Context) {
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	account, err := s.store.GetAccount(ctx, req.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-		} else {
-			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		}
+	account, valid := s.getAuthorizedAccount(ctx, req.ID, auth.ActionReadAccount)
+	if !valid {
 		return
 	}
 
+	ctx.JSON(http.StatusOK, s.newAccountResponse(account))
+}
+
+// getAuthorizedAccount loads an account and makes sure the authenticated user
+// may perform act on it, writing the error response otherwise.
+func (s *Server) getAuthorizedAccount(ctx *gin.Context, accountID int64, act auth.Action) (db.Account, bool) {
+	account, valid := s.loadAccount(ctx, accountID)
+	if !valid {
+		return account, false
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if account.Owner != authPayload.Username {
-		err := errors.New("account doesn't belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	if err := auth.Authorize(authPayload, account.Owner, act); err != nil {
+		writeError(ctx, err)
+		return account, false
+	}
+
+	return account, true
+}
+
+// loadAccount loads an account, writing the error response when it cannot.
+func (s *Server) loadAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		writeError(ctx, notFound(err, errAccountNotFound))
+		return account, false
+	}
+
+	return account, true
+}
+
+type setOverdraftLimitRequest struct {
+	// how far below zero the balance may go; zero allows no overdraft
+	OverdraftLimit *int64 `json:"overdraftLimit" binding:"required,min=0"`
+}
+
+// setOverdraftLimit sets how far below zero the balance of an account may go.
+// Lowering it below what the account already owes only stops the account from
+// sending more. System accounts have no overdraft limit to set.
+func (s *Server) setOverdraftLimit(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	var req setOverdraftLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	account, valid := s.getAuthorizedAccount(ctx, uri.ID, auth.ActionManageLimits)
+	if !valid {
+		return
+	}
+
+	if account.Kind != db.AccountKindCustomer {
+		writeError(ctx, errSystemAccount)
+		return
+	}
+
+	account, err := s.store.UpdateAccountOverdraftLimit(ctx, db.UpdateAccountOverdraftLimitParams{
+		ID:             account.ID,
+		OverdraftLimit: *req.OverdraftLimit,
+	})
+	if err != nil {
+		writeError(ctx, notFound(err, errAccountNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, s.newAccountResponse(account))
 }
 
 type listAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	// lists the accounts of another user, which only some roles may do
+	Owner    string `form:"owner" binding:"omitempty,alphanum"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
 }
 
 func (s *Server) listAccounts(ctx *gin.Context) {
 	var req listAccountRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	owner := authPayload.Username
+	if len(req.Owner) > 0 {
+		owner = req.Owner
+	}
+	if err := auth.Authorize(authPayload, owner, auth.ActionReadAccount); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
 	arg := db.ListAccountsParams{
-		Owner:  authPayload.Username,
+		Owner:  owner,
 		Limit:  req.PageSize,
 		Offset: req.PageSize * (req.PageID - 1),
 	}
 	accounts, err := s.store.ListAccounts(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
-}
-
-type deleteAccountAccountRequest struct {
-	ID int64 `uri:"id" binding:"required,min=1"`
-}
-
-func (s *Server) deleteAccount(ctx *gin.Context) {
-	var req deleteAccountAccountRequest
-	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return
-	}
-
-	err := s.store.DeleteAccount(ctx, req.ID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-		} else {
-			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		}
-		return
+	res := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		res[i] = s.newAccountResponse(account)
 	}
 
-	ctx.Status(http.StatusOK)
+	ctx.JSON(http.StatusOK, res)
 }
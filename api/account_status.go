@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+)
+
+type changeAccountStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=active frozen closed"`
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+type changeAccountStatusResponse struct {
+	Account      accountResponse        `json:"account"`
+	StatusChange db.AccountStatusChange `json:"statusChange"`
+}
+
+// changeAccountStatus moves an account to another status, recording who did it
+// and why. Closing is only possible once the balance is zero, and system
+// accounts can't be moved at all.
+func (s *Server) changeAccountStatus(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req changeAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	account, valid := s.loadAccount(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := auth.Authorize(authPayload, account.Owner, statusChangeAction(account.Status, req.Status)); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if account.Kind != db.AccountKindCustomer {
+		writeError(ctx, errSystemAccount)
+		return
+	}
+
+	result, err := s.store.ChangeAccountStatusTx(ctx, db.ChangeAccountStatusTxParams{
+		AccountID:  account.ID,
+		FromStatus: account.Status,
+		Status:     req.Status,
+		ChangedBy:  authPayload.Username,
+		Reason:     req.Reason,
+	})
+	if err != nil {
+		writeError(ctx, notFound(err, errAccountNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changeAccountStatusResponse{
+		Account:      s.newAccountResponse(result.Account),
+		StatusChange: result.StatusChange,
+	})
+}
+
+// statusChangeAction is what the user must be allowed to do for an account to
+// go from one status to another. Owners may close their accounts, but only
+// those who may freeze an account can lift a freeze, close a frozen account or
+// reopen a closed one, which may have been frozen before it was closed.
+func statusChangeAction(from, to string) auth.Action {
+	if from == util.AccountStatusFrozen || to == util.AccountStatusFrozen || from == util.AccountStatusClosed {
+		return auth.ActionFreezeAccount
+	}
+
+	return auth.ActionCloseAccount
+}
+
+type listAccountStatusChangesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (s *Server) listAccountStatusChanges(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req listAccountStatusChangesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	account, valid := s.getAuthorizedAccount(ctx, uri.ID, auth.ActionReadAccount)
+	if !valid {
+		return
+	}
+
+	changes, err := s.store.ListAccountStatusChanges(ctx, db.ListAccountStatusChangesParams{
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    req.PageSize * (req.PageID - 1),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
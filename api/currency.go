@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+)
+
+// loadCurrencyRegistry reads the currencies from CURRENCIES_FILE when it is set
+// and from the currencies table otherwise. An empty table falls back to
+// util.DefaultCurrencies.
+func loadCurrencyRegistry(config util.Config, store db.Store) (*util.CurrencyRegistry, error) {
+	if len(config.CurrenciesFile) > 0 {
+		return util.LoadCurrencyRegistry(config.CurrenciesFile)
+	}
+
+	return db.LoadCurrencyRegistry(context.Background(), store)
+}
+
+// formatAmount renders an amount in minor units as a decimal string in the
+// given currency. Currencies missing from the registry are rendered as is.
+func (s *Server) formatAmount(amount int64, code string) string {
+	currency, ok := s.currencies.Lookup(code)
+	if !ok {
+		return strconv.FormatInt(amount, 10)
+	}
+
+	return currency.FormatAmount(amount)
+}
+
+func (s *Server) listCurrencies(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, s.currencies.List())
+}
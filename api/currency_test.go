@@ -0,0 +1,73 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCurrenciesAPI(t *testing.T) {
+	rows := []db.Currency{
+		{Code: util.USD, NumericCode: 840, MinorUnit: 2, Symbol: "$", Enabled: true},
+		{Code: "JPY", NumericCode: 392, MinorUnit: 0, Symbol: "¥", Enabled: false},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(rows, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	req, err := http.NewRequest(http.MethodGet, "/currencies", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var currencies []util.Currency
+	err = json.Unmarshal(recorder.Body.Bytes(), &currencies)
+	require.NoError(t, err)
+	require.Len(t, currencies, 2)
+	require.Equal(t, "JPY", currencies[0].Code)
+	require.False(t, currencies[0].Enabled)
+	require.Equal(t, util.USD, currencies[1].Code)
+
+	// disabled currencies are listed but not accepted by the validator
+	require.True(t, server.currencies.IsSupported(util.USD))
+	require.False(t, server.currencies.IsSupported("JPY"))
+}
+
+func TestLoadCurrencyRegistry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return([]db.Currency{}, nil)
+
+	registry, err := loadCurrencyRegistry(util.Config{}, store)
+	require.NoError(t, err)
+	require.Len(t, registry.List(), len(util.DefaultCurrencies))
+
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+
+	registry, err = loadCurrencyRegistry(util.Config{}, store)
+	require.Error(t, err)
+	require.Nil(t, registry)
+}
+
+func requireDecimalAmount(t *testing.T, amount int64, code string, decimal string) {
+	currency, ok := util.NewCurrencyRegistry(util.DefaultCurrencies).Lookup(code)
+	require.True(t, ok)
+	require.Equal(t, currency.FormatAmount(amount), decimal)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// swaggerInitializer replaces the one shipped with Swagger UI, which points at
+// the petstore example.
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+func (s *Server) getOpenAPI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json", s.openAPI)
+}
+
+// serveDocs serves the Swagger UI under /docs.
+func serveDocs() gin.HandlerFunc {
+	fileServer := http.StripPrefix("/docs", http.FileServer(http.FS(swaggerFiles.FS)))
+
+	return func(ctx *gin.Context) {
+		if ctx.Param("filepath") == "/swagger-initializer.js" {
+			ctx.Data(http.StatusOK, "application/javascript", []byte(swaggerInitializer))
+			return
+		}
+
+		fileServer.ServeHTTP(ctx.Writer, ctx.Request)
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+)
+
+type listAccountEntriesRequest struct {
+	FromTime  time.Time `form:"from_time"`
+	ToTime    time.Time `form:"to_time"`
+	Direction string    `form:"direction" binding:"omitempty,oneof=credit debit"`
+	PageID    int32     `form:"page_id" binding:"required,min=1"`
+	PageSize  int32     `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+type accountStatementResponse struct {
+	AccountID      int64                    `json:"accountID"`
+	Balance        int64                    `json:"balance"`
+	BalanceDecimal string                   `json:"balanceDecimal"`
+	Currency       string                   `json:"currency"`
+	Entries        []statementEntryResponse `json:"entries"`
+}
+
+type statementEntryResponse struct {
+	db.ListAccountStatementRow
+	AmountDecimal         string `json:"amountDecimal"`
+	RunningBalanceDecimal string `json:"runningBalanceDecimal"`
+}
+
+func (s *Server) listAccountEntries(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req listAccountEntriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if !req.FromTime.IsZero() && !req.ToTime.IsZero() && !req.FromTime.Before(req.ToTime) {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "from_time", Message: "must be before to_time"}))
+		return
+	}
+
+	account, valid := s.getAuthorizedAccount(ctx, uri.ID, auth.ActionReadAccount)
+	if !valid {
+		return
+	}
+
+	arg := db.ListAccountStatementParams{
+		AccountID:  account.ID,
+		FromTime:   sql.NullTime{Time: req.FromTime, Valid: !req.FromTime.IsZero()},
+		ToTime:     sql.NullTime{Time: req.ToTime, Valid: !req.ToTime.IsZero()},
+		Direction:  sql.NullString{String: req.Direction, Valid: len(req.Direction) > 0},
+		PageLimit:  req.PageSize,
+		PageOffset: req.PageSize * (req.PageID - 1),
+	}
+	entries, err := s.store.ListAccountStatement(ctx, arg)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	res := accountStatementResponse{
+		AccountID:      account.ID,
+		Balance:        account.Balance,
+		BalanceDecimal: s.formatAmount(account.Balance, account.Currency),
+		Currency:       account.Currency,
+		Entries:        make([]statementEntryResponse, len(entries)),
+	}
+	for i, entry := range entries {
+		res.Entries[i] = statementEntryResponse{
+			ListAccountStatementRow: entry,
+			AmountDecimal:           s.formatAmount(entry.Amount, account.Currency),
+			RunningBalanceDecimal:   s.formatAmount(entry.RunningBalance, account.Currency),
+		}
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+type getAccountBalanceRequest struct {
+	At time.Time `form:"at"`
+}
+
+type accountBalanceResponse struct {
+	AccountID      int64     `json:"accountID"`
+	Currency       string    `json:"currency"`
+	At             time.Time `json:"at"`
+	Balance        int64     `json:"balance"`
+	BalanceDecimal string    `json:"balanceDecimal"`
+}
+
+// getAccountBalance gets the balance an account had at a point in time, now
+// when none is given.
+func (s *Server) getAccountBalance(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req getAccountBalanceRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	now := time.Now()
+	if req.At.IsZero() {
+		req.At = now
+	} else if req.At.After(now) {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "at", Message: "must not be in the future"}))
+		return
+	}
+
+	account, valid := s.getAuthorizedAccount(ctx, uri.ID, auth.ActionReadAccount)
+	if !valid {
+		return
+	}
+
+	balance, err := s.store.GetAccountBalanceAt(ctx, db.GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        req.At,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountBalanceResponse{
+		AccountID:      account.ID,
+		Currency:       account.Currency,
+		At:             req.At,
+		Balance:        balance,
+		BalanceDecimal: s.formatAmount(balance, account.Currency),
+	})
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountEntriesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	n := 5
+	entries := make([]db.ListAccountStatementRow, n)
+	balance := account.Balance
+	for i := n - 1; i >= 0; i-- {
+		entries[i] = db.ListAccountStatementRow{
+			ID:             int64(i + 1),
+			AccountID:      account.ID,
+			Amount:         util.RandomInt(-100, 100),
+			RunningBalance: balance,
+		}
+		balance -= entries[i].Amount
+	}
+
+	fromTime := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	type query struct {
+		fromTime  string
+		toTime    string
+		direction string
+		pageID    int
+		pageSize  int
+	}
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		query         query
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     query{pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.ListAccountStatementParams{
+					AccountID:  account.ID,
+					PageLimit:  int32(n),
+					PageOffset: 0,
+				}
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStatement(t, recorder.Body, account, entries)
+			},
+		},
+		{
+			name:      "Filters",
+			accountID: account.ID,
+			query: query{
+				fromTime:  fromTime.Format(time.RFC3339),
+				toTime:    toTime.Format(time.RFC3339),
+				direction: "debit",
+				pageID:    2,
+				pageSize:  n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.ListAccountStatementParams{
+					AccountID:  account.ID,
+					FromTime:   sql.NullTime{Time: fromTime, Valid: true},
+					ToTime:     sql.NullTime{Time: toTime, Valid: true},
+					Direction:  sql.NullString{String: "debit", Valid: true},
+					PageLimit:  int32(n),
+					PageOffset: int32(n),
+				}
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidDirection",
+			accountID: account.ID,
+			query:     query{direction: "sideways", pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidTimeRange",
+			accountID: account.ID,
+			query: query{
+				fromTime: toTime.Format(time.RFC3339),
+				toTime:   fromTime.Format(time.RFC3339),
+				pageID:   1,
+				pageSize: n,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			query:     query{pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "UnauthorizedUser",
+			accountID: account.ID,
+			query:     query{pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			accountID: account.ID,
+			query:     query{pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					ListAccountStatement(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ListAccountStatementRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			params := url.Values{}
+			params.Add("page_id", fmt.Sprint(tc.query.pageID))
+			params.Add("page_size", fmt.Sprint(tc.query.pageSize))
+			if len(tc.query.fromTime) > 0 {
+				params.Add("from_time", tc.query.fromTime)
+			}
+			if len(tc.query.toTime) > 0 {
+				params.Add("to_time", tc.query.toTime)
+			}
+			if len(tc.query.direction) > 0 {
+				params.Add("direction", tc.query.direction)
+			}
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", tc.accountID, params.Encode())
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, req, server.tokenMaker)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetAccountBalanceAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	at := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	balance := util.RandomMoney()
+
+	testCases := []struct {
+		name          string
+		at            string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			at:       at.Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.GetAccountBalanceAtParams{
+					AccountID: account.ID,
+					At:        at,
+				}
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Eq(arg)).Times(1).Return(balance, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res accountBalanceResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, account.ID, res.AccountID)
+				require.Equal(t, account.Currency, res.Currency)
+				require.True(t, at.Equal(res.At))
+				require.Equal(t, balance, res.Balance)
+				requireDecimalAmount(t, balance, account.Currency, res.BalanceDecimal)
+			},
+		},
+		{
+			name:     "Now",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					GetAccountBalanceAt(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.GetAccountBalanceAtParams) (int64, error) {
+						require.WithinDuration(t, time.Now(), arg.At, time.Second)
+						return balance, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Future",
+			at:       time.Now().Add(time.Hour).Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "at", res.Fields[0].Field)
+			},
+		},
+		{
+			name:     "NotOwner",
+			at:       at.Format(time.RFC3339),
+			username: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+		{
+			name:     "InternalError",
+			at:       at.Format(time.RFC3339),
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusInternalServerError, "internal_error")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			params := url.Values{}
+			if len(tc.at) > 0 {
+				params.Add("at", tc.at)
+			}
+
+			url := fmt.Sprintf("/accounts/%d/balance?%s", account.ID, params.Encode())
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, req)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyMatchStatement(t *testing.T, body *bytes.Buffer, account db.Account, entries []db.ListAccountStatementRow) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var statement accountStatementResponse
+	err = json.Unmarshal(data, &statement)
+
+	require.NoError(t, err)
+	require.Equal(t, account.ID, statement.AccountID)
+	require.Equal(t, account.Balance, statement.Balance)
+	requireDecimalAmount(t, account.Balance, account.Currency, statement.BalanceDecimal)
+	require.Len(t, statement.Entries, len(entries))
+	for i, entry := range statement.Entries {
+		require.Equal(t, entries[i], entry.ListAccountStatementRow)
+		requireDecimalAmount(t, entry.Amount, account.Currency, entry.AmountDecimal)
+		requireDecimalAmount(t, entry.RunningBalance, account.Currency, entry.RunningBalanceDecimal)
+	}
+}
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/funding"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/transfers"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errorResponse is the body of every error response. Code is stable and meant
+// to be switched on by clients, Message is meant for humans.
+type errorResponse struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []fieldError `json:"fields,omitempty"`
+	status  int
+}
+
+// fieldError explains why a single field of the request was rejected. Field is
+// named as in the request, whether it came from the body, the query or the path.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func newErrorResponse(status int, code, message string) *errorResponse {
+	return &errorResponse{Code: code, Message: message, status: status}
+}
+
+func (e *errorResponse) Error() string {
+	return e.Message
+}
+
+// withMessage returns a copy of the error with a more specific message.
+func (e *errorResponse) withMessage(format string, args ...interface{}) *errorResponse {
+	res := *e
+	res.Message = fmt.Sprintf(format, args...)
+	return &res
+}
+
+// withFields returns a copy of the error listing the offending fields.
+func (e *errorResponse) withFields(fields ...fieldError) *errorResponse {
+	res := *e
+	res.Fields = fields
+	return &res
+}
+
+// The catalogue of errors reported by the API.
+var (
+	errInvalidRequest   = newErrorResponse(http.StatusBadRequest, "invalid_request", "request is invalid")
+	errMalformedRequest = newErrorResponse(http.StatusBadRequest, "malformed_request", "request could not be parsed")
+
+	errMissingAuthorization     = newErrorResponse(http.StatusUnauthorized, "missing_authorization", "authorization header not provided")
+	errInvalidAuthorization     = newErrorResponse(http.StatusUnauthorized, "invalid_authorization", "invalid authorization header provided")
+	errUnsupportedAuthorization = newErrorResponse(http.StatusUnauthorized, "unsupported_authorization_type", "unsupported authorization type provided")
+	errTokenExpired             = newErrorResponse(http.StatusUnauthorized, "token_expired", "token has expired")
+	errTokenInvalid             = newErrorResponse(http.StatusUnauthorized, "token_invalid", "token is invalid")
+	errTokenRevoked             = newErrorResponse(http.StatusUnauthorized, "token_revoked", "token has been revoked")
+	errInvalidCredentials       = newErrorResponse(http.StatusUnauthorized, "invalid_credentials", "incorrect username or password")
+	errSessionBlocked           = newErrorResponse(http.StatusUnauthorized, "session_blocked", "session is blocked")
+	errSessionMismatch          = newErrorResponse(http.StatusUnauthorized, "session_mismatch", "session doesn't match the refresh token")
+	errSessionExpired           = newErrorResponse(http.StatusUnauthorized, "session_expired", "session has expired")
+	errNotAuthorized            = newErrorResponse(http.StatusUnauthorized, "not_authorized", "the authenticated user is not allowed to do this")
+	errUnknownRole              = newErrorResponse(http.StatusUnauthorized, "unknown_role", "the role of the authenticated user is unknown")
+
+	errEmailNotVerified          = newErrorResponse(http.StatusForbidden, "email_not_verified", "email address must be verified first")
+	errUsernameTaken             = newErrorResponse(http.StatusForbidden, "username_taken", "username is already taken")
+	errEmailTaken                = newErrorResponse(http.StatusForbidden, "email_taken", "email is already registered")
+	errAccountExists             = newErrorResponse(http.StatusForbidden, "account_exists", "user already has an account in this currency")
+	errOwnerNotFound             = newErrorResponse(http.StatusForbidden, "owner_not_found", "account owner doesn't exist")
+	errConflict                  = newErrorResponse(http.StatusForbidden, "conflict", "request conflicts with existing data")
+	errNotFound                  = newErrorResponse(http.StatusNotFound, "not_found", "resource not found")
+	errUserNotFound              = newErrorResponse(http.StatusNotFound, "user_not_found", "user not found")
+	errSessionNotFound           = newErrorResponse(http.StatusNotFound, "session_not_found", "session not found")
+	errAccountNotFound           = newErrorResponse(http.StatusNotFound, "account_not_found", "account not found")
+	errTransferNotFound          = newErrorResponse(http.StatusNotFound, "transfer_not_found", "transfer not found")
+	errWebhookNotFound           = newErrorResponse(http.StatusNotFound, "webhook_not_found", "webhook not found")
+	errDeliveryNotFound          = newErrorResponse(http.StatusNotFound, "webhook_delivery_not_found", "webhook delivery not found")
+	errScheduledTransferNotFound = newErrorResponse(http.StatusNotFound, "scheduled_transfer_not_found", "scheduled transfer not found")
+	errTransferLimitNotFound     = newErrorResponse(http.StatusNotFound, "transfer_limit_not_found", "transfer limit not found")
+
+	errAccountCurrencyMismatch = newErrorResponse(http.StatusBadRequest, "account_currency_mismatch", "account currency doesn't match the request")
+	errCurrencyMismatch        = newErrorResponse(http.StatusUnprocessableEntity, "currency_mismatch", "accounts have different currencies and no exchange rate was given")
+	errInsufficientFunds       = newErrorResponse(http.StatusUnprocessableEntity, "insufficient_funds", "insufficient funds")
+	errExchangeRateNotFound    = newErrorResponse(http.StatusUnprocessableEntity, "exchange_rate_not_found", "exchange rate not found")
+	errAmountTooLow            = newErrorResponse(http.StatusUnprocessableEntity, "amount_too_low", "amount is too low to be converted")
+	errAmountTooHigh           = newErrorResponse(http.StatusUnprocessableEntity, "amount_too_high", "amount is too high to be converted")
+	errAccountNotActive        = newErrorResponse(http.StatusUnprocessableEntity, "account_not_active", "only active accounts can send or receive money")
+	errSystemAccount           = newErrorResponse(http.StatusUnprocessableEntity, "system_account", "system accounts are only used by the ledger itself")
+	errStatusTransition        = newErrorResponse(http.StatusUnprocessableEntity, "invalid_status_transition", "account cannot change to this status")
+	errBalanceNotZero          = newErrorResponse(http.StatusUnprocessableEntity, "balance_not_zero", "account balance must be zero to close it")
+	errStatusChanged           = newErrorResponse(http.StatusConflict, "account_status_changed", "account status changed in the meantime, try again")
+	errInvalidVerifyCode       = newErrorResponse(http.StatusUnprocessableEntity, "invalid_verification_code", "verification code is wrong, used or expired")
+	errEmailVerified           = newErrorResponse(http.StatusUnprocessableEntity, "email_already_verified", "email address is already verified")
+	errIdempotencyKeyReused    = newErrorResponse(http.StatusUnprocessableEntity, "idempotency_key_reused", "idempotency key was already used with a different request")
+	errTransferIsReversal      = newErrorResponse(http.StatusUnprocessableEntity, "transfer_is_reversal", "a reversal cannot be reversed")
+	errTransferReversed        = newErrorResponse(http.StatusUnprocessableEntity, "transfer_already_reversed", "transfer is already fully reversed")
+	errReversalTooHigh         = newErrorResponse(http.StatusUnprocessableEntity, "reversal_too_high", "amount is more than is left to reverse of the transfer")
+	errReversalTooLow          = newErrorResponse(http.StatusUnprocessableEntity, "reversal_too_low", "amount is too low to be given back in the currency it was received in")
+	errTransferIsFunding       = newErrorResponse(http.StatusUnprocessableEntity, "transfer_is_funding", "deposits and withdrawals cannot be reversed")
+	errFundingPending          = newErrorResponse(http.StatusAccepted, "funding_pending", "the funding gateway did not confirm the payment yet; it is settled once it does")
+	errFundingDeclined         = newErrorResponse(http.StatusUnprocessableEntity, "funding_declined", "payment was declined by the funding gateway")
+	errTransferLimitExceeded   = newErrorResponse(http.StatusUnprocessableEntity, "transfer_limit_exceeded", "transfer is larger than allowed")
+	errDailyLimitExceeded      = newErrorResponse(http.StatusUnprocessableEntity, "daily_limit_exceeded", "transfer goes over the daily limit")
+	errMonthlyLimitExceeded    = newErrorResponse(http.StatusUnprocessableEntity, "monthly_limit_exceeded", "transfer goes over the monthly limit")
+
+	errVerifyEmailTooSoon = newErrorResponse(http.StatusTooManyRequests, "verification_code_too_soon", "a verification code was sent too recently, try again later")
+
+	errInternal = newErrorResponse(http.StatusInternalServerError, "internal_error", "internal server error")
+)
+
+// constraintErrors names the errors reported when an insert or update breaks a
+// database constraint.
+var constraintErrors = map[string]*errorResponse{
+	"users_pkey":                 errUsernameTaken,
+	"users_email_key":            errEmailTaken,
+	"owner_currency_key":         errAccountExists,
+	"accounts_owner_fkey":        errOwnerNotFound,
+	"transfer_limits_owner_fkey": errOwnerNotFound,
+}
+
+// notFound reports a missing row as res, leaving other errors alone.
+func notFound(err error, res *errorResponse) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return res
+	}
+	return err
+}
+
+// toErrorResponse translates err into the error reported to the client. It is
+// the one place where errors of the store, the database driver, the token maker
+// and the validator are mapped to the catalogue. Anything it doesn't recognise
+// is an internal error, whose details are never sent to the client.
+func toErrorResponse(err error) *errorResponse {
+	var res *errorResponse
+	if errors.As(err, &res) {
+		return res
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]fieldError, len(validationErrs))
+		for i, fieldErr := range validationErrs {
+			fields[i] = fieldError{Field: fieldErr.Field(), Message: validationMessage(fieldErr)}
+		}
+		return errInvalidRequest.withFields(fields...)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return errInvalidRequest.withFields(fieldError{
+			Field:   typeErr.Field,
+			Message: "must be " + describeType(typeErr.Type),
+		})
+	}
+
+	var syntaxErr *json.SyntaxError
+	var numErr *strconv.NumError
+	var timeErr *time.ParseError
+	if errors.As(err, &syntaxErr) || errors.As(err, &numErr) || errors.As(err, &timeErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errMalformedRequest
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation", "foreign_key_violation":
+			if res, ok := constraintErrors[pqErr.Constraint]; ok {
+				return res
+			}
+			return errConflict
+		}
+		return errInternal
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errNotFound
+	case errors.Is(err, token.ErrExpiredToken):
+		return errTokenExpired
+	case errors.Is(err, token.ErrInvalidToken):
+		return errTokenInvalid
+	case errors.Is(err, auth.ErrNotAuthorized):
+		return errNotAuthorized
+	case errors.Is(err, auth.ErrUnknownRole):
+		return errUnknownRole
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return errInvalidCredentials
+	case errors.Is(err, bcrypt.ErrPasswordTooLong):
+		return errInvalidRequest.withFields(fieldError{Field: "password", Message: "must have at most 72 bytes"})
+	case errors.Is(err, db.ErrInsufficientFunds):
+		return errInsufficientFunds
+	case errors.Is(err, db.ErrCurrencyMismatch):
+		return errCurrencyMismatch
+	case errors.Is(err, db.ErrAccountNotActive):
+		return errAccountNotActive
+	case errors.Is(err, db.ErrSystemAccount):
+		return errSystemAccount
+	case errors.Is(err, db.ErrStatusTransition):
+		return errStatusTransition.withMessage("%s", err)
+	case errors.Is(err, db.ErrBalanceNotZero):
+		return errBalanceNotZero
+	case errors.Is(err, db.ErrEmailVerified):
+		return errEmailVerified
+	case errors.Is(err, db.ErrVerifyEmailTooSoon):
+		return errVerifyEmailTooSoon
+	case errors.Is(err, db.ErrStatusChanged):
+		return errStatusChanged
+	case errors.Is(err, db.ErrIdempotencyKeyInUse):
+		return errIdempotencyKeyReused
+	case errors.Is(err, db.ErrTransferIsReversal):
+		return errTransferIsReversal
+	case errors.Is(err, db.ErrTransferReversed):
+		return errTransferReversed
+	case errors.Is(err, db.ErrReversalTooHigh):
+		return errReversalTooHigh.withMessage("%s", err)
+	case errors.Is(err, db.ErrReversalTooLow):
+		return errReversalTooLow
+	case errors.Is(err, db.ErrTransferIsFunding):
+		return errTransferIsFunding
+	case errors.Is(err, db.ErrTransferLimitExceeded):
+		return errTransferLimitExceeded.withMessage("%s", err)
+	case errors.Is(err, db.ErrDailyLimitExceeded):
+		return errDailyLimitExceeded.withMessage("%s", err)
+	case errors.Is(err, db.ErrMonthlyLimitExceeded):
+		return errMonthlyLimitExceeded.withMessage("%s", err)
+	case errors.Is(err, funding.ErrDeclined):
+		return errFundingDeclined
+	case errors.Is(err, db.ErrFundingPending):
+		return errFundingPending
+	case errors.Is(err, transfers.ErrAccountNotFound):
+		return errAccountNotFound
+	case errors.Is(err, transfers.ErrAccountCurrencyMismatch):
+		return errAccountCurrencyMismatch.withMessage("%s", err)
+	case errors.Is(err, transfers.ErrIdempotencyKeyTooLong):
+		return errInvalidRequest.withFields(fieldError{
+			Field:   idempotencyKeyHeader,
+			Message: fmt.Sprintf("must have at most %d characters", transfers.MaxIdempotencyKeyLength),
+		})
+	case errors.Is(err, transfers.ErrIdempotencyKeyReused):
+		return errIdempotencyKeyReused
+	case errors.Is(err, exchange.ErrRateNotFound):
+		return errExchangeRateNotFound
+	case errors.Is(err, exchange.ErrAmountTooLow):
+		return errAmountTooLow
+	case errors.Is(err, exchange.ErrAmountTooHigh):
+		return errAmountTooHigh
+	}
+
+	return errInternal
+}
+
+// writeError aborts the request with the response err translates to. The error
+// itself is attached to the context, so that the logger still records it.
+func writeError(ctx *gin.Context, err error) {
+	res := toErrorResponse(err)
+	ctx.Error(err)
+	ctx.AbortWithStatusJSON(res.status, res)
+}
+
+// validationMessage describes a failed binding rule in words.
+func validationMessage(fieldErr validator.FieldError) string {
+	isString := fieldErr.Kind() == reflect.String
+	isSlice := fieldErr.Kind() == reflect.Slice
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must have at least %s characters", fieldErr.Param())
+		}
+		if isSlice {
+			return fmt.Sprintf("must have at least %s items", fieldErr.Param())
+		}
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		if isString {
+			return fmt.Sprintf("must have at most %s characters", fieldErr.Param())
+		}
+		if isSlice {
+			return fmt.Sprintf("must have at most %s items", fieldErr.Param())
+		}
+		return "must be at most " + fieldErr.Param()
+	case "len":
+		if isString {
+			return fmt.Sprintf("must have exactly %s characters", fieldErr.Param())
+		}
+		return "must have " + fieldErr.Param() + " items"
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fieldErr.Param()), ", ")
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "currency":
+		return "is not a supported currency"
+	case "cron":
+		return "must be a cron expression"
+	}
+
+	return "is invalid"
+}
+
+// describeType names the JSON type a Go type is decoded from.
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+
+	return "an object"
+}
+
+// requestFieldName names struct fields after the key they are bound from, so
+// that validation errors refer to the request rather than to Go identifiers.
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if len(name) > 0 {
+			return name
+		}
+	}
+
+	return field.Name
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestToErrorResponse(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{
+			name:   "Catalogue",
+			err:    errInsufficientFunds,
+			status: http.StatusUnprocessableEntity,
+			code:   "insufficient_funds",
+		},
+		{
+			name:   "NoRows",
+			err:    sql.ErrNoRows,
+			status: http.StatusNotFound,
+			code:   "not_found",
+		},
+		{
+			name:   "NoRowsOfAccount",
+			err:    notFound(sql.ErrNoRows, errAccountNotFound),
+			status: http.StatusNotFound,
+			code:   "account_not_found",
+		},
+		{
+			name:   "ExpiredToken",
+			err:    fmt.Errorf("cannot verify: %w", token.ErrExpiredToken),
+			status: http.StatusUnauthorized,
+			code:   "token_expired",
+		},
+		{
+			name:   "InvalidToken",
+			err:    token.ErrInvalidToken,
+			status: http.StatusUnauthorized,
+			code:   "token_invalid",
+		},
+		{
+			name:   "IncorrectPassword",
+			err:    bcrypt.ErrMismatchedHashAndPassword,
+			status: http.StatusUnauthorized,
+			code:   "invalid_credentials",
+		},
+		{
+			name:   "DuplicateEmail",
+			err:    &pq.Error{Code: "23505", Constraint: "users_email_key"},
+			status: http.StatusForbidden,
+			code:   "email_taken",
+		},
+		{
+			name:   "UnknownConstraint",
+			err:    &pq.Error{Code: "23503", Constraint: "entries_account_id_fkey"},
+			status: http.StatusForbidden,
+			code:   "conflict",
+		},
+		{
+			name:   "OtherDatabaseError",
+			err:    &pq.Error{Code: "40001", Message: "could not serialize access"},
+			status: http.StatusInternalServerError,
+			code:   "internal_error",
+		},
+		{
+			name:   "CurrencyMismatch",
+			err:    fmt.Errorf("transfer tx: %w", db.ErrCurrencyMismatch),
+			status: http.StatusUnprocessableEntity,
+			code:   "currency_mismatch",
+		},
+		{
+			name:   "MonthlyLimitExceeded",
+			err:    fmt.Errorf("transfer tx: %w", db.ErrMonthlyLimitExceeded),
+			status: http.StatusUnprocessableEntity,
+			code:   "monthly_limit_exceeded",
+		},
+		{
+			name:   "RateNotFound",
+			err:    exchange.ErrRateNotFound,
+			status: http.StatusUnprocessableEntity,
+			code:   "exchange_rate_not_found",
+		},
+		{
+			name:   "Unknown",
+			err:    errors.New("dial tcp 10.0.0.1:5432: connection refused"),
+			status: http.StatusInternalServerError,
+			code:   "internal_error",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			res := toErrorResponse(tc.err)
+			require.Equal(t, tc.status, res.status)
+			require.Equal(t, tc.code, res.Code)
+			require.NotContains(t, res.Message, "5432")
+		})
+	}
+}
+
+func TestErrorResponseAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "ValidationErrors",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body: gin.H{
+				"toAccountID": account.ID,
+				"amount":      0,
+				"currency":    "XYZ",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{
+					{Field: "fromAccountID", Message: "is required"},
+					{Field: "amount", Message: "is required"},
+					{Field: "currency", Message: "is not a supported currency"},
+				}, res.Fields)
+			},
+		},
+		{
+			name:   "WrongType",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body: gin.H{
+				"fromAccountID": "one",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "fromAccountID", Message: "must be an integer"}}, res.Fields)
+			},
+		},
+		{
+			name:   "MissingAuthorization",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "missing_authorization")
+			},
+		},
+		{
+			name:   "AccountNotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusNotFound, "account_not_found")
+			},
+		},
+		{
+			name:   "InternalDetailsHidden",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusInternalServerError, "internal_error")
+				require.NotContains(t, res.Message, "sql")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body bytes.Buffer
+			if tc.body != nil {
+				require.NoError(t, json.NewEncoder(&body).Encode(tc.body))
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, &body)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireErrorCode(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) errorResponse {
+	require.Equal(t, status, recorder.Code)
+
+	var res errorResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &res)
+	require.NoError(t, err)
+	require.Equal(t, code, res.Code)
+	require.NotEmpty(t, res.Message)
+	return res
+}
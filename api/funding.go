@@ -0,0 +1,99 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/funding"
+	"github.com/leoomi/simplebank/token"
+)
+
+type fundingRequest struct {
+	Amount int64 `json:"amount" binding:"required,gt=0"`
+}
+
+// fundingRecordResponse renders a db.Funding with its nullable fields left out
+// until they are set.
+type fundingRecordResponse struct {
+	db.Funding
+	// transfer that moved the money, absent until there is one
+	TransferID *int64 `json:"transferID,omitempty"`
+	// what the gateway calls the payment, absent until it is settled
+	Reference string `json:"reference,omitempty"`
+}
+
+func newFundingRecordResponse(funding db.Funding) fundingRecordResponse {
+	return fundingRecordResponse{
+		Funding:    funding,
+		TransferID: nullableID(funding.TransferID),
+		Reference:  funding.Reference.String,
+	}
+}
+
+type fundingResponse struct {
+	Funding  fundingRecordResponse `json:"funding"`
+	Transfer transferResponse      `json:"transfer"`
+	Account  accountResponse       `json:"account"`
+	Entry    entryResponse         `json:"entry"`
+}
+
+// depositFunds pays money into an account through the funding gateway.
+func (s *Server) depositFunds(ctx *gin.Context) {
+	s.fundAccount(ctx, db.FundingDeposit)
+}
+
+// withdrawFunds pays money out of an account through the funding gateway.
+func (s *Server) withdrawFunds(ctx *gin.Context) {
+	s.fundAccount(ctx, db.FundingWithdrawal)
+}
+
+// fundAccount moves money between an account of the user and the outside
+// world. The gateway is asked to settle the payment only once the funding is
+// recorded, so a withdrawal without the funds for it is never paid out.
+// When the gateway doesn't answer, the funding is accepted but stays pending
+// until the funding sweeper learns what became of the payment.
+func (s *Server) fundAccount(ctx *gin.Context, kind string) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req fundingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !s.requireVerifiedEmail(ctx, authPayload.Username) {
+		return
+	}
+
+	account, valid := s.getAuthorizedAccount(ctx, uri.ID, auth.ActionMoveFunds)
+	if !valid {
+		return
+	}
+
+	result, err := s.store.FundingTx(ctx, db.FundingTxParams{
+		AccountID: account.ID,
+		Amount:    req.Amount,
+		Kind:      kind,
+		Settle: func(pending db.Funding) (string, error) {
+			return funding.Settle(s.gateway, pending, account.Currency)
+		},
+	})
+	if err != nil {
+		writeError(ctx, notFound(err, errAccountNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, fundingResponse{
+		Funding:  newFundingRecordResponse(result.Funding),
+		Transfer: s.newTransferResponse(result.Transfer, account.Currency, account.Currency),
+		Account:  s.newAccountResponse(result.Account),
+		Entry:    s.newEntryResponse(result.Entry, account.Currency),
+	})
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/funding"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFundingAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	clearingID := util.RandomInt(1001, 2000)
+
+	// settle stubs FundingTx to record the funding, settle it with the gateway
+	// and make the transfer, like the store does
+	settle := func(store *mockdb.MockStore, kind string, amount int64) {
+		store.EXPECT().
+			FundingTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.FundingTxParams) (db.FundingTxResult, error) {
+				require.Equal(t, account.ID, arg.AccountID)
+				require.Equal(t, amount, arg.Amount)
+				require.Equal(t, kind, arg.Kind)
+
+				pending := db.Funding{ID: util.RandomInt(1, 1000), AccountID: account.ID, Amount: amount, Kind: kind, Status: db.FundingPending}
+				reference, err := arg.Settle(pending)
+				if err != nil {
+					return db.FundingTxResult{}, err
+				}
+
+				transfer := db.Transfer{ID: util.RandomInt(1, 1000), FromAccountID: clearingID, ToAccountID: account.ID, Amount: amount, ToAmount: amount}
+				if kind == db.FundingWithdrawal {
+					transfer.FromAccountID, transfer.ToAccountID = account.ID, clearingID
+				}
+
+				settled := pending
+				settled.Status = db.FundingSettled
+				settled.Reference = sql.NullString{String: reference, Valid: true}
+				settled.TransferID = sql.NullInt64{Int64: transfer.ID, Valid: true}
+
+				return db.FundingTxResult{
+					Funding:  settled,
+					Transfer: transfer,
+					Account:  account,
+					Entry:    db.Entry{AccountID: account.ID, TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true}},
+				}, nil
+			})
+	}
+
+	testCases := []struct {
+		name          string
+		path          string
+		body          gin.H
+		username      string
+		gatewayErr    error
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway)
+	}{
+		{
+			name:     "Deposit",
+			path:     "deposits",
+			body:     gin.H{"amount": 500},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				settle(store, db.FundingDeposit, 500)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res struct {
+					Funding struct {
+						ID         int64  `json:"id"`
+						Kind       string `json:"kind"`
+						Status     string `json:"status"`
+						Reference  string `json:"reference"`
+						TransferID int64  `json:"transferID"`
+					} `json:"funding"`
+					Entry struct {
+						TransferID *int64 `json:"transferID"`
+					} `json:"entry"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, db.FundingDeposit, res.Funding.Kind)
+				require.Equal(t, db.FundingSettled, res.Funding.Status)
+				require.Equal(t, "fake_deposit_1", res.Funding.Reference)
+				require.NotNil(t, res.Entry.TransferID)
+				require.Equal(t, res.Funding.TransferID, *res.Entry.TransferID)
+
+				deposits := gateway.Deposits()
+				require.Len(t, deposits, 1)
+				require.Equal(t, account.Currency, deposits[0].Currency)
+				require.Equal(t, fmt.Sprint(res.Funding.ID), deposits[0].Reference)
+				require.Empty(t, gateway.Withdrawals())
+			},
+		},
+		{
+			name:     "Withdrawal",
+			path:     "withdrawals",
+			body:     gin.H{"amount": 300},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				settle(store, db.FundingWithdrawal, 300)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, gateway.Withdrawals(), 1)
+				require.Empty(t, gateway.Deposits())
+			},
+		},
+		{
+			name:     "InsufficientFunds",
+			path:     "withdrawals",
+			body:     gin.H{"amount": 300},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().FundingTx(gomock.Any(), gomock.Any()).Times(1).Return(db.FundingTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				requireErrorCode(t, recorder, http.StatusUnprocessableEntity, "insufficient_funds")
+				require.Empty(t, gateway.Withdrawals())
+			},
+		},
+		{
+			name:     "TransferLimitExceeded",
+			path:     "withdrawals",
+			body:     gin.H{"amount": 300},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().FundingTx(gomock.Any(), gomock.Any()).Times(1).Return(db.FundingTxResult{}, db.ErrTransferLimitExceeded)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				requireErrorCode(t, recorder, http.StatusUnprocessableEntity, "transfer_limit_exceeded")
+				require.Empty(t, gateway.Withdrawals())
+			},
+		},
+		{
+			name:     "GatewayUnavailable",
+			path:     "withdrawals",
+			body:     gin.H{"amount": 300},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().FundingTx(gomock.Any(), gomock.Any()).Times(1).Return(db.FundingTxResult{}, db.ErrFundingPending)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				requireErrorCode(t, recorder, http.StatusAccepted, "funding_pending")
+			},
+		},
+		{
+			name:       "Declined",
+			path:       "deposits",
+			body:       gin.H{"amount": 500},
+			username:   user.Username,
+			gatewayErr: funding.ErrDeclined,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				settle(store, db.FundingDeposit, 500)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				requireErrorCode(t, recorder, http.StatusUnprocessableEntity, "funding_declined")
+			},
+		},
+		{
+			name:     "NotOwner",
+			path:     "deposits",
+			body:     gin.H{"amount": 500},
+			username: util.RandomOwner(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().FundingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+		{
+			name:     "AccountNotFound",
+			path:     "deposits",
+			body:     gin.H{"amount": 500},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().FundingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				requireErrorCode(t, recorder, http.StatusNotFound, "account_not_found")
+			},
+		},
+		{
+			name:     "InvalidAmount",
+			path:     "withdrawals",
+			body:     gin.H{"amount": 0},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().FundingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, gateway *funding.FakeGateway) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "amount", res.Fields[0].Field)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubVerifiedUsers(store)
+			tc.buildStubs(store)
+
+			gateway := funding.NewFakeGateway()
+			gateway.Err = tc.gatewayErr
+
+			server := newTestServer(t, store)
+			server.gateway = gateway
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/%s", account.ID, tc.path)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, gateway)
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package api
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/leoomi/simplebank/auth"
+	mockdb "github.com/leoomi/simplebank/db/mock"
 	db "github.com/leoomi/simplebank/db/sqlc"
 	"github.com/leoomi/simplebank/util"
 	"github.com/stretchr/testify/require"
@@ -13,16 +17,60 @@ import (
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:         util.RandomString(32),
+		AccessTokenDuration:       time.Minute,
+		RefreshTokenDuration:      time.Hour,
+		IdempotencyKeyTTL:         time.Hour,
+		RevocationCacheTTL:        time.Minute,
+		VerifyEmailDuration:       15 * time.Minute,
+		VerifyEmailResendInterval: time.Minute,
 	}
 
-	server, err := NewServer(config, store)
+	// authenticated requests consult the revocation list and the server loads
+	// its currencies on start; tests that care about either register their own
+	// expectation before creating the server
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().
+			IsTokenRevoked(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(false, nil)
+		mockStore.EXPECT().
+			ListCurrencies(gomock.Any()).
+			AnyTimes().
+			Return(defaultCurrencyRows(), nil)
+	}
+
+	server, err := NewServer(config, store, auth.NewRevocationList(store, config.RevocationCacheTTL))
 	require.NoError(t, err)
 
 	return server
 }
 
+// stubVerifiedUsers makes every user look up return a user whose email address
+// is verified, for tests of routes that only serve such users.
+func stubVerifiedUsers(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, IsEmailVerified: true}, nil
+		})
+}
+
+func defaultCurrencyRows() []db.Currency {
+	rows := make([]db.Currency, len(util.DefaultCurrencies))
+	for i, currency := range util.DefaultCurrencies {
+		rows[i] = db.Currency{
+			Code:        currency.Code,
+			NumericCode: currency.NumericCode,
+			MinorUnit:   currency.MinorUnit,
+			Symbol:      currency.Symbol,
+			Enabled:     currency.Enabled,
+		}
+	}
+	return rows
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
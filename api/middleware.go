@@ -1,12 +1,10 @@
 package api
 
 import (
-	"errors"
-	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
 	"github.com/leoomi/simplebank/token"
 )
 
@@ -16,33 +14,40 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, revocations *auth.RevocationList) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
-			err := errors.New("authorization header not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, errMissingAuthorization)
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
-			err := errors.New("invalid authorization header provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, errInvalidAuthorization)
 			return
 		}
 
 		authorizationType := strings.ToLower(fields[0])
 		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("unsupported authorization type provided %s", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, errUnsupportedAuthorization.withMessage("unsupported authorization type provided %s", authorizationType))
 			return
 		}
 
 		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccess)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			writeError(ctx, err)
+			return
+		}
+
+		revoked, err := revocations.IsRevoked(ctx, payload)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		if revoked {
+			writeError(ctx, errTokenRevoked)
 			return
 		}
 
@@ -0,0 +1,746 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/transfers"
+	"github.com/leoomi/simplebank/util"
+)
+
+// openAPIOperation documents one of the routes registered in setupRouter. The
+// request and response schemas are derived from the structs the handler binds
+// and writes, including their binding rules, so they can't drift apart.
+type openAPIOperation struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	public      bool
+	// zero values of the structs bound from the path, the query and the body
+	uri   interface{}
+	query interface{}
+	body  interface{}
+	// body may be left out entirely
+	optionalBody bool
+	headers      []openAPIHeader
+	// zero value of what is written on success, nil when there is no body
+	response      interface{}
+	successStatus int
+	errorStatuses []int
+}
+
+type openAPIHeader struct {
+	name        string
+	description string
+}
+
+var openAPIOperations = []openAPIOperation{
+	{
+		method:        http.MethodPost,
+		path:          "/users",
+		operationID:   "createUser",
+		summary:       "Sign up a new user",
+		public:        true,
+		body:          createUserRequest{},
+		response:      userResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/login",
+		operationID:   "loginUser",
+		summary:       "Log in and open a session",
+		public:        true,
+		body:          loginUserRequest{},
+		response:      loginUserResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/users/verify_email",
+		operationID:   "verifyEmail",
+		summary:       "Verify the email address of a user with the code sent on signup",
+		public:        true,
+		query:         verifyEmailRequest{},
+		response:      verifyEmailResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/tokens/renew_access",
+		operationID:   "renewAccessToken",
+		summary:       "Issue a new access token from a refresh token",
+		public:        true,
+		body:          renewAccessTokenRequest{},
+		response:      renewAccessTokenResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:      http.MethodGet,
+		path:        "/currencies",
+		operationID: "listCurrencies",
+		summary:     "List the currencies known to the bank",
+		public:      true,
+		response:    []util.Currency{},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/logout",
+		operationID:   "logoutUser",
+		summary:       "Revoke the access token and optionally the session of a refresh token",
+		body:          logoutUserRequest{},
+		optionalBody:  true,
+		successStatus: http.StatusNoContent,
+		errorStatuses: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/logout_all",
+		operationID:   "logoutAllUser",
+		summary:       "Revoke every token and session of the user",
+		successStatus: http.StatusNoContent,
+		errorStatuses: []int{http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/users/verify_email/resend",
+		operationID:   "resendVerifyEmail",
+		summary:       "Send the user a new code that verifies their email address",
+		successStatus: http.StatusNoContent,
+		errorStatuses: []int{http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/accounts",
+		operationID:   "createAccount",
+		summary:       "Open an account for the user",
+		body:          createAccountRequest{},
+		response:      accountResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/accounts/:id",
+		operationID:   "getAccount",
+		summary:       "Get an account",
+		uri:           getAccountRequest{},
+		response:      accountResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/accounts/:id/entries",
+		operationID:   "listAccountEntries",
+		summary:       "Get the statement of an account with running balances",
+		uri:           getAccountRequest{},
+		query:         listAccountEntriesRequest{},
+		response:      accountStatementResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/accounts/:id/balance",
+		operationID:   "getAccountBalance",
+		summary:       "Get the balance of an account at a point in time",
+		uri:           getAccountRequest{},
+		query:         getAccountBalanceRequest{},
+		response:      accountBalanceResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/accounts",
+		operationID:   "listAccounts",
+		summary:       "List the accounts of a user",
+		query:         listAccountRequest{},
+		response:      []accountResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPut,
+		path:          "/accounts/:id/status",
+		operationID:   "changeAccountStatus",
+		summary:       "Freeze, unfreeze, close or reopen an account",
+		uri:           getAccountRequest{},
+		body:          changeAccountStatusRequest{},
+		response:      changeAccountStatusResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/accounts/:id/status_changes",
+		operationID:   "listAccountStatusChanges",
+		summary:       "List who changed the status of an account and why, newest first",
+		uri:           getAccountRequest{},
+		query:         listAccountStatusChangesRequest{},
+		response:      []db.AccountStatusChange{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPut,
+		path:          "/accounts/:id/overdraft_limit",
+		operationID:   "setOverdraftLimit",
+		summary:       "Set how far below zero the balance of an account may go",
+		uri:           getAccountRequest{},
+		body:          setOverdraftLimitRequest{},
+		response:      accountResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	{
+		method:      http.MethodPost,
+		path:        "/accounts/:id/deposits",
+		operationID: "depositFunds",
+		summary:     "Pay money into an account of the user through the funding gateway",
+		uri:         getAccountRequest{},
+		body:        fundingRequest{},
+		response:    fundingResponse{},
+		errorStatuses: []int{
+			http.StatusAccepted, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+			http.StatusUnprocessableEntity, http.StatusInternalServerError,
+		},
+	},
+	{
+		method:      http.MethodPost,
+		path:        "/accounts/:id/withdrawals",
+		operationID: "withdrawFunds",
+		summary:     "Pay money out of an account of the user through the funding gateway",
+		uri:         getAccountRequest{},
+		body:        fundingRequest{},
+		response:    fundingResponse{},
+		errorStatuses: []int{
+			http.StatusAccepted, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+			http.StatusUnprocessableEntity, http.StatusInternalServerError,
+		},
+	},
+	{
+		method:      http.MethodPost,
+		path:        "/transfers",
+		operationID: "createTransfer",
+		summary:     "Move money between two accounts",
+		body:        transferRequest{},
+		headers: []openAPIHeader{
+			{
+				name:        idempotencyKeyHeader,
+				description: "Retries with the same key and body return the original result",
+			},
+		},
+		response: transferTxResponse{},
+		errorStatuses: []int{
+			http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity,
+			http.StatusInternalServerError,
+		},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/transfers",
+		operationID:   "listTransfers",
+		summary:       "List the transfers touching the accounts of the user",
+		query:         listTransfersRequest{},
+		response:      listTransfersResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/transfers/:id",
+		operationID:   "getTransfer",
+		summary:       "Get a transfer",
+		uri:           getTransferRequest{},
+		response:      transferResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/transfers/:id/reversals",
+		operationID:   "reverseTransfer",
+		summary:       "Give back a transfer received by an account of the user, in full or in part",
+		uri:           getTransferRequest{},
+		body:          reverseTransferRequest{},
+		optionalBody:  true,
+		response:      transferTxResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/scheduled_transfers",
+		operationID:   "createScheduledTransfer",
+		summary:       "Schedule a one-off or recurring transfer from an account of the user",
+		body:          createScheduledTransferRequest{},
+		response:      db.ScheduledTransfer{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/scheduled_transfers",
+		operationID:   "listScheduledTransfers",
+		summary:       "List the scheduled transfers of the user",
+		query:         listScheduledTransfersRequest{},
+		response:      []db.ScheduledTransfer{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/scheduled_transfers/:id",
+		operationID:   "getScheduledTransfer",
+		summary:       "Get a scheduled transfer",
+		uri:           scheduledTransferRequest{},
+		response:      db.ScheduledTransfer{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPut,
+		path:          "/scheduled_transfers/:id",
+		operationID:   "updateScheduledTransfer",
+		summary:       "Change, pause or resume a scheduled transfer",
+		uri:           scheduledTransferRequest{},
+		body:          updateScheduledTransferRequest{},
+		response:      db.ScheduledTransfer{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodDelete,
+		path:          "/scheduled_transfers/:id",
+		operationID:   "deleteScheduledTransfer",
+		summary:       "Cancel a scheduled transfer",
+		uri:           scheduledTransferRequest{},
+		successStatus: http.StatusNoContent,
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/scheduled_transfers/:id/runs",
+		operationID:   "listScheduledTransferRuns",
+		summary:       "List the runs of a scheduled transfer, newest first",
+		uri:           scheduledTransferRequest{},
+		query:         listScheduledTransferRunsRequest{},
+		response:      []scheduledTransferRunResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/webhooks",
+		operationID:   "createWebhook",
+		summary:       "Subscribe a URL to events on the accounts of the user",
+		body:          createWebhookRequest{},
+		response:      createWebhookResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/webhooks",
+		operationID:   "listWebhooks",
+		summary:       "List the webhooks of the user",
+		query:         listWebhooksRequest{},
+		response:      []webhookResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodDelete,
+		path:          "/webhooks/:id",
+		operationID:   "deleteWebhook",
+		summary:       "Delete a webhook and its deliveries",
+		uri:           webhookRequest{},
+		successStatus: http.StatusNoContent,
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/webhooks/:id/deliveries",
+		operationID:   "listWebhookDeliveries",
+		summary:       "List the deliveries of a webhook, newest first",
+		uri:           webhookRequest{},
+		query:         listWebhookDeliveriesRequest{},
+		response:      []db.WebhookDelivery{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/webhooks/:id/deliveries/:delivery_id",
+		operationID:   "getWebhookDelivery",
+		summary:       "Get a delivery of a webhook and every attempt to post it",
+		uri:           webhookDeliveryRequest{},
+		response:      webhookDeliveryResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPost,
+		path:          "/webhooks/:id/deliveries/:delivery_id/replay",
+		operationID:   "replayWebhookDelivery",
+		summary:       "Post a delivery of a webhook again",
+		uri:           webhookDeliveryRequest{},
+		response:      db.WebhookDelivery{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodPut,
+		path:          "/transfer_limits",
+		operationID:   "setTransferLimit",
+		summary:       "Set the transfer limits of an account, of a user or the defaults of a currency",
+		body:          setTransferLimitRequest{},
+		response:      transferLimitResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodGet,
+		path:          "/transfer_limits",
+		operationID:   "listTransferLimits",
+		summary:       "List transfer limits",
+		query:         listTransferLimitsRequest{},
+		response:      []transferLimitResponse{},
+		errorStatuses: []int{http.StatusBadRequest, http.StatusInternalServerError},
+	},
+	{
+		method:        http.MethodDelete,
+		path:          "/transfer_limits/:id",
+		operationID:   "deleteTransferLimit",
+		summary:       "Delete transfer limits",
+		uri:           transferLimitRequest{},
+		successStatus: http.StatusNoContent,
+		errorStatuses: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	},
+}
+
+// openAPISchemas collects the component schemas of a document as they are
+// referenced.
+type openAPISchemas struct {
+	schemas    map[string]interface{}
+	currencies []string
+}
+
+// newOpenAPIDocument builds the OpenAPI 3 description of every route. The
+// currency binding rule is documented as the list of enabled currencies.
+func newOpenAPIDocument(currencies *util.CurrencyRegistry) ([]byte, error) {
+	b := &openAPISchemas{schemas: map[string]interface{}{}}
+	for _, currency := range currencies.List() {
+		if currency.Enabled {
+			b.currencies = append(b.currencies, currency.Code)
+		}
+	}
+
+	paths := map[string]gin.H{}
+	for _, op := range openAPIOperations {
+		path := openAPIPath(op.path)
+		if paths[path] == nil {
+			paths[path] = gin.H{}
+		}
+		paths[path][strings.ToLower(op.method)] = b.operation(op)
+	}
+
+	doc := gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "SimpleBank API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": b.schemas,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "PASETO",
+				},
+			},
+		},
+	}
+
+	return json.Marshal(doc)
+}
+
+func (b *openAPISchemas) operation(op openAPIOperation) gin.H {
+	operation := gin.H{
+		"operationId": op.operationID,
+		"summary":     op.summary,
+	}
+	if !op.public {
+		operation["security"] = []gin.H{{"bearerAuth": []string{}}}
+	}
+
+	parameters := []gin.H{}
+	if op.uri != nil {
+		parameters = append(parameters, b.parameters(op.uri, "path", "uri")...)
+	}
+	if op.query != nil {
+		parameters = append(parameters, b.parameters(op.query, "query", "form")...)
+	}
+	for _, header := range op.headers {
+		parameters = append(parameters, gin.H{
+			"name":        header.name,
+			"in":          "header",
+			"description": header.description,
+			"schema":      gin.H{"type": "string", "maxLength": transfers.MaxIdempotencyKeyLength},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.body != nil {
+		operation["requestBody"] = gin.H{
+			"required": !op.optionalBody,
+			"content": gin.H{
+				"application/json": gin.H{"schema": b.schema(reflect.TypeOf(op.body))},
+			},
+		}
+	}
+
+	successStatus := op.successStatus
+	if successStatus == 0 {
+		successStatus = http.StatusOK
+	}
+	success := gin.H{"description": http.StatusText(successStatus)}
+	if op.response != nil {
+		success["content"] = gin.H{
+			"application/json": gin.H{"schema": b.schema(reflect.TypeOf(op.response))},
+		}
+	}
+
+	responses := gin.H{strconv.Itoa(successStatus): success}
+	errorStatuses := append([]int{}, op.errorStatuses...)
+	if !op.public {
+		errorStatuses = append(errorStatuses, http.StatusUnauthorized)
+	}
+	for _, status := range errorStatuses {
+		responses[strconv.Itoa(status)] = gin.H{
+			"description": http.StatusText(status),
+			"content": gin.H{
+				"application/json": gin.H{"schema": b.schema(reflect.TypeOf(errorResponse{}))},
+			},
+		}
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// parameters describes the fields of a struct bound from the path or the query.
+func (b *openAPISchemas) parameters(request interface{}, in string, tag string) []gin.H {
+	t := reflect.TypeOf(request)
+	parameters := []gin.H{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+
+		schema := b.schema(field.Type)
+		required := b.applyBinding(schema, field)
+		parameters = append(parameters, gin.H{
+			"name":     name,
+			"in":       in,
+			"required": required || in == "path",
+			"schema":   schema,
+		})
+	}
+
+	return parameters
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schema describes how t is encoded to JSON. Structs become component schemas
+// and are referenced.
+func (b *openAPISchemas) schema(t reflect.Type) gin.H {
+	switch t {
+	case timeType:
+		return gin.H{"type": "string", "format": "date-time"}
+	case uuidType:
+		return gin.H{"type": "string", "format": "uuid"}
+	case rawMessageType:
+		return gin.H{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := b.schema(t.Elem())
+		if _, ok := schema["$ref"]; ok {
+			return gin.H{"allOf": []gin.H{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int32:
+		return gin.H{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return gin.H{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, ok := b.schemas[name]; !ok {
+			// registered before the fields are walked, so that recursive types
+			// terminate
+			b.schemas[name] = gin.H{}
+			b.schemas[name] = b.object(t)
+		}
+		return gin.H{"$ref": "#/components/schemas/" + name}
+	}
+
+	panic(fmt.Sprintf("openapi: unsupported type %s", t))
+}
+
+// object describes the JSON object a struct is encoded to, flattening embedded
+// structs like encoding/json does.
+func (b *openAPISchemas) object(t reflect.Type) gin.H {
+	properties := gin.H{}
+	required := []string{}
+	b.addProperties(t, properties, &required)
+
+	object := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+func (b *openAPISchemas) addProperties(t reflect.Type, properties gin.H, required *[]string) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && len(tag) == 0 {
+			embedded = append(embedded, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		if _, ok := properties[name]; ok {
+			continue
+		}
+
+		schema := b.schema(field.Type)
+		if b.applyBinding(schema, field) {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+
+	// fields of embedded structs are walked last, since the fields of the
+	// struct itself shadow them
+	for _, e := range embedded {
+		b.addProperties(e, properties, required)
+	}
+}
+
+// applyBinding documents the binding rules of a field on its schema and tells
+// whether the field is required.
+func (b *openAPISchemas) applyBinding(schema gin.H, field reflect.StructField) (required bool) {
+	binding := field.Tag.Get("binding")
+	if len(binding) == 0 {
+		return false
+	}
+
+	return b.applyRules(schema, field.Type, strings.Split(binding, ","))
+}
+
+// applyRules documents binding rules on the schema of a value of type t. Rules
+// after dive apply to the items of a slice.
+func (b *openAPISchemas) applyRules(schema gin.H, t reflect.Type, rules []string) (required bool) {
+	isString := t.Kind() == reflect.String
+	isSlice := t.Kind() == reflect.Slice
+	for i, rule := range rules {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			if items, ok := schema["items"].(gin.H); ok {
+				b.applyRules(items, t.Elem(), rules[i+1:])
+			}
+			return required
+		case "required":
+			required = true
+		case "min":
+			if isString {
+				schema["minLength"] = mustAtoi(value)
+			} else if isSlice {
+				schema["minItems"] = mustAtoi(value)
+			} else {
+				schema["minimum"] = mustAtoi(value)
+			}
+		case "max":
+			if isString {
+				schema["maxLength"] = mustAtoi(value)
+			} else if isSlice {
+				schema["maxItems"] = mustAtoi(value)
+			} else {
+				schema["maximum"] = mustAtoi(value)
+			}
+		case "gt":
+			schema["minimum"] = mustAtoi(value)
+			schema["exclusiveMinimum"] = true
+		case "oneof":
+			schema["enum"] = strings.Fields(value)
+		case "alphanum":
+			schema["pattern"] = "^[a-zA-Z0-9]+$"
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "currency":
+			schema["enum"] = b.currencies
+		case "cron":
+			schema["format"] = "cron"
+		}
+	}
+
+	return required
+}
+
+func mustAtoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic(fmt.Sprintf("openapi: invalid binding parameter %q", s))
+	}
+	return n
+}
+
+// schemaName names the component schema of a struct after its Go type, so
+// accountResponse becomes AccountResponse.
+func schemaName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// openAPIPath turns a Gin path like /accounts/:id into /accounts/{id}.
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
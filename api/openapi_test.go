@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                                        `json:"openapi"`
+	Paths      map[string]map[string]openAPIDocOperation     `json:"paths"`
+	Components struct{ Schemas map[string]openAPIDocSchema } `json:"components"`
+}
+
+type openAPIDocOperation struct {
+	Security   []map[string][]string `json:"security"`
+	Parameters []struct {
+		Name     string           `json:"name"`
+		In       string           `json:"in"`
+		Required bool             `json:"required"`
+		Schema   openAPIDocSchema `json:"schema"`
+	} `json:"parameters"`
+	RequestBody struct {
+		Content map[string]struct {
+			Schema openAPIDocSchema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]interface{} `json:"responses"`
+}
+
+type openAPIDocSchema struct {
+	Ref              string                      `json:"$ref"`
+	Type             string                      `json:"type"`
+	Format           string                      `json:"format"`
+	Required         []string                    `json:"required"`
+	Properties       map[string]openAPIDocSchema `json:"properties"`
+	Enum             []string                    `json:"enum"`
+	Pattern          string                      `json:"pattern"`
+	Minimum          *int                        `json:"minimum"`
+	Maximum          *int                        `json:"maximum"`
+	MinLength        *int                        `json:"minLength"`
+	MinItems         *int                        `json:"minItems"`
+	Items            *openAPIDocSchema           `json:"items"`
+	ExclusiveMinimum bool                        `json:"exclusiveMinimum"`
+}
+
+func getOpenAPIDocument(t *testing.T, server *Server) openAPIDocument {
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &doc))
+	require.Equal(t, "3.0.3", doc.OpenAPI)
+	return doc
+}
+
+func (doc openAPIDocument) component(t *testing.T, ref string) openAPIDocSchema {
+	schema, ok := doc.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	require.True(t, ok, "missing schema %s", ref)
+	return schema
+}
+
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	doc := getOpenAPIDocument(t, server)
+
+	documented := 0
+	for _, route := range server.router.Routes() {
+		if route.Path == "/openapi.json" || strings.HasPrefix(route.Path, "/docs/") {
+			continue
+		}
+
+		path := openAPIPath(route.Path)
+		operation, ok := doc.Paths[path][strings.ToLower(route.Method)]
+		require.True(t, ok, "%s %s is not documented", route.Method, route.Path)
+		require.NotEmpty(t, operation.Responses)
+		documented++
+	}
+
+	operations := 0
+	for _, methods := range doc.Paths {
+		operations += len(methods)
+	}
+	require.Equal(t, documented, operations, "documented routes that are not registered")
+}
+
+func TestOpenAPIBindingRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	doc := getOpenAPIDocument(t, server)
+
+	createUser := doc.Paths["/users"]["post"]
+	require.Empty(t, createUser.Security)
+	user := doc.component(t, createUser.RequestBody.Content["application/json"].Schema.Ref)
+	require.ElementsMatch(t, []string{"username", "password", "full_name", "email"}, user.Required)
+	require.Equal(t, "^[a-zA-Z0-9]+$", user.Properties["username"].Pattern)
+	require.Equal(t, 6, *user.Properties["password"].MinLength)
+	require.Equal(t, "email", user.Properties["email"].Format)
+
+	createTransfer := doc.Paths["/transfers"]["post"]
+	require.NotEmpty(t, createTransfer.Security)
+	transfer := doc.component(t, createTransfer.RequestBody.Content["application/json"].Schema.Ref)
+	require.Equal(t, 0, *transfer.Properties["amount"].Minimum)
+	require.True(t, transfer.Properties["amount"].ExclusiveMinimum)
+	require.Equal(t, []string{util.CAD, util.EUR, util.USD}, transfer.Properties["currency"].Enum)
+	require.Equal(t, idempotencyKeyHeader, createTransfer.Parameters[0].Name)
+	require.Equal(t, "header", createTransfer.Parameters[0].In)
+
+	// rules after dive apply to the items of a slice
+	createWebhook := doc.Paths["/webhooks"]["post"]
+	webhook := doc.component(t, createWebhook.RequestBody.Content["application/json"].Schema.Ref)
+	require.Equal(t, "uri", webhook.Properties["url"].Format)
+	require.Equal(t, 1, *webhook.Properties["eventTypes"].MinItems)
+	require.Equal(t, db.EventTypes, webhook.Properties["eventTypes"].Items.Enum)
+
+	createScheduledTransfer := doc.Paths["/scheduled_transfers"]["post"]
+	scheduledTransfer := doc.component(t, createScheduledTransfer.RequestBody.Content["application/json"].Schema.Ref)
+	require.Equal(t, "cron", scheduledTransfer.Properties["schedule"].Format)
+	require.NotContains(t, scheduledTransfer.Required, "schedule")
+
+	listAccounts := doc.Paths["/accounts"]["get"]
+	params := map[string]int{}
+	for i, param := range listAccounts.Parameters {
+		require.Equal(t, "query", param.In)
+		params[param.Name] = i
+	}
+	pageSize := listAccounts.Parameters[params["page_size"]]
+	require.True(t, pageSize.Required)
+	require.Equal(t, 5, *pageSize.Schema.Minimum)
+	require.Equal(t, 10, *pageSize.Schema.Maximum)
+	require.False(t, listAccounts.Parameters[params["owner"]].Required)
+
+	getAccount := doc.Paths["/accounts/{id}"]["get"]
+	require.Equal(t, "id", getAccount.Parameters[0].Name)
+	require.Equal(t, "path", getAccount.Parameters[0].In)
+	require.True(t, getAccount.Parameters[0].Required)
+
+	// embedded structs are flattened like encoding/json does
+	account := doc.component(t, "AccountResponse")
+	require.Contains(t, account.Properties, "balance")
+	require.Contains(t, account.Properties, "balanceDecimal")
+	require.Equal(t, "date-time", account.Properties["createdAt"].Format)
+}
+
+func TestDocsAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	testCases := []struct {
+		url      string
+		contains string
+	}{
+		{url: "/docs/", contains: `<div id="swagger-ui"></div>`},
+		{url: "/docs/swagger-initializer.js", contains: `url: "/openapi.json"`},
+		{url: "/docs/swagger-ui-bundle.js", contains: "SwaggerUIBundle"},
+	}
+
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code, tc.url)
+		require.Contains(t, recorder.Body.String(), tc.contains, tc.url)
+	}
+}
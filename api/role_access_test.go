@@ -0,0 +1,476 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// publicRoutes are the routes served without authentication.
+var publicRoutes = []string{
+	"POST /users",
+	"POST /users/login",
+	"GET /users/verify_email",
+	"POST /tokens/renew_access",
+	"GET /currencies",
+	"GET /openapi.json",
+	"GET /docs/*filepath",
+}
+
+// TestRoleAccessAPI calls every authenticated route on resources of another
+// user with each role. Routes that only ever act on the resources of the
+// caller are called too, to show every role may use them. The test fails when
+// a route is added without being listed here or in publicRoutes.
+func TestRoleAccessAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	toAccount := randomAccount(util.RandomOwner())
+	toAccount.Currency = account.Currency
+	transfer := db.Transfer{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: account.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        util.RandomMoney(),
+	}
+	scheduledTransfer := db.ScheduledTransfer{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         account.Owner,
+		FromAccountID: account.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        util.RandomMoney(),
+		Status:        db.ScheduledTransferActive,
+	}
+	webhook := db.Webhook{ID: util.RandomInt(1, 1000), Owner: account.Owner}
+	delivery := db.WebhookDelivery{ID: util.RandomInt(1, 1000), WebhookID: webhook.ID}
+	limit := db.TransferLimit{ID: util.RandomInt(1, 1000), Currency: account.Currency}
+	startAt := time.Now().Add(time.Hour)
+
+	// statuses of routes that act on the resources of the caller only
+	anyRole := func(status int) map[string]int {
+		return map[string]int{
+			util.DepositorRole: status,
+			util.BankerRole:    status,
+			util.AdminRole:     status,
+		}
+	}
+
+	routes := []struct {
+		name string
+		// route as registered on the router
+		route  string
+		method string
+		url    string
+		body   gin.H
+		// expected status per role
+		status map[string]int
+	}{
+		{
+			name:   "LogoutUser",
+			route:  "POST /users/logout",
+			method: http.MethodPost,
+			url:    "/users/logout",
+			status: anyRole(http.StatusNoContent),
+		},
+		{
+			name:   "LogoutAllUser",
+			route:  "POST /users/logout_all",
+			method: http.MethodPost,
+			url:    "/users/logout_all",
+			status: anyRole(http.StatusNoContent),
+		},
+		{
+			name:   "ResendVerifyEmail",
+			route:  "POST /users/verify_email/resend",
+			method: http.MethodPost,
+			url:    "/users/verify_email/resend",
+			status: anyRole(http.StatusNoContent),
+		},
+		{
+			name:   "CreateAccount",
+			route:  "POST /accounts",
+			method: http.MethodPost,
+			url:    "/accounts",
+			body:   gin.H{"currency": account.Currency},
+			status: anyRole(http.StatusOK),
+		},
+		{
+			name:   "GetAccount",
+			route:  "GET /accounts/:id",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "ListAccounts",
+			route:  "GET /accounts",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts?owner=%s&page_id=1&page_size=5", account.Owner),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "ListAccountEntries",
+			route:  "GET /accounts/:id/entries",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/entries?page_id=1&page_size=5", account.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "GetAccountBalance",
+			route:  "GET /accounts/:id/balance",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/balance", account.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "FreezeAccount",
+			route:  "PUT /accounts/:id/status",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/accounts/%d/status", account.ID),
+			body:   gin.H{"status": util.AccountStatusFrozen, "reason": "suspicious activity"},
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusUnauthorized,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "ListAccountStatusChanges",
+			route:  "GET /accounts/:id/status_changes",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d/status_changes?page_id=1&page_size=5", account.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "SetOverdraftLimit",
+			route:  "PUT /accounts/:id/overdraft_limit",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/accounts/%d/overdraft_limit", account.ID),
+			body:   gin.H{"overdraftLimit": 500},
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusUnauthorized,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "DepositFunds",
+			route:  "POST /accounts/:id/deposits",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/accounts/%d/deposits", account.ID),
+			body:   gin.H{"amount": 10},
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "WithdrawFunds",
+			route:  "POST /accounts/:id/withdrawals",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/accounts/%d/withdrawals", account.ID),
+			body:   gin.H{"amount": 10},
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "CreateTransfer",
+			route:  "POST /transfers",
+			method: http.MethodPost,
+			url:    "/transfers",
+			body: gin.H{
+				"fromAccountID": account.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      account.Currency,
+			},
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusUnauthorized,
+				util.AdminRole:     http.StatusUnauthorized,
+			},
+		},
+		{
+			name:   "GetTransfer",
+			route:  "GET /transfers/:id",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/transfers/%d", transfer.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "ListTransfers",
+			route:  "GET /transfers",
+			method: http.MethodGet,
+			url:    "/transfers?page_size=5",
+			status: anyRole(http.StatusOK),
+		},
+		{
+			name:   "ReverseTransfer",
+			route:  "POST /transfers/:id/reversals",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/transfers/%d/reversals", transfer.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusUnauthorized,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "CreateScheduledTransfer",
+			route:  "POST /scheduled_transfers",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"fromAccountID": account.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      account.Currency,
+				"startAt":       startAt,
+			},
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "ListScheduledTransfers",
+			route:  "GET /scheduled_transfers",
+			method: http.MethodGet,
+			url:    "/scheduled_transfers?page_id=1&page_size=5",
+			status: anyRole(http.StatusOK),
+		},
+		{
+			name:   "GetScheduledTransfer",
+			route:  "GET /scheduled_transfers/:id",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "UpdateScheduledTransfer",
+			route:  "PUT /scheduled_transfers/:id",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			body: gin.H{
+				"amount":  10,
+				"startAt": startAt,
+				"status":  db.ScheduledTransferPaused,
+			},
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "DeleteScheduledTransfer",
+			route:  "DELETE /scheduled_transfers/:id",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "ListScheduledTransferRuns",
+			route:  "GET /scheduled_transfers/:id/runs",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers/%d/runs?page_id=1&page_size=5", scheduledTransfer.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusOK,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "CreateWebhook",
+			route:  "POST /webhooks",
+			method: http.MethodPost,
+			url:    "/webhooks",
+			body:   gin.H{"url": "https://example.com/hook", "eventTypes": []string{"transfer.created"}},
+			status: anyRole(http.StatusOK),
+		},
+		{
+			name:   "ListWebhooks",
+			route:  "GET /webhooks",
+			method: http.MethodGet,
+			url:    "/webhooks?page_id=1&page_size=5",
+			status: anyRole(http.StatusOK),
+		},
+		{
+			name:   "DeleteWebhook",
+			route:  "DELETE /webhooks/:id",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/webhooks/%d", webhook.ID),
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "ListWebhookDeliveries",
+			route:  "GET /webhooks/:id/deliveries",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/webhooks/%d/deliveries?page_id=1&page_size=5", webhook.ID),
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "GetWebhookDelivery",
+			route:  "GET /webhooks/:id/deliveries/:delivery_id",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/webhooks/%d/deliveries/%d", webhook.ID, delivery.ID),
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "ReplayWebhookDelivery",
+			route:  "POST /webhooks/:id/deliveries/:delivery_id/replay",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/webhooks/%d/deliveries/%d/replay", webhook.ID, delivery.ID),
+			status: anyRole(http.StatusUnauthorized),
+		},
+		{
+			name:   "SetTransferLimit",
+			route:  "PUT /transfer_limits",
+			method: http.MethodPut,
+			url:    "/transfer_limits",
+			body:   gin.H{"currency": account.Currency, "owner": account.Owner, "dailyAmount": 1000},
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusUnauthorized,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+		{
+			name:   "DeleteTransferLimit",
+			route:  "DELETE /transfer_limits/:id",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/transfer_limits/%d", limit.ID),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusUnauthorized,
+				util.AdminRole:     http.StatusNoContent,
+			},
+		},
+		{
+			name:   "ListTransferLimits",
+			route:  "GET /transfer_limits",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/transfer_limits?owner=%s&page_id=1&page_size=5", account.Owner),
+			status: map[string]int{
+				util.DepositorRole: http.StatusUnauthorized,
+				util.BankerRole:    http.StatusUnauthorized,
+				util.AdminRole:     http.StatusOK,
+			},
+		},
+	}
+
+	for _, route := range routes {
+		for _, role := range []string{util.DepositorRole, util.BankerRole, util.AdminRole} {
+			route, role := route, role
+
+			t.Run(fmt.Sprintf("%s/%s", route.name, role), func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				store := mockdb.NewMockStore(ctrl)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).AnyTimes().Return(account, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).AnyTimes().Return(toAccount, nil)
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).AnyTimes().Return(transfer, nil)
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.Account{account}, nil)
+				store.EXPECT().
+					ListAccountStatement(gomock.Any(), gomock.Any()).
+					AnyTimes().
+					Return([]db.ListAccountStatementRow{}, nil)
+				store.EXPECT().ListTransferLimits(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.TransferLimit{}, nil)
+				store.EXPECT().GetAccountBalanceAt(gomock.Any(), gomock.Any()).AnyTimes().Return(account.Balance, nil)
+				store.EXPECT().
+					ChangeAccountStatusTx(gomock.Any(), gomock.Any()).
+					AnyTimes().
+					Return(db.ChangeAccountStatusTxResult{Account: account}, nil)
+				store.EXPECT().ListAccountStatusChanges(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.AccountStatusChange{}, nil)
+				store.EXPECT().UpdateAccountOverdraftLimit(gomock.Any(), gomock.Any()).AnyTimes().Return(account, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).AnyTimes().Return(db.TransferTxResult{Transfer: transfer}, nil)
+				store.EXPECT().ListUserTransfers(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.ListUserTransfersRow{}, nil)
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).AnyTimes().Return(scheduledTransfer, nil)
+				store.EXPECT().ListScheduledTransfers(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.ScheduledTransfer{}, nil)
+				store.EXPECT().
+					ListScheduledTransferRuns(gomock.Any(), gomock.Any()).
+					AnyTimes().
+					Return([]db.ScheduledTransferRun{}, nil)
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).AnyTimes().Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).AnyTimes().Return(delivery, nil)
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).AnyTimes().Return(db.Webhook{}, nil)
+				store.EXPECT().ListWebhooks(gomock.Any(), gomock.Any()).AnyTimes().Return([]db.Webhook{}, nil)
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).AnyTimes().Return(limit, nil)
+				store.EXPECT().GetTransferLimit(gomock.Any(), gomock.Eq(limit.ID)).AnyTimes().Return(limit, nil)
+				store.EXPECT().DeleteTransferLimit(gomock.Any(), gomock.Eq(limit.ID)).AnyTimes().Return(nil)
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).AnyTimes().Return(db.Account{}, nil)
+				store.EXPECT().ResendVerifyEmailTx(gomock.Any(), gomock.Any()).AnyTimes().Return(db.VerifyEmail{}, nil)
+				store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+				store.EXPECT().RevokeAllTokensTx(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+
+				// nothing may be changed on behalf of another user
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().FundingTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Any()).Times(0)
+				stubVerifiedUsers(store)
+
+				server := newTestServer(t, store)
+				recorder := httptest.NewRecorder()
+
+				var body []byte
+				if route.body != nil {
+					data, err := json.Marshal(route.body)
+					require.NoError(t, err)
+					body = data
+				}
+
+				req, err := http.NewRequest(route.method, route.url, bytes.NewReader(body))
+				require.NoError(t, err)
+
+				addAuthorization(t, req, server.tokenMaker, authorizationTypeBearer, "caller", role, time.Minute)
+				server.router.ServeHTTP(recorder, req)
+				require.Equal(t, route.status[role], recorder.Code)
+			})
+		}
+	}
+
+	listed := make(map[string]bool, len(publicRoutes)+len(routes))
+	for _, route := range publicRoutes {
+		listed[route] = true
+	}
+	for _, route := range routes {
+		listed[route.route] = true
+	}
+
+	server := newTestServer(t, mockdb.NewMockStore(gomock.NewController(t)))
+	for _, info := range server.router.Routes() {
+		route := fmt.Sprintf("%s %s", info.Method, info.Path)
+		require.True(t, listed[route], "route %s is neither public nor checked for role access", route)
+	}
+}
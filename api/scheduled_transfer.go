@@ -0,0 +1,323 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64  `json:"fromAccountID" binding:"required,min=1"`
+	ToAccountID   int64  `json:"toAccountID" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	// cron expression the transfer recurs on, in UTC; left out for a one-off
+	Schedule string `json:"schedule" binding:"omitempty,max=100,cron"`
+	// when a one-off transfer is made, or from when a recurring one starts
+	StartAt       time.Time `json:"startAt" binding:"required"`
+	FailurePolicy string    `json:"failurePolicy" binding:"omitempty,oneof=retry skip"`
+}
+
+// createScheduledTransfer schedules a transfer from an account of the user,
+// once or on a cron schedule. Both accounts must hold the same currency, since
+// there is no rate to convert at until the transfer is made, and must be
+// customer accounts.
+func (s *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	nextRunAt, valid := firstRunAt(ctx, req.Schedule, req.StartAt)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !s.requireVerifiedEmail(ctx, authPayload.Username) {
+		return
+	}
+
+	fromAccount, valid := s.validateAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if err := auth.Authorize(authPayload, fromAccount.Owner, auth.ActionMoveFunds); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	toAccount, valid := s.loadAccount(ctx, req.ToAccountID)
+	if !valid {
+		return
+	}
+
+	if toAccount.Currency != fromAccount.Currency {
+		writeError(ctx, errCurrencyMismatch)
+		return
+	}
+
+	if fromAccount.Kind != db.AccountKindCustomer || toAccount.Kind != db.AccountKindCustomer {
+		writeError(ctx, errSystemAccount)
+		return
+	}
+
+	scheduledTransfer, err := s.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Schedule:      req.Schedule,
+		FailurePolicy: failurePolicyOrDefault(req.FailurePolicy),
+		NextRunAt:     nextRunAt,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+// firstRunAt tells when a scheduled transfer first runs: at startAt for a
+// one-off, or at the first occurrence of the schedule from startAt on. It
+// writes the error response when that isn't in the future.
+func firstRunAt(ctx *gin.Context, schedule string, startAt time.Time) (time.Time, bool) {
+	if !startAt.After(time.Now()) {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "startAt", Message: "must be in the future"}))
+		return startAt, false
+	}
+
+	if len(schedule) == 0 {
+		return startAt, true
+	}
+
+	// the schedule was validated on binding
+	cron, _ := util.ParseCron(schedule)
+	next := cron.Next(startAt.UTC().Add(-time.Nanosecond))
+	if next.IsZero() {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "schedule", Message: "never occurs"}))
+		return next, false
+	}
+
+	return next, true
+}
+
+func failurePolicyOrDefault(policy string) string {
+	if len(policy) == 0 {
+		return db.FailurePolicyRetry
+	}
+	return policy
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (s *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfers, err := s.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: req.PageSize * (req.PageID - 1),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfers)
+}
+
+type scheduledTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getScheduledTransfer(ctx *gin.Context) {
+	var req scheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	scheduledTransfer, valid := s.getAuthorizedScheduledTransfer(ctx, req.ID, auth.ActionReadAccount)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+type updateScheduledTransferRequest struct {
+	Amount        int64     `json:"amount" binding:"required,gt=0"`
+	Schedule      string    `json:"schedule" binding:"omitempty,max=100,cron"`
+	StartAt       time.Time `json:"startAt" binding:"required"`
+	FailurePolicy string    `json:"failurePolicy" binding:"omitempty,oneof=retry skip"`
+	Status        string    `json:"status" binding:"required,oneof=active paused"`
+}
+
+// updateScheduledTransfer replaces the amount, schedule and policy of a
+// scheduled transfer, and pauses or resumes it. Completed and failed transfers
+// can be scheduled again this way. Failed attempts at the current run are
+// forgotten.
+func (s *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	nextRunAt, valid := firstRunAt(ctx, req.Schedule, req.StartAt)
+	if !valid {
+		return
+	}
+
+	scheduledTransfer, valid := s.getAuthorizedScheduledTransfer(ctx, uri.ID, auth.ActionMoveFunds)
+	if !valid {
+		return
+	}
+
+	scheduledTransfer, err := s.store.UpdateScheduledTransfer(ctx, db.UpdateScheduledTransferParams{
+		ID:            scheduledTransfer.ID,
+		Amount:        req.Amount,
+		Schedule:      req.Schedule,
+		FailurePolicy: failurePolicyOrDefault(req.FailurePolicy),
+		Status:        req.Status,
+		NextRunAt:     nextRunAt,
+	})
+	if err != nil {
+		writeError(ctx, notFound(err, errScheduledTransferNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+// deleteScheduledTransfer cancels a scheduled transfer along with the record of
+// its runs. Transfers already made are kept.
+func (s *Server) deleteScheduledTransfer(ctx *gin.Context) {
+	var req scheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	scheduledTransfer, valid := s.getAuthorizedScheduledTransfer(ctx, req.ID, auth.ActionMoveFunds)
+	if !valid {
+		return
+	}
+
+	if err := s.store.DeleteScheduledTransfer(ctx, scheduledTransfer.ID); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listScheduledTransferRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+type scheduledTransferRunResponse struct {
+	ID                  int64     `json:"id"`
+	ScheduledTransferID int64     `json:"scheduledTransferID"`
+	ScheduledFor        time.Time `json:"scheduledFor"`
+	Outcome             string    `json:"outcome"`
+	// transfer made by the run, absent unless it succeeded
+	TransferID *int64    `json:"transferID,omitempty"`
+	Error      string    `json:"error"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func newScheduledTransferRunResponse(run db.ScheduledTransferRun) scheduledTransferRunResponse {
+	res := scheduledTransferRunResponse{
+		ID:                  run.ID,
+		ScheduledTransferID: run.ScheduledTransferID,
+		ScheduledFor:        run.ScheduledFor,
+		Outcome:             run.Outcome,
+		Error:               run.Error,
+		CreatedAt:           run.CreatedAt,
+	}
+	if run.TransferID.Valid {
+		res.TransferID = &run.TransferID.Int64
+	}
+	return res
+}
+
+// listScheduledTransferRuns lists the runs of a scheduled transfer, newest
+// first, with the transfer each successful run made.
+func (s *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri scheduledTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req listScheduledTransferRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	scheduledTransfer, valid := s.getAuthorizedScheduledTransfer(ctx, uri.ID, auth.ActionReadAccount)
+	if !valid {
+		return
+	}
+
+	runs, err := s.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               req.PageSize,
+		Offset:              req.PageSize * (req.PageID - 1),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	res := make([]scheduledTransferRunResponse, len(runs))
+	for i, run := range runs {
+		res[i] = newScheduledTransferRunResponse(run)
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// getAuthorizedScheduledTransfer loads a scheduled transfer and makes sure the
+// authenticated user may act on it, writing the error response otherwise.
+func (s *Server) getAuthorizedScheduledTransfer(ctx *gin.Context, scheduledTransferID int64, act auth.Action) (db.ScheduledTransfer, bool) {
+	scheduledTransfer, err := s.store.GetScheduledTransfer(ctx, scheduledTransferID)
+	if err != nil {
+		writeError(ctx, notFound(err, errScheduledTransferNotFound))
+		return scheduledTransfer, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := auth.Authorize(authPayload, scheduledTransfer.Owner, act); err != nil {
+		writeError(ctx, err)
+		return scheduledTransfer, false
+	}
+
+	return scheduledTransfer, true
+}
@@ -0,0 +1,442 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	fromAccount := randomAccount(user.Username)
+	toAccount := randomAccount(util.RandomOwner())
+	toAccount.Currency = fromAccount.Currency
+	otherAccount := randomAccount(util.RandomOwner())
+	otherAccount.Currency = fromAccount.Currency
+
+	// midnight UTC a few days ahead, so that @daily first occurs on it
+	startAt := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 3)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OneOff",
+			body: gin.H{
+				"fromAccountID": fromAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      fromAccount.Currency,
+				"startAt":       startAt.Add(90 * time.Second),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Empty(t, arg.Schedule)
+						require.Equal(t, db.FailurePolicyRetry, arg.FailurePolicy)
+						require.True(t, startAt.Add(90*time.Second).Equal(arg.NextRunAt))
+						return randomScheduledTransfer(user.Username, fromAccount.ID, toAccount.ID), nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Recurring",
+			body: gin.H{
+				"fromAccountID": fromAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      fromAccount.Currency,
+				"schedule":      "@daily",
+				"startAt":       startAt.Add(time.Hour),
+				"failurePolicy": db.FailurePolicySkip,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, "@daily", arg.Schedule)
+						require.Equal(t, db.FailurePolicySkip, arg.FailurePolicy)
+						// the first midnight from startAt on
+						require.True(t, startAt.AddDate(0, 0, 1).Equal(arg.NextRunAt))
+						return randomScheduledTransfer(user.Username, fromAccount.ID, toAccount.ID), nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidSchedule",
+			body: gin.H{
+				"fromAccountID": fromAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      fromAccount.Currency,
+				"schedule":      "0 25 * * *",
+				"startAt":       startAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "schedule", Message: "must be a cron expression"}}, res.Fields)
+			},
+		},
+		{
+			name: "ScheduleNeverOccurs",
+			body: gin.H{
+				"fromAccountID": fromAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      fromAccount.Currency,
+				"schedule":      "0 0 30 2 *",
+				"startAt":       startAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "schedule", Message: "never occurs"}}, res.Fields)
+			},
+		},
+		{
+			name: "StartInPast",
+			body: gin.H{
+				"fromAccountID": fromAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      fromAccount.Currency,
+				"startAt":       time.Now().Add(-time.Minute),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "startAt", Message: "must be in the future"}}, res.Fields)
+			},
+		},
+		{
+			name: "ForeignAccount",
+			body: gin.H{
+				"fromAccountID": otherAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      otherAccount.Currency,
+				"startAt":       startAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherAccount.ID)).Times(1).Return(otherAccount, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"fromAccountID": fromAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      fromAccount.Currency,
+				"startAt":       startAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				foreignCurrency := toAccount
+				foreignCurrency.Currency = "XXX"
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(foreignCurrency, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnprocessableEntity, "currency_mismatch")
+			},
+		},
+		{
+			name: "SystemAccount",
+			body: gin.H{
+				"fromAccountID": fromAccount.ID,
+				"toAccountID":   toAccount.ID,
+				"amount":        10,
+				"currency":      fromAccount.Currency,
+				"startAt":       startAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				clearing := toAccount
+				clearing.Owner = db.SystemOwner
+				clearing.Kind = db.AccountKindClearing
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(clearing, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnprocessableEntity, "system_account")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			stubVerifiedUsers(store)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/scheduled_transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	scheduledTransfer := randomScheduledTransfer(user.Username, util.RandomInt(1, 1000), util.RandomInt(1, 1000))
+	startAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	runs := []db.ScheduledTransferRun{
+		{ID: 2, ScheduledTransferID: scheduledTransfer.ID, Outcome: db.ScheduledRunSucceeded, TransferID: sql.NullInt64{Int64: 7, Valid: true}},
+		{ID: 1, ScheduledTransferID: scheduledTransfer.ID, Outcome: db.ScheduledRunRetrying, Error: "insufficient funds"},
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		username      string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Get",
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res db.ScheduledTransfer
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, scheduledTransfer.ID, res.ID)
+			},
+		},
+		{
+			name:     "GetForeign",
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			username: util.RandomOwner(),
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+		{
+			name:     "NotFound",
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusNotFound, "scheduled_transfer_not_found")
+			},
+		},
+		{
+			name:     "Pause",
+			method:   http.MethodPut,
+			url:      fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			body:     gin.H{"amount": 20, "startAt": startAt, "status": db.ScheduledTransferPaused},
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().
+					UpdateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, scheduledTransfer.ID, arg.ID)
+						require.Equal(t, int64(20), arg.Amount)
+						require.Equal(t, db.ScheduledTransferPaused, arg.Status)
+						require.Equal(t, db.FailurePolicyRetry, arg.FailurePolicy)
+						require.True(t, startAt.Equal(arg.NextRunAt))
+
+						updated := scheduledTransfer
+						updated.Status = arg.Status
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "UpdateToCompleted",
+			method:   http.MethodPut,
+			url:      fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			body:     gin.H{"amount": 20, "startAt": startAt, "status": db.ScheduledTransferCompleted},
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "status", res.Fields[0].Field)
+			},
+		},
+		{
+			name:     "DeleteByAdmin",
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			username: util.RandomOwner(),
+			role:     util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().DeleteScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+		{
+			name:     "Delete",
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/scheduled_transfers/%d", scheduledTransfer.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().DeleteScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "ListRuns",
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/scheduled_transfers/%d/runs?page_id=1&page_size=5", scheduledTransfer.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduledTransfer.ID)).Times(1).Return(scheduledTransfer, nil)
+				store.EXPECT().
+					ListScheduledTransferRuns(gomock.Any(), gomock.Eq(db.ListScheduledTransferRunsParams{
+						ScheduledTransferID: scheduledTransfer.ID,
+						Limit:               5,
+						Offset:              0,
+					})).
+					Times(1).
+					Return(runs, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res []scheduledTransferRunResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Len(t, res, 2)
+				require.NotNil(t, res[0].TransferID)
+				require.Equal(t, int64(7), *res[0].TransferID)
+				require.Nil(t, res[1].TransferID)
+				require.Equal(t, "insufficient funds", res[1].Error)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var data []byte
+			if tc.body != nil {
+				var err error
+				data, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomScheduledTransfer(owner string, fromAccountID, toAccountID int64) db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:            util.RandomInt(1, 1000),
+		Owner:         owner,
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        util.RandomMoney(),
+		Schedule:      "@monthly",
+		FailurePolicy: db.FailurePolicyRetry,
+		Status:        db.ScheduledTransferActive,
+		NextRunAt:     time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+	}
+}
@@ -2,34 +2,74 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/leoomi/simplebank/auth"
 	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/funding"
 	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/transfers"
 	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
 )
 
 type Server struct {
-	config     util.Config
-	store      db.Store
-	router     *gin.Engine
-	tokenMaker token.Maker
+	config      util.Config
+	store       db.Store
+	router      *gin.Engine
+	tokenMaker  token.Maker
+	revocations *auth.RevocationList
+	currencies  *util.CurrencyRegistry
+	transfers   *transfers.Service
+	gateway     funding.Gateway
+	distributor worker.TaskDistributor
+	openAPI     []byte
 }
 
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+// NewServer creates a server that checks tokens against revocations, which is
+// shared with the other servers so that a token revoked through one of them is
+// turned away by all of them right away.
+func NewServer(config util.Config, store db.Store, revocations *auth.RevocationList) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:      config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: revocations,
+		distributor: worker.NewPGTaskDistributor(),
+		// no real gateway is wired up yet, so payments are only pretended
+		gateway: funding.NewFakeGateway(),
 	}
+	var rates exchange.RateProvider = exchange.NewStoreProvider(store)
+	if len(config.ExchangeRatesFile) > 0 {
+		rates, err = exchange.NewFileProvider(config.ExchangeRatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load exchange rates: %w", err)
+		}
+	}
+
+	server.currencies, err = loadCurrencyRegistry(config, store)
+	if err != nil {
+		return nil, err
+	}
+	server.transfers = transfers.NewService(config, store, rates, server.currencies, server.distributor)
+
+	server.openAPI, err = newOpenAPIDocument(server.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build openapi document: %w", err)
+	}
+
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("currency", server.validCurrency)
+		v.RegisterValidation("cron", validCron)
+		v.RegisterTagNameFunc(requestFieldName)
 	}
 
 	server.setupRouter()
@@ -42,21 +82,50 @@ func (s *Server) setupRouter() {
 
 	router.POST("/users", s.createUser)
 	router.POST("/users/login", s.loginUser)
+	router.GET("/users/verify_email", s.verifyEmail)
+	router.POST("/tokens/renew_access", s.renewAccessToken)
+	router.GET("/currencies", s.listCurrencies)
+	router.GET("/openapi.json", s.getOpenAPI)
+	router.GET("/docs/*filepath", serveDocs())
 
-	authRoutes := router.Group("/").Use(authMiddleware(s.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(s.tokenMaker, s.revocations))
+	authRoutes.POST("/users/logout", s.logoutUser)
+	authRoutes.POST("/users/logout_all", s.logoutAllUser)
+	authRoutes.POST("/users/verify_email/resend", s.resendVerifyEmail)
 	authRoutes.POST("/accounts", s.createAccount)
 	authRoutes.GET("/accounts/:id", s.getAccount)
+	authRoutes.GET("/accounts/:id/entries", s.listAccountEntries)
+	authRoutes.GET("/accounts/:id/balance", s.getAccountBalance)
 	authRoutes.GET("/accounts", s.listAccounts)
-	authRoutes.DELETE("/accounts", s.listAccounts)
+	authRoutes.PUT("/accounts/:id/status", s.changeAccountStatus)
+	authRoutes.GET("/accounts/:id/status_changes", s.listAccountStatusChanges)
+	authRoutes.PUT("/accounts/:id/overdraft_limit", s.setOverdraftLimit)
+	authRoutes.POST("/accounts/:id/deposits", s.depositFunds)
+	authRoutes.POST("/accounts/:id/withdrawals", s.withdrawFunds)
 	authRoutes.POST("/transfers", s.createTransfer)
+	authRoutes.GET("/transfers", s.listTransfers)
+	authRoutes.GET("/transfers/:id", s.getTransfer)
+	authRoutes.POST("/transfers/:id/reversals", s.reverseTransfer)
+	authRoutes.POST("/scheduled_transfers", s.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", s.listScheduledTransfers)
+	authRoutes.GET("/scheduled_transfers/:id", s.getScheduledTransfer)
+	authRoutes.PUT("/scheduled_transfers/:id", s.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", s.deleteScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", s.listScheduledTransferRuns)
+	authRoutes.POST("/webhooks", s.createWebhook)
+	authRoutes.GET("/webhooks", s.listWebhooks)
+	authRoutes.DELETE("/webhooks/:id", s.deleteWebhook)
+	authRoutes.GET("/webhooks/:id/deliveries", s.listWebhookDeliveries)
+	authRoutes.GET("/webhooks/:id/deliveries/:delivery_id", s.getWebhookDelivery)
+	authRoutes.POST("/webhooks/:id/deliveries/:delivery_id/replay", s.replayWebhookDelivery)
+	authRoutes.PUT("/transfer_limits", s.setTransferLimit)
+	authRoutes.GET("/transfer_limits", s.listTransferLimits)
+	authRoutes.DELETE("/transfer_limits/:id", s.deleteTransferLimit)
 
 	s.router = router
 }
 
-func (s *Server) Start(address string) error {
-	return s.router.Run(address)
-}
-
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// ServeHTTP lets the server share a listener with other handlers.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
 }
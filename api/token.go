@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/token"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (s *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	refreshPayload, err := s.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefresh)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	session, err := s.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		writeError(ctx, notFound(err, errSessionNotFound))
+		return
+	}
+
+	if session.IsBlocked {
+		writeError(ctx, errSessionBlocked)
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		writeError(ctx, errSessionMismatch)
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		writeError(ctx, errSessionMismatch)
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		writeError(ctx, errSessionExpired)
+		return
+	}
+
+	// the role is read again, so that a user who was demoted since they logged
+	// in doesn't keep their old role until the refresh token expires
+	user, err := s.store.GetUser(ctx, refreshPayload.Username)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, s.config.AccessTokenDuration)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	res := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, res)
+}
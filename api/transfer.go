@@ -2,15 +2,81 @@ package api
 
 import (
 	"database/sql"
-	"errors"
-	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
 	db "github.com/leoomi/simplebank/db/sqlc"
 	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/transfers"
+	"github.com/leoomi/simplebank/worker"
 )
 
+const idempotencyKeyHeader = "Idempotency-Key"
+
+type transferResponse struct {
+	db.Transfer
+	AmountDecimal   string `json:"amountDecimal"`
+	ToAmountDecimal string `json:"toAmountDecimal"`
+	// transfer this one gives back, absent unless it is a reversal
+	ReversalOf *int64 `json:"reversalOf,omitempty"`
+}
+
+func (s *Server) newTransferResponse(transfer db.Transfer, fromCurrency, toCurrency string) transferResponse {
+	return transferResponse{
+		Transfer:        transfer,
+		AmountDecimal:   s.formatAmount(transfer.Amount, fromCurrency),
+		ToAmountDecimal: s.formatAmount(transfer.ToAmount, toCurrency),
+		ReversalOf:      nullableID(transfer.ReversalOf),
+	}
+}
+
+// nullableID renders a nullable reference as a JSON number, or leaves it out.
+func nullableID(id sql.NullInt64) *int64 {
+	if !id.Valid {
+		return nil
+	}
+	return &id.Int64
+}
+
+type entryResponse struct {
+	db.Entry
+	AmountDecimal string `json:"amountDecimal"`
+	// transfer the entry was made for, absent for entries of other journals
+	TransferID *int64 `json:"transferID,omitempty"`
+}
+
+func (s *Server) newEntryResponse(entry db.Entry, currency string) entryResponse {
+	return entryResponse{
+		Entry:         entry,
+		AmountDecimal: s.formatAmount(entry.Amount, currency),
+		TransferID:    nullableID(entry.TransferID),
+	}
+}
+
+// transferTxResponse renders a db.TransferTxResult with decimal amounts, keeping
+// its JSON field names.
+type transferTxResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromAccount accountResponse  `json:"from_account"`
+	ToAccount   accountResponse  `json:"to_account"`
+	FromEntry   entryResponse    `json:"from_entry"`
+	ToEntry     entryResponse    `json:"To_entry"`
+}
+
+func (s *Server) newTransferTxResponse(result db.TransferTxResult) transferTxResponse {
+	fromCurrency, toCurrency := result.FromAccount.Currency, result.ToAccount.Currency
+
+	return transferTxResponse{
+		Transfer:    s.newTransferResponse(result.Transfer, fromCurrency, toCurrency),
+		FromAccount: s.newAccountResponse(result.FromAccount),
+		ToAccount:   s.newAccountResponse(result.ToAccount),
+		FromEntry:   s.newEntryResponse(result.FromEntry, fromCurrency),
+		ToEntry:     s.newEntryResponse(result.ToEntry, toCurrency),
+	}
+}
+
 type transferRequest struct {
 	FromAccountID int64  `json:"fromAccountID" binding:"required,min=1"`
 	ToAccountID   int64  `json:"toAccountID" binding:"required,min=1"`
@@ -21,58 +87,212 @@ type transferRequest struct {
 func (s *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	fromAccount, valid := s.validateAccount(ctx, req.FromAccountID, req.Currency)
-	if !valid {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !s.requireVerifiedEmail(ctx, authPayload.Username) {
 		return
 	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if fromAccount.Owner != authPayload.Username {
-		err := errors.New("from account doesn't belong to authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	result, err := s.transfers.Create(ctx, authPayload, transfers.CreateParams{
+		FromAccountID:  req.FromAccountID,
+		ToAccountID:    req.ToAccountID,
+		Amount:         req.Amount,
+		Currency:       req.Currency,
+		IdempotencyKey: ctx.GetHeader(idempotencyKeyHeader),
+	})
+	if err != nil {
+		writeError(ctx, err)
 		return
 	}
 
-	_, valid = s.validateAccount(ctx, req.ToAccountID, req.Currency)
+	ctx.JSON(http.StatusOK, s.newTransferTxResponse(result))
+}
+
+func (s *Server) validateAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, valid := s.loadAccount(ctx, accountID)
 	if !valid {
+		return account, false
+	}
+
+	if account.Currency != currency {
+		writeError(ctx, errAccountCurrencyMismatch.withMessage("account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency))
+		return account, false
+	}
+
+	return account, true
+}
+
+type listTransfersRequest struct {
+	Cursor                int64     `form:"cursor" binding:"omitempty,min=1"`
+	CounterpartyAccountID int64     `form:"counterparty_account_id" binding:"omitempty,min=1"`
+	MinAmount             int64     `form:"min_amount" binding:"omitempty,min=1"`
+	MaxAmount             int64     `form:"max_amount" binding:"omitempty,min=1"`
+	FromTime              time.Time `form:"from_time"`
+	ToTime                time.Time `form:"to_time"`
+	PageSize              int32     `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+type transferHistoryResponse struct {
+	db.ListUserTransfersRow
+	AmountDecimal   string `json:"amountDecimal"`
+	ToAmountDecimal string `json:"toAmountDecimal"`
+	ReversalOf      *int64 `json:"reversalOf,omitempty"`
+}
+
+type listTransfersResponse struct {
+	Transfers []transferHistoryResponse `json:"transfers"`
+	// ID to pass as cursor for the next page, absent on the last page
+	NextCursor *int64 `json:"nextCursor,omitempty"`
+}
+
+func (s *Server) listTransfers(ctx *gin.Context) {
+	var req listTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if req.MinAmount > 0 && req.MaxAmount > 0 && req.MinAmount > req.MaxAmount {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "min_amount", Message: "must not be greater than max_amount"}))
 		return
 	}
 
-	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+	if !req.FromTime.IsZero() && !req.ToTime.IsZero() && !req.FromTime.Before(req.ToTime) {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "from_time", Message: "must be before to_time"}))
+		return
 	}
 
-	result, err := s.store.TransferTx(ctx, arg)
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.ListUserTransfersParams{
+		Owner:                 authPayload.Username,
+		Cursor:                sql.NullInt64{Int64: req.Cursor, Valid: req.Cursor > 0},
+		CounterpartyAccountID: sql.NullInt64{Int64: req.CounterpartyAccountID, Valid: req.CounterpartyAccountID > 0},
+		MinAmount:             sql.NullInt64{Int64: req.MinAmount, Valid: req.MinAmount > 0},
+		MaxAmount:             sql.NullInt64{Int64: req.MaxAmount, Valid: req.MaxAmount > 0},
+		FromTime:              sql.NullTime{Time: req.FromTime, Valid: !req.FromTime.IsZero()},
+		ToTime:                sql.NullTime{Time: req.ToTime, Valid: !req.ToTime.IsZero()},
+		PageLimit:             req.PageSize,
+	}
+	transfers, err := s.store.ListUserTransfers(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	res := listTransfersResponse{Transfers: make([]transferHistoryResponse, len(transfers))}
+	for i, transfer := range transfers {
+		res.Transfers[i] = transferHistoryResponse{
+			ListUserTransfersRow: transfer,
+			AmountDecimal:        s.formatAmount(transfer.Amount, transfer.FromCurrency),
+			ToAmountDecimal:      s.formatAmount(transfer.ToAmount, transfer.ToCurrency),
+			ReversalOf:           nullableID(transfer.ReversalOf),
+		}
+	}
+	if len(transfers) == int(req.PageSize) {
+		nextCursor := transfers[len(transfers)-1].ID
+		res.NextCursor = &nextCursor
+	}
+
+	ctx.JSON(http.StatusOK, res)
 }
 
-func (s *Server) validateAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
-	account, err := s.store.GetAccount(ctx, accountID)
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getTransfer(ctx *gin.Context) {
+	var req getTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	transfer, err := s.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		writeError(ctx, notFound(err, errTransferNotFound))
+		return
+	}
+
+	fromAccount, err := s.store.GetAccount(ctx, transfer.FromAccountID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-		} else {
-			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
+		return
+	}
+
+	toAccount, err := s.store.GetAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if auth.Authorize(authPayload, fromAccount.Owner, auth.ActionReadAccount) != nil &&
+		auth.Authorize(authPayload, toAccount.Owner, auth.ActionReadAccount) != nil {
+		writeError(ctx, errNotAuthorized.withMessage("transfer doesn't belong to the authenticated user"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, s.newTransferResponse(transfer, fromAccount.Currency, toAccount.Currency))
+}
+
+type reverseTransferRequest struct {
+	// how much to give back, in the currency of the source account of the
+	// transfer; left out to give back all that is left of it
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// reverseTransfer gives back a transfer, in full or in part, with a transfer
+// the other way. The owner of the account that received the transfer may
+// refund it, and so may admins.
+func (s *Server) reverseTransfer(ctx *gin.Context) {
+	var uri getTransferRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req reverseTransferRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			writeError(ctx, err)
+			return
 		}
-		return account, false
 	}
 
-	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
+	transfer, err := s.store.GetTransfer(ctx, uri.ID)
+	if err != nil {
+		writeError(ctx, notFound(err, errTransferNotFound))
+		return
 	}
 
-	return account, true
+	toAccount, err := s.store.GetAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := auth.Authorize(authPayload, toAccount.Owner, auth.ActionReverseTransfer); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	result, err := s.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+		Amount:     req.Amount,
+		AfterTransfer: func(q db.Querier, result db.TransferTxResult) error {
+			return s.distributor.DistributeTaskSendTransferNotification(ctx, q, &worker.PayloadSendTransferNotification{
+				TransferID: result.Transfer.ID,
+			})
+		},
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, s.newTransferTxResponse(result))
 }
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/token"
+)
+
+type setTransferLimitRequest struct {
+	Currency string `json:"currency" binding:"required,currency"`
+	// user whose accounts in the currency are limited together; with neither
+	// owner nor accountID the limits are the defaults of the currency
+	Owner     string `json:"owner" binding:"omitempty,alphanum"`
+	AccountID int64  `json:"accountID" binding:"omitempty,min=1"`
+	// amounts left out are not limited, or fall back to the defaults of the
+	// currency for an account
+	MaxAmount     *int64 `json:"maxAmount" binding:"omitempty,min=0"`
+	DailyAmount   *int64 `json:"dailyAmount" binding:"omitempty,min=0"`
+	MonthlyAmount *int64 `json:"monthlyAmount" binding:"omitempty,min=0"`
+}
+
+type transferLimitResponse struct {
+	ID            int64     `json:"id"`
+	Currency      string    `json:"currency"`
+	Owner         string    `json:"owner,omitempty"`
+	AccountID     *int64    `json:"accountID,omitempty"`
+	MaxAmount     *int64    `json:"maxAmount"`
+	DailyAmount   *int64    `json:"dailyAmount"`
+	MonthlyAmount *int64    `json:"monthlyAmount"`
+	UpdatedBy     string    `json:"updatedBy"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func newTransferLimitResponse(limit db.TransferLimit) transferLimitResponse {
+	return transferLimitResponse{
+		ID:            limit.ID,
+		Currency:      limit.Currency,
+		Owner:         limit.Owner.String,
+		AccountID:     nullableID(limit.AccountID),
+		MaxAmount:     nullableAmount(limit.MaxAmount),
+		DailyAmount:   nullableAmount(limit.DailyAmount),
+		MonthlyAmount: nullableAmount(limit.MonthlyAmount),
+		UpdatedBy:     limit.UpdatedBy,
+		UpdatedAt:     limit.UpdatedAt,
+	}
+}
+
+func nullableAmount(amount sql.NullInt64) *int64 {
+	if !amount.Valid {
+		return nil
+	}
+	return &amount.Int64
+}
+
+func toNullAmount(amount *int64) sql.NullInt64 {
+	if amount == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *amount, Valid: true}
+}
+
+// setTransferLimit sets the limits of an account, of a user in a currency or
+// the defaults of a currency, replacing the ones it had.
+func (s *Server) setTransferLimit(ctx *gin.Context) {
+	var req setTransferLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if len(req.Owner) > 0 && req.AccountID > 0 {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "accountID", Message: "must be left out with owner"}))
+		return
+	}
+
+	authPayload, valid := authorizeLimits(ctx)
+	if !valid {
+		return
+	}
+
+	if req.AccountID > 0 {
+		if _, valid := s.validateAccount(ctx, req.AccountID, req.Currency); !valid {
+			return
+		}
+	}
+
+	limit, err := s.store.UpsertTransferLimit(ctx, db.UpsertTransferLimitParams{
+		Currency:      req.Currency,
+		Owner:         sql.NullString{String: req.Owner, Valid: len(req.Owner) > 0},
+		AccountID:     sql.NullInt64{Int64: req.AccountID, Valid: req.AccountID > 0},
+		MaxAmount:     toNullAmount(req.MaxAmount),
+		DailyAmount:   toNullAmount(req.DailyAmount),
+		MonthlyAmount: toNullAmount(req.MonthlyAmount),
+		UpdatedBy:     authPayload.Username,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferLimitResponse(limit))
+}
+
+type listTransferLimitsRequest struct {
+	Currency  string `form:"currency" binding:"omitempty,currency"`
+	Owner     string `form:"owner" binding:"omitempty,alphanum"`
+	AccountID int64  `form:"account_id" binding:"omitempty,min=1"`
+	PageID    int32  `form:"page_id" binding:"required,min=1"`
+	PageSize  int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (s *Server) listTransferLimits(ctx *gin.Context) {
+	var req listTransferLimitsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if _, valid := authorizeLimits(ctx); !valid {
+		return
+	}
+
+	limits, err := s.store.ListTransferLimits(ctx, db.ListTransferLimitsParams{
+		Currency:   sql.NullString{String: req.Currency, Valid: len(req.Currency) > 0},
+		Owner:      sql.NullString{String: req.Owner, Valid: len(req.Owner) > 0},
+		AccountID:  sql.NullInt64{Int64: req.AccountID, Valid: req.AccountID > 0},
+		PageLimit:  req.PageSize,
+		PageOffset: req.PageSize * (req.PageID - 1),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	res := make([]transferLimitResponse, len(limits))
+	for i, limit := range limits {
+		res[i] = newTransferLimitResponse(limit)
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+type transferLimitRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteTransferLimit removes limits, so that what they limited falls back to
+// the defaults of the currency or isn't limited any more.
+func (s *Server) deleteTransferLimit(ctx *gin.Context) {
+	var req transferLimitRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if _, valid := authorizeLimits(ctx); !valid {
+		return
+	}
+
+	limit, err := s.store.GetTransferLimit(ctx, req.ID)
+	if err != nil {
+		writeError(ctx, notFound(err, errTransferLimitNotFound))
+		return
+	}
+
+	if err := s.store.DeleteTransferLimit(ctx, limit.ID); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// authorizeLimits makes sure the authenticated user may manage transfer
+// limits, writing the error response otherwise. Limits don't belong to the
+// users they limit, so only roles that may manage them on anyone's behalf can.
+func authorizeLimits(ctx *gin.Context) (*token.Payload, bool) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := auth.Authorize(authPayload, "", auth.ActionManageLimits); err != nil {
+		writeError(ctx, err)
+		return authPayload, false
+	}
+	return authPayload, true
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTransferLimitAPI(t *testing.T) {
+	admin := util.RandomOwner()
+	account := randomAccount(util.RandomOwner())
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Account",
+			body: gin.H{"currency": account.Currency, "accountID": account.ID, "dailyAmount": 1000},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				arg := db.UpsertTransferLimitParams{
+					Currency:    account.Currency,
+					AccountID:   sql.NullInt64{Int64: account.ID, Valid: true},
+					DailyAmount: sql.NullInt64{Int64: 1000, Valid: true},
+					UpdatedBy:   admin,
+				}
+				limit := db.TransferLimit{
+					ID:          1,
+					Currency:    arg.Currency,
+					AccountID:   arg.AccountID,
+					DailyAmount: arg.DailyAmount,
+					UpdatedBy:   admin,
+				}
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Eq(arg)).Times(1).Return(limit, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res transferLimitResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, account.ID, *res.AccountID)
+				require.Empty(t, res.Owner)
+				require.Nil(t, res.MaxAmount)
+				require.Equal(t, int64(1000), *res.DailyAmount)
+				require.Equal(t, admin, res.UpdatedBy)
+			},
+		},
+		{
+			name: "CurrencyDefaults",
+			body: gin.H{"currency": util.USD, "maxAmount": 0, "monthlyAmount": 50000},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+				arg := db.UpsertTransferLimitParams{
+					Currency:      util.USD,
+					MaxAmount:     sql.NullInt64{Int64: 0, Valid: true},
+					MonthlyAmount: sql.NullInt64{Int64: 50000, Valid: true},
+					UpdatedBy:     admin,
+				}
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferLimit{ID: 2}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OwnerNotFound",
+			body: gin.H{"currency": util.USD, "owner": "nobody", "dailyAmount": 1000},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertTransferLimit(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TransferLimit{}, &pq.Error{Code: "23503", Constraint: "transfer_limits_owner_fkey"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusForbidden, "owner_not_found")
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{"currency": util.CAD, "accountID": account.ID, "dailyAmount": 1000},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				mismatched := account
+				mismatched.Currency = util.EUR
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(mismatched, nil)
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusBadRequest, "account_currency_mismatch")
+			},
+		},
+		{
+			name: "OwnerAndAccount",
+			body: gin.H{"currency": account.Currency, "owner": account.Owner, "accountID": account.ID},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "accountID", res.Fields[0].Field)
+			},
+		},
+		{
+			name: "NegativeAmount",
+			body: gin.H{"currency": util.USD, "dailyAmount": -1},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "dailyAmount", res.Fields[0].Field)
+			},
+		},
+		{
+			name: "NotAdmin",
+			body: gin.H{"currency": util.USD, "dailyAmount": 1000},
+			role: util.BankerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/transfer_limits", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListTransferLimitsAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	owner := util.RandomOwner()
+	limits := []db.TransferLimit{
+		{ID: 3, Currency: util.USD, Owner: sql.NullString{String: owner, Valid: true}, MaxAmount: sql.NullInt64{Int64: 500, Valid: true}},
+		{ID: 4, Currency: util.EUR, Owner: sql.NullString{String: owner, Valid: true}, DailyAmount: sql.NullInt64{Int64: 900, Valid: true}},
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	arg := db.ListTransferLimitsParams{
+		Owner:      sql.NullString{String: owner, Valid: true},
+		PageLimit:  5,
+		PageOffset: 5,
+	}
+	store.EXPECT().ListTransferLimits(gomock.Any(), gomock.Eq(arg)).Times(1).Return(limits, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := fmt.Sprintf("/transfer_limits?owner=%s&page_id=2&page_size=5", owner)
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwner(), util.AdminRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var res []transferLimitResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+	require.Len(t, res, len(limits))
+	require.Equal(t, owner, res[0].Owner)
+	require.Equal(t, int64(500), *res[0].MaxAmount)
+	require.Nil(t, res[0].DailyAmount)
+	require.Nil(t, res[1].MaxAmount)
+}
+
+func TestDeleteTransferLimitAPI(t *testing.T) {
+	limit := db.TransferLimit{ID: util.RandomInt(1, 1000), Currency: util.USD}
+
+	testCases := []struct {
+		name          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferLimit(gomock.Any(), gomock.Eq(limit.ID)).Times(1).Return(limit, nil)
+				store.EXPECT().DeleteTransferLimit(gomock.Any(), gomock.Eq(limit.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferLimit(gomock.Any(), gomock.Eq(limit.ID)).Times(1).Return(db.TransferLimit{}, sql.ErrNoRows)
+				store.EXPECT().DeleteTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusNotFound, "transfer_limit_not_found")
+			},
+		},
+		{
+			name: "NotAdmin",
+			role: util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DeleteTransferLimit(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfer_limits/%d", limit.ID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, util.RandomOwner(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
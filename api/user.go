@@ -1,14 +1,15 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/token"
 	"github.com/leoomi/simplebank/util"
-	"github.com/lib/pq"
+	"github.com/leoomi/simplebank/worker"
 )
 
 type createUserRequest struct {
@@ -22,6 +23,8 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"fullName"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"isEmailVerified"`
 	PasswordChangedAt time.Time `json:"passwordChangedAt"`
 	CreatedAt         time.Time `json:"createdAt"`
 }
@@ -31,6 +34,8 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.PasswordChangedAt,
 	}
@@ -39,81 +44,264 @@ func newUserResponse(user db.User) userResponse {
 func (s *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
 	hashedPassword, err := util.HashedPassword(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username: req.Username,
-		Password: hashedPassword,
-		FullName: req.FullName,
-		Email:    req.Email,
+	secretCode, err := util.NewSecretCode()
+	if err != nil {
+		writeError(ctx, err)
+		return
 	}
 
-	user, err := s.store.CreateUser(ctx, arg)
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username: req.Username,
+			Password: hashedPassword,
+			FullName: req.FullName,
+			Email:    req.Email,
+		},
+		SecretCode: secretCode,
+		ExpiresAt:  time.Now().Add(s.config.VerifyEmailDuration),
+		AfterCreate: func(q db.Querier, user db.User, verifyEmail db.VerifyEmail) error {
+			return s.distributor.DistributeTaskSendVerifyEmail(ctx, q, &worker.PayloadSendVerifyEmail{
+				Username:   user.Username,
+				EmailID:    verifyEmail.ID,
+				SecretCode: verifyEmail.SecretCode,
+			})
+		},
+	}
+
+	result, err := s.store.CreateUserTx(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errorResponse(err))
-			}
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
-	res := newUserResponse(user)
+	res := newUserResponse(result.User)
 	ctx.JSON(http.StatusOK, res)
 }
 
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required,len=64"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail is where the link sent on signup leads. It needs no token, the
+// secret code proves that the user received the email.
+func (s *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	result, err := s.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.EmailID,
+		SecretCode: req.SecretCode,
+	})
+	if err != nil {
+		writeError(ctx, notFound(err, errInvalidVerifyCode))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+// resendVerifyEmail sends the authenticated user a new verification code, for
+// when the one sent on signup expired or got lost. A new code is sent at most
+// once every VERIFY_EMAIL_RESEND_INTERVAL, so that the endpoint can't be used
+// to flood a mailbox.
+func (s *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	secretCode, err := util.NewSecretCode()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	now := time.Now()
+	_, err = s.store.ResendVerifyEmailTx(ctx, db.ResendVerifyEmailTxParams{
+		Username:       authPayload.Username,
+		SecretCode:     secretCode,
+		ExpiresAt:      now.Add(s.config.VerifyEmailDuration),
+		LastSentBefore: now.Add(-s.config.VerifyEmailResendInterval),
+		AfterCreate: func(q db.Querier, user db.User, verifyEmail db.VerifyEmail) error {
+			return s.distributor.DistributeTaskSendVerifyEmail(ctx, q, &worker.PayloadSendVerifyEmail{
+				Username:   user.Username,
+				EmailID:    verifyEmail.ID,
+				SecretCode: verifyEmail.SecretCode,
+			})
+		},
+	})
+	if err != nil {
+		writeError(ctx, notFound(err, errUserNotFound))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// requireVerifiedEmail makes sure the user has verified their email address,
+// writing the error response otherwise.
+func (s *Server) requireVerifiedEmail(ctx *gin.Context, username string) bool {
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		writeError(ctx, notFound(err, errUserNotFound))
+		return false
+	}
+
+	if !user.IsEmailVerified {
+		writeError(ctx, errEmailNotVerified)
+		return false
+	}
+
+	return true
+}
+
 type loginUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
 	Password string `json:"password" binding:"required,min=6"`
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeError(ctx, err)
+		return
+	}
+
+	// the owner of the system accounts is never logged in as, whatever its
+	// password is set to
+	if req.Username == db.SystemOwner {
+		writeError(ctx, errUserNotFound)
 		return
 	}
 
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, notFound(err, errUserNotFound))
 		return
 	}
 
 	err = util.CheckPassword(user.Password, req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		writeError(ctx, err)
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, server.config.AccessTokenDuration)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefresh, server.config.RefreshTokenDuration)
+	if err != nil {
+		writeError(ctx, err)
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
 
 	res := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
 	}
 	ctx.JSON(http.StatusOK, res)
 }
+
+type logoutUserRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// logoutUser revokes the access token of the request and, when a refresh token
+// is given, blocks its session as well.
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if len(req.RefreshToken) > 0 {
+		refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefresh)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+
+		if refreshPayload.Username != authPayload.Username {
+			writeError(ctx, errNotAuthorized.withMessage("refresh token doesn't belong to the authenticated user"))
+			return
+		}
+
+		err = server.store.BlockSession(ctx, db.BlockSessionParams{
+			ID:       refreshPayload.ID,
+			Username: authPayload.Username,
+		})
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+
+	if err := server.revocations.RevokeToken(ctx, authPayload); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// logoutAllUser revokes every token and session of the authenticated user.
+func (server *Server) logoutAllUser(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if err := server.revocations.RevokeAllTokens(ctx, authPayload.Username); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
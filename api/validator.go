@@ -5,9 +5,17 @@ import (
 	"github.com/leoomi/simplebank/util"
 )
 
-var validCurrency validator.Func = func(fl validator.FieldLevel) bool {
+func (s *Server) validCurrency(fl validator.FieldLevel) bool {
 	if currency, ok := fl.Field().Interface().(string); ok {
-		return util.IsSupportedCurrency(currency)
+		return s.currencies.IsSupported(currency)
+	}
+	return false
+}
+
+func validCron(fl validator.FieldLevel) bool {
+	if expr, ok := fl.Field().Interface().(string); ok {
+		_, err := util.ParseCron(expr)
+		return err == nil
 	}
 	return false
 }
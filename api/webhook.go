@@ -0,0 +1,284 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
+)
+
+// webhookResponse leaves out the secret, which is only shown once, when the
+// webhook is created.
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func newWebhookResponse(webhook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:         webhook.ID,
+		Owner:      webhook.Owner,
+		Url:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+type createWebhookRequest struct {
+	Url        string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes []string `json:"eventTypes" binding:"required,min=1,dive,oneof=transfer.created account.created account.status_changed"`
+}
+
+type createWebhookResponse struct {
+	webhookResponse
+	// key of the HMAC-SHA256 signature sent with every delivery
+	Secret string `json:"secret"`
+}
+
+// createWebhook subscribes a URL of the user to events on their accounts. The
+// secret that signs the deliveries is generated here and never shown again.
+func (s *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if err := worker.ValidateWebhookURL(req.Url, s.config.WebhookAllowInsecure); err != nil {
+		writeError(ctx, errInvalidRequest.withFields(fieldError{Field: "url", Message: webhookURLMessage(err)}))
+		return
+	}
+
+	secret, err := util.NewSecretCode()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	webhook, err := s.store.CreateWebhook(ctx, db.CreateWebhookParams{
+		Owner:      authPayload.Username,
+		Url:        req.Url,
+		Secret:     secret,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createWebhookResponse{
+		webhookResponse: newWebhookResponse(webhook),
+		Secret:          webhook.Secret,
+	})
+}
+
+// webhookURLMessage describes why a webhook URL was refused.
+func webhookURLMessage(err error) string {
+	switch {
+	case errors.Is(err, worker.ErrWebhookInsecure):
+		return "must use https"
+	case errors.Is(err, worker.ErrWebhookAddress):
+		return "must not point to an internal address"
+	}
+	return "must be a valid URL"
+}
+
+type listWebhooksRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (s *Server) listWebhooks(ctx *gin.Context) {
+	var req listWebhooksRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	webhooks, err := s.store.ListWebhooks(ctx, db.ListWebhooksParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: req.PageSize * (req.PageID - 1),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	res := make([]webhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		res[i] = newWebhookResponse(webhook)
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+type webhookRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteWebhook unsubscribes a webhook. Its pending deliveries are dropped.
+func (s *Server) deleteWebhook(ctx *gin.Context) {
+	var req webhookRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	webhook, valid := s.getAuthorizedWebhook(ctx, req.ID)
+	if !valid {
+		return
+	}
+
+	if err := s.store.DeleteWebhook(ctx, webhook.ID); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// getAuthorizedWebhook loads a webhook and makes sure the authenticated user
+// may manage it, writing the error response otherwise.
+func (s *Server) getAuthorizedWebhook(ctx *gin.Context, webhookID int64) (db.Webhook, bool) {
+	webhook, err := s.store.GetWebhook(ctx, webhookID)
+	if err != nil {
+		writeError(ctx, notFound(err, errWebhookNotFound))
+		return webhook, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if err := auth.Authorize(authPayload, webhook.Owner, auth.ActionManageWebhooks); err != nil {
+		writeError(ctx, err)
+		return webhook, false
+	}
+
+	return webhook, true
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (s *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	webhook, valid := s.getAuthorizedWebhook(ctx, uri.ID)
+	if !valid {
+		return
+	}
+
+	deliveries, err := s.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     req.PageSize,
+		Offset:    req.PageSize * (req.PageID - 1),
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+type webhookDeliveryRequest struct {
+	ID         int64 `uri:"id" binding:"required,min=1"`
+	DeliveryID int64 `uri:"delivery_id" binding:"required,min=1"`
+}
+
+type webhookDeliveryResponse struct {
+	Delivery db.WebhookDelivery          `json:"delivery"`
+	Attempts []db.WebhookDeliveryAttempt `json:"attempts"`
+}
+
+// getWebhookDelivery shows a delivery along with every attempt to post it, so
+// that failures can be looked into.
+func (s *Server) getWebhookDelivery(ctx *gin.Context) {
+	var req webhookDeliveryRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	delivery, valid := s.getAuthorizedWebhookDelivery(ctx, req)
+	if !valid {
+		return
+	}
+
+	attempts, err := s.store.ListWebhookDeliveryAttempts(ctx, delivery.ID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookDeliveryResponse{
+		Delivery: delivery,
+		Attempts: attempts,
+	})
+}
+
+// replayWebhookDelivery posts a delivery again, whether it failed or not.
+func (s *Server) replayWebhookDelivery(ctx *gin.Context) {
+	var req webhookDeliveryRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	delivery, valid := s.getAuthorizedWebhookDelivery(ctx, req)
+	if !valid {
+		return
+	}
+
+	delivery, err := s.store.ReplayWebhookDeliveryTx(ctx, delivery.ID)
+	if err != nil {
+		writeError(ctx, notFound(err, errDeliveryNotFound))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+// getAuthorizedWebhookDelivery loads a delivery of a webhook the authenticated
+// user may manage, writing the error response otherwise.
+func (s *Server) getAuthorizedWebhookDelivery(ctx *gin.Context, req webhookDeliveryRequest) (db.WebhookDelivery, bool) {
+	webhook, valid := s.getAuthorizedWebhook(ctx, req.ID)
+	if !valid {
+		return db.WebhookDelivery{}, false
+	}
+
+	delivery, err := s.store.GetWebhookDelivery(ctx, req.DeliveryID)
+	if err != nil {
+		writeError(ctx, notFound(err, errDeliveryNotFound))
+		return delivery, false
+	}
+
+	if delivery.WebhookID != webhook.ID {
+		writeError(ctx, errDeliveryNotFound)
+		return delivery, false
+	}
+
+	return delivery, true
+}
@@ -0,0 +1,396 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWebhookAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	webhook := randomWebhook(user.Username)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"url":        webhook.Url,
+				"eventTypes": webhook.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhook(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateWebhookParams) (db.Webhook, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, webhook.Url, arg.Url)
+						require.Equal(t, webhook.EventTypes, arg.EventTypes)
+						require.Len(t, arg.Secret, 64)
+
+						created := webhook
+						created.Secret = arg.Secret
+						return created, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res createWebhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, webhook.ID, res.ID)
+				require.Equal(t, webhook.EventTypes, res.EventTypes)
+				require.Len(t, res.Secret, 64)
+			},
+		},
+		{
+			name: "InvalidURL",
+			body: gin.H{
+				"url":        "not a url",
+				"eventTypes": webhook.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "url", Message: "must be a valid URL"}}, res.Fields)
+			},
+		},
+		{
+			name: "PlainHTTP",
+			body: gin.H{
+				"url":        "http://example.com/hooks",
+				"eventTypes": webhook.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "url", Message: "must use https"}}, res.Fields)
+			},
+		},
+		{
+			name: "InternalAddress",
+			body: gin.H{
+				"url":        "https://169.254.169.254/latest/meta-data",
+				"eventTypes": webhook.EventTypes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "url", Message: "must not point to an internal address"}}, res.Fields)
+			},
+		},
+		{
+			name: "NoEventTypes",
+			body: gin.H{
+				"url":        webhook.Url,
+				"eventTypes": []string{},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, []fieldError{{Field: "eventTypes", Message: "must have at least 1 items"}}, res.Fields)
+			},
+		},
+		{
+			name: "UnknownEventType",
+			body: gin.H{
+				"url":        webhook.Url,
+				"eventTypes": []string{db.EventTransferCreated, "account.deleted"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				res := requireErrorCode(t, recorder, http.StatusBadRequest, "invalid_request")
+				require.Equal(t, "eventTypes[1]", res.Fields[0].Field)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListWebhooksAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	webhooks := []db.Webhook{randomWebhook(user.Username), randomWebhook(user.Username)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListWebhooks(gomock.Any(), gomock.Eq(db.ListWebhooksParams{
+			Owner:  user.Username,
+			Limit:  5,
+			Offset: 5,
+		})).
+		Times(1).
+		Return(webhooks, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/webhooks?page_id=2&page_size=5", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// the secrets are only shown when the webhooks are created
+	require.NotContains(t, recorder.Body.String(), "secret")
+
+	var res []webhookResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+	require.Len(t, res, len(webhooks))
+	require.Equal(t, webhooks[1].Url, res[1].Url)
+}
+
+func TestWebhookDeliveryAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	webhook := randomWebhook(user.Username)
+	delivery := randomWebhookDelivery(webhook.ID)
+	attempts := []db.WebhookDeliveryAttempt{
+		{ID: 1, DeliveryID: delivery.ID, ResponseStatus: http.StatusBadGateway, Error: "webhook answered with status 502"},
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		username      string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Get",
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/webhooks/%d/deliveries/%d", webhook.ID, delivery.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().ListWebhookDeliveryAttempts(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(attempts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, delivery.ID, res.Delivery.ID)
+				require.Equal(t, attempts, res.Attempts)
+			},
+		},
+		{
+			name:     "Replay",
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/webhooks/%d/deliveries/%d/replay", webhook.ID, delivery.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				replayed := delivery
+				replayed.Status = db.WebhookDeliveryPending
+
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(replayed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res db.WebhookDelivery
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+				require.Equal(t, db.WebhookDeliveryPending, res.Status)
+			},
+		},
+		{
+			name:     "DeliveryOfAnotherWebhook",
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/webhooks/%d/deliveries/%d/replay", webhook.ID, delivery.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				other := delivery
+				other.WebhookID = webhook.ID + 1
+
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(other, nil)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusNotFound, "webhook_delivery_not_found")
+			},
+		},
+		{
+			name:     "WebhookOfAnotherUser",
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/webhooks/%d/deliveries/%d/replay", webhook.ID, delivery.ID),
+			username: "unauthorized_user",
+			role:     util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReplayWebhookDeliveryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusUnauthorized, "not_authorized")
+			},
+		},
+		{
+			name:     "WebhookNotFound",
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/webhooks/%d/deliveries?page_id=1&page_size=10", webhook.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(db.Webhook{}, sql.ErrNoRows)
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusNotFound, "webhook_not_found")
+			},
+		},
+		{
+			name:     "List",
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/webhooks/%d/deliveries?page_id=1&page_size=10", webhook.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().
+					ListWebhookDeliveries(gomock.Any(), gomock.Eq(db.ListWebhookDeliveriesParams{
+						WebhookID: webhook.ID,
+						Limit:     10,
+						Offset:    0,
+					})).
+					Times(1).
+					Return([]db.WebhookDelivery{delivery}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Delete",
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/webhooks/%d", webhook.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().DeleteWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "DeleteNotFound",
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/webhooks/%d", webhook.ID),
+			username: user.Username,
+			role:     util.DepositorRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(db.Webhook{}, sql.ErrNoRows)
+				store.EXPECT().DeleteWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorCode(t, recorder, http.StatusNotFound, "webhook_not_found")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomWebhook(owner string) db.Webhook {
+	return db.Webhook{
+		ID:         util.RandomInt(1, 1000),
+		Owner:      owner,
+		Url:        "https://example.com/hooks/" + util.RandomString(8),
+		Secret:     util.RandomString(64),
+		EventTypes: []string{db.EventTransferCreated, db.EventAccountStatusChanged},
+	}
+}
+
+func randomWebhookDelivery(webhookID int64) db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:        util.RandomInt(1, 1000),
+		WebhookID: webhookID,
+		EventID:   uuid.New(),
+		EventType: db.EventTransferCreated,
+		Payload:   json.RawMessage(`{"type":"transfer.created"}`),
+		Status:    db.WebhookDeliveryFailed,
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+)
+
+// Action is something a user can do to an account, to the transfers that touch
+// it, to a webhook or to transfer limits. Managing limits also covers the
+// overdraft limits of accounts.
+type Action string
+
+const (
+	ActionReadAccount     Action = "read_account"
+	ActionMoveFunds       Action = "move_funds"
+	ActionFreezeAccount   Action = "freeze_account"
+	ActionCloseAccount    Action = "close_account"
+	ActionReverseTransfer Action = "reverse_transfer"
+	ActionManageWebhooks  Action = "manage_webhooks"
+	ActionManageLimits    Action = "manage_limits"
+)
+
+var (
+	ErrUnknownRole   = errors.New("unknown role")
+	ErrNotAuthorized = errors.New("account doesn't belong to the authenticated user")
+)
+
+// ownerActions are allowed to every role on its own accounts. Reversing a
+// transfer is authorized against the account that received it, so owners may
+// refund what they were sent but not take back what they sent.
+var ownerActions = []Action{ActionReadAccount, ActionMoveFunds, ActionCloseAccount, ActionReverseTransfer, ActionManageWebhooks}
+
+// roleActions are allowed to a role on any account, whoever owns it.
+var roleActions = map[string][]Action{
+	util.DepositorRole: {},
+	util.BankerRole:    {ActionReadAccount},
+	util.AdminRole:     {ActionReadAccount, ActionFreezeAccount, ActionCloseAccount, ActionReverseTransfer, ActionManageLimits},
+}
+
+// Authorize decides whether the token holder may perform act on an account
+// owned by owner.
+func Authorize(payload *token.Payload, owner string, act Action) error {
+	allowed, ok := roleActions[payload.Role]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownRole, payload.Role)
+	}
+
+	if owner == payload.Username && hasAction(ownerActions, act) {
+		return nil
+	}
+
+	if hasAction(allowed, act) {
+		return nil
+	}
+
+	return ErrNotAuthorized
+}
+
+func hasAction(actions []Action, act Action) bool {
+	for _, a := range actions {
+		if a == act {
+			return true
+		}
+	}
+
+	return false
+}
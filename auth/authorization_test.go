@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	owner := util.RandomOwner()
+
+	testCases := []struct {
+		role    string
+		own     []Action
+		foreign []Action
+	}{
+		{
+			role:    util.DepositorRole,
+			own:     []Action{ActionReadAccount, ActionMoveFunds, ActionCloseAccount, ActionReverseTransfer, ActionManageWebhooks},
+			foreign: []Action{},
+		},
+		{
+			role:    util.BankerRole,
+			own:     []Action{ActionReadAccount, ActionMoveFunds, ActionCloseAccount, ActionReverseTransfer, ActionManageWebhooks},
+			foreign: []Action{ActionReadAccount},
+		},
+		{
+			role:    util.AdminRole,
+			own:     []Action{ActionReadAccount, ActionMoveFunds, ActionFreezeAccount, ActionCloseAccount, ActionReverseTransfer, ActionManageWebhooks, ActionManageLimits},
+			foreign: []Action{ActionReadAccount, ActionFreezeAccount, ActionCloseAccount, ActionReverseTransfer, ActionManageLimits},
+		},
+	}
+
+	allActions := []Action{ActionReadAccount, ActionMoveFunds, ActionFreezeAccount, ActionCloseAccount, ActionReverseTransfer, ActionManageWebhooks, ActionManageLimits}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.role, func(t *testing.T) {
+			payload, err := token.NewPayload(owner, tc.role, token.TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			for _, act := range allActions {
+				err := Authorize(payload, owner, act)
+				if hasAction(tc.own, act) {
+					require.NoError(t, err, "own account: %s", act)
+				} else {
+					require.ErrorIs(t, err, ErrNotAuthorized, "own account: %s", act)
+				}
+
+				err = Authorize(payload, "another_user", act)
+				if hasAction(tc.foreign, act) {
+					require.NoError(t, err, "foreign account: %s", act)
+				} else {
+					require.ErrorIs(t, err, ErrNotAuthorized, "foreign account: %s", act)
+				}
+			}
+		})
+	}
+
+	t.Run("UnknownRole", func(t *testing.T) {
+		payload, err := token.NewPayload(owner, "janitor", token.TokenTypeAccess, time.Minute)
+		require.NoError(t, err)
+
+		err = Authorize(payload, owner, ActionReadAccount)
+		require.ErrorIs(t, err, ErrUnknownRole)
+	})
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/token"
+)
+
+const revocationSweepInterval = time.Minute
+
+// RevocationList answers whether a verified token has been revoked. Answers are
+// cached in memory so the database is not queried on every authenticated
+// request. Tokens that are still valid are only cached for ttl, which bounds how
+// long a revocation made by another server instance can go unnoticed; tokens
+// revoked through this instance are reflected in the cache right away.
+type RevocationList struct {
+	store db.Store
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	entries   map[uuid.UUID]revocationEntry
+	lastSweep time.Time
+}
+
+type revocationEntry struct {
+	username  string
+	revoked   bool
+	expiresAt time.Time
+}
+
+func NewRevocationList(store db.Store, ttl time.Duration) *RevocationList {
+	return &RevocationList{
+		store:     store,
+		ttl:       ttl,
+		entries:   make(map[uuid.UUID]revocationEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// IsRevoked tells whether a verified token has been revoked.
+func (r *RevocationList) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	if revoked, ok := r.lookup(payload.ID); ok {
+		return revoked, nil
+	}
+
+	revoked, err := r.store.IsTokenRevoked(ctx, db.IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	expiresAt := time.Now().Add(r.ttl)
+	if revoked {
+		// a revoked token stays revoked until it expires anyway
+		expiresAt = payload.ExpiredAt
+	}
+	r.set(payload.ID, revocationEntry{
+		username:  payload.Username,
+		revoked:   revoked,
+		expiresAt: expiresAt,
+	})
+
+	return revoked, nil
+}
+
+// RevokeToken revokes a single token until it expires.
+func (r *RevocationList) RevokeToken(ctx context.Context, payload *token.Payload) error {
+	err := r.store.CreateRevokedToken(ctx, db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.set(payload.ID, revocationEntry{
+		username:  payload.Username,
+		revoked:   true,
+		expiresAt: payload.ExpiredAt,
+	})
+
+	return nil
+}
+
+// RevokeAllTokens revokes every token issued to the user so far and blocks all
+// of their sessions.
+func (r *RevocationList) RevokeAllTokens(ctx context.Context, username string) error {
+	err := r.store.RevokeAllTokensTx(ctx, db.RevokeAllTokensTxParams{
+		Username:  username,
+		RevokedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, entry := range r.entries {
+		if entry.username == username {
+			delete(r.entries, id)
+		}
+	}
+
+	return nil
+}
+
+func (r *RevocationList) lookup(id uuid.UUID) (revoked bool, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.revoked, true
+}
+
+func (r *RevocationList) set(id uuid.UUID, entry revocationEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[id] = entry
+
+	now := time.Now()
+	if now.Sub(r.lastSweep) < revocationSweepInterval {
+		return
+	}
+
+	for id, entry := range r.entries {
+		if now.After(entry.expiresAt) {
+			delete(r.entries, id)
+		}
+	}
+	r.lastSweep = now
+}
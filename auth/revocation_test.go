@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationListCachesAnswers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+
+	revocations := NewRevocationList(store, time.Minute)
+	payload, err := token.NewPayload(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		revoked, err := revocations.IsRevoked(context.Background(), payload)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	}
+}
+
+func TestRevocationListRevokeToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+	store.EXPECT().CreateRevokedToken(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+	revocations := NewRevocationList(store, time.Minute)
+	payload, err := token.NewPayload(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := revocations.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = revocations.RevokeToken(context.Background(), payload)
+	require.NoError(t, err)
+
+	// the cached answer is replaced without asking the database again
+	revoked, err = revocations.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevocationListRevokeAllTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := util.RandomOwner()
+	payload, err := token.NewPayload(username, util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	otherPayload, err := token.NewPayload(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(2).Return(false, nil),
+		store.EXPECT().RevokeAllTokensTx(gomock.Any(), gomock.Any()).Times(1).Return(nil),
+		store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(true, nil),
+	)
+
+	revocations := NewRevocationList(store, time.Minute)
+
+	for _, p := range []*token.Payload{payload, otherPayload} {
+		revoked, err := revocations.IsRevoked(context.Background(), p)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	}
+
+	err = revocations.RevokeAllTokens(context.Background(), username)
+	require.NoError(t, err)
+
+	// only the cached answers of the user are dropped
+	revoked, err := revocations.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = revocations.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
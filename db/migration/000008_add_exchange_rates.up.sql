@@ -0,0 +1,27 @@
+CREATE TABLE "rates" (
+  "base_currency" varchar NOT NULL,
+  "quote_currency" varchar NOT NULL,
+  "rate" numeric NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("base_currency", "quote_currency")
+);
+
+ALTER TABLE "rates" ADD CONSTRAINT "rate_positive" CHECK ("rate" > 0);
+
+COMMENT ON COLUMN "rates"."rate" IS 'units of the quote currency bought by one unit of the base currency';
+
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+
+ALTER TABLE "transfers" ADD COLUMN "rate_timestamp" timestamptz;
+
+UPDATE "transfers" SET "to_amount" = "amount", "rate_timestamp" = "created_at";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ALTER COLUMN "rate_timestamp" SET NOT NULL;
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited, in the currency of the destination account';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'rate used to convert amount into to_amount';
@@ -0,0 +1,20 @@
+CREATE TABLE "currencies" (
+  "code" varchar PRIMARY KEY,
+  "numeric_code" integer UNIQUE NOT NULL,
+  "minor_unit" integer NOT NULL,
+  "symbol" varchar NOT NULL,
+  "enabled" boolean NOT NULL DEFAULT true
+);
+
+ALTER TABLE "currencies" ADD CONSTRAINT "minor_unit_range" CHECK ("minor_unit" BETWEEN 0 AND 4);
+
+COMMENT ON COLUMN "currencies"."minor_unit" IS 'digits after the decimal separator';
+
+INSERT INTO "currencies" ("code", "numeric_code", "minor_unit", "symbol", "enabled") VALUES
+  ('CAD', 124, 2, 'CA$', true),
+  ('EUR', 978, 2, '€', true),
+  ('GBP', 826, 2, '£', false),
+  ('JPY', 392, 0, '¥', false),
+  ('USD', 840, 2, '$', true);
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "tasks";
\ No newline at end of file
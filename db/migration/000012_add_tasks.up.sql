@@ -0,0 +1,17 @@
+CREATE TABLE "tasks" (
+  "id" bigserial PRIMARY KEY,
+  "type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" int NOT NULL DEFAULT 0,
+  "max_attempts" int NOT NULL,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "status_supported" CHECK ("status" IN ('pending', 'running', 'done', 'dead'))
+);
+
+CREATE INDEX ON "tasks" ("status", "run_at");
+
+COMMENT ON COLUMN "tasks"."run_at" IS 'when a pending task is due, or when the lease of a running task runs out';
@@ -0,0 +1,52 @@
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "schedule" varchar NOT NULL DEFAULT '',
+  "failure_policy" varchar NOT NULL DEFAULT 'retry',
+  "status" varchar NOT NULL DEFAULT 'active',
+  "next_run_at" timestamptz NOT NULL,
+  "attempts" int NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "amount_positive" CHECK ("amount" > 0),
+  CONSTRAINT "failure_policy_supported" CHECK ("failure_policy" IN ('retry', 'skip')),
+  CONSTRAINT "status_supported" CHECK ("status" IN ('active', 'paused', 'completed', 'failed'))
+);
+
+CREATE TABLE "scheduled_transfer_runs" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "scheduled_for" timestamptz NOT NULL,
+  "outcome" varchar NOT NULL,
+  "transfer_id" bigint,
+  "error" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "outcome_supported" CHECK ("outcome" IN ('succeeded', 'retrying', 'skipped'))
+);
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+
+CREATE INDEX ON "scheduled_transfers" ("status", "next_run_at");
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
+
+COMMENT ON COLUMN "scheduled_transfers"."schedule" IS 'cron expression of a recurring transfer, empty for a one-off';
+
+COMMENT ON COLUMN "scheduled_transfers"."failure_policy" IS 'whether a run that fails for lack of funds or an inactive account is retried before being skipped';
+
+COMMENT ON COLUMN "scheduled_transfers"."attempts" IS 'failed attempts at the current run';
+
+COMMENT ON COLUMN "scheduled_transfer_runs"."transfer_id" IS 'transfer made by the run, null unless it succeeded';
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
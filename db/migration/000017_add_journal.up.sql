@@ -0,0 +1,85 @@
+CREATE TABLE "journal_transactions" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "tx_id" bigint NOT NULL DEFAULT (txid_current()),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "kind_supported" CHECK ("kind" IN ('opening_balance', 'transfer', 'reversal'))
+);
+
+CREATE INDEX ON "journal_transactions" ("tx_id");
+
+COMMENT ON COLUMN "journal_transactions"."tx_id" IS 'database transaction that wrote the journal, so that it can be checked to balance before commit';
+
+ALTER TABLE "entries" ADD COLUMN "journal_id" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'transfer the entry was made for, null for entries of other journals';
+
+ALTER TABLE "accounts" ADD COLUMN "kind" varchar NOT NULL DEFAULT 'customer';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "kind_supported" CHECK ("kind" IN ('customer', 'suspense', 'exchange'));
+
+COMMENT ON COLUMN "accounts"."kind" IS 'customer accounts hold money of their owner, the others balance the ledger for money entering it or changing currency';
+
+ALTER TABLE "accounts" DROP CONSTRAINT "owner_currency_key";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency", "kind");
+
+-- system accounts are owned by a user nobody can log in as; its password is no
+-- bcrypt hash, so no password matches it, and its name isn't alphanumeric, so
+-- no customer can have taken it
+INSERT INTO "users" ("username", "password", "full_name", "email") VALUES
+  ('simplebank_ledger', '!', 'Simple Bank', 'ledger@simplebank.invalid');
+
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind")
+SELECT 'simplebank_ledger', 0, "code", "kind"
+FROM "currencies" CROSS JOIN (VALUES ('suspense'), ('exchange')) AS "kinds" ("kind");
+
+-- entries written before journals existed go into one opening journal, along
+-- with whatever it takes to bring every account to its balance, offset per
+-- currency by the suspense account
+INSERT INTO "journal_transactions" ("kind") VALUES ('opening_balance');
+
+UPDATE "entries" SET "journal_id" = currval('journal_transactions_id_seq');
+
+INSERT INTO "entries" ("account_id", "amount", "journal_id")
+SELECT a."id", a."balance" - COALESCE(SUM(e."amount"), 0), currval('journal_transactions_id_seq')
+FROM "accounts" a
+LEFT JOIN "entries" e ON e."account_id" = a."id"
+WHERE a."kind" = 'customer'
+GROUP BY a."id"
+HAVING a."balance" <> COALESCE(SUM(e."amount"), 0);
+
+INSERT INTO "entries" ("account_id", "amount", "journal_id")
+SELECT s."id", -SUM(a."balance"), currval('journal_transactions_id_seq')
+FROM "accounts" s
+JOIN "accounts" a ON a."currency" = s."currency" AND a."kind" = 'customer'
+WHERE s."kind" = 'suspense'
+GROUP BY s."id"
+HAVING SUM(a."balance") <> 0;
+
+UPDATE "accounts" a
+  SET "balance" = (SELECT COALESCE(SUM(e."amount"), 0) FROM "entries" e WHERE e."account_id" = a."id")
+WHERE a."kind" = 'suspense';
+
+-- transfers write their entries in the same transaction, so they share its
+-- timestamp
+UPDATE "entries" e
+  SET "transfer_id" = t."id"
+FROM "transfers" t
+WHERE e."created_at" = t."created_at"
+  AND (
+    (e."account_id" = t."from_account_id" AND e."amount" = -t."amount")
+    OR (e."account_id" = t."to_account_id" AND e."amount" = t."to_amount")
+  );
+
+ALTER TABLE "entries" ALTER COLUMN "journal_id" SET NOT NULL;
+
+CREATE INDEX ON "entries" ("journal_id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("journal_id") REFERENCES "journal_transactions" ("id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
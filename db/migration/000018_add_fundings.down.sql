@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS "fundings";
+
+DELETE FROM "entries" WHERE "journal_id" IN (SELECT "id" FROM "journal_transactions" WHERE "kind" IN ('deposit', 'withdrawal'));
+
+DELETE FROM "journal_transactions" WHERE "kind" IN ('deposit', 'withdrawal');
+
+DELETE FROM "transfers" WHERE "from_account_id" IN (SELECT "id" FROM "accounts" WHERE "kind" = 'clearing')
+  OR "to_account_id" IN (SELECT "id" FROM "accounts" WHERE "kind" = 'clearing');
+
+DELETE FROM "accounts" WHERE "kind" = 'clearing';
+
+ALTER TABLE IF EXISTS "journal_transactions" DROP CONSTRAINT IF EXISTS "kind_supported";
+
+ALTER TABLE IF EXISTS "journal_transactions" ADD CONSTRAINT "kind_supported" CHECK ("kind" IN ('opening_balance', 'transfer', 'reversal'));
+
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "kind_supported";
+
+ALTER TABLE IF EXISTS "accounts" ADD CONSTRAINT "kind_supported" CHECK ("kind" IN ('customer', 'suspense', 'exchange'));
\ No newline at end of file
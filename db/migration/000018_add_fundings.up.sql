@@ -0,0 +1,23 @@
+ALTER TABLE "accounts" DROP CONSTRAINT "kind_supported";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "kind_supported" CHECK ("kind" IN ('customer', 'suspense', 'exchange', 'clearing'));
+
+ALTER TABLE "journal_transactions" DROP CONSTRAINT "kind_supported";
+
+ALTER TABLE "journal_transactions" ADD CONSTRAINT "kind_supported" CHECK ("kind" IN ('opening_balance', 'transfer', 'reversal', 'deposit', 'withdrawal'));
+
+INSERT INTO "accounts" ("owner", "balance", "currency", "kind")
+SELECT 'simplebank_ledger', 0, "code", 'clearing'
+FROM "currencies";
+
+CREATE TABLE "fundings" (
+  "transfer_id" bigint PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "reference" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "kind_supported" CHECK ("kind" IN ('deposit', 'withdrawal'))
+);
+
+COMMENT ON COLUMN "fundings"."reference" IS 'what the funding gateway calls the payment';
+
+ALTER TABLE "fundings" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
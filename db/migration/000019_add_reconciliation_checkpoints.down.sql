@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "reconciliation_checkpoints";
\ No newline at end of file
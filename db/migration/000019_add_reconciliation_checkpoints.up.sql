@@ -0,0 +1,10 @@
+CREATE TABLE "reconciliation_checkpoints" (
+  "account_id" bigint PRIMARY KEY,
+  "balance" bigint NOT NULL,
+  "last_entry_id" bigint NOT NULL,
+  "verified_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "reconciliation_checkpoints"."balance" IS 'balance the entries of the account added up to, up to and including last_entry_id';
+
+ALTER TABLE "reconciliation_checkpoints" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
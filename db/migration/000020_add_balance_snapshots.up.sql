@@ -0,0 +1,14 @@
+CREATE TABLE "balance_snapshots" (
+  "account_id" bigint NOT NULL,
+  "day" date NOT NULL,
+  "closing_at" timestamptz NOT NULL,
+  "balance" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "day")
+);
+
+CREATE INDEX ON "balance_snapshots" ("day");
+
+COMMENT ON COLUMN "balance_snapshots"."closing_at" IS 'end of the day in UTC; the balance adds up the entries written before it';
+
+ALTER TABLE "balance_snapshots" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,34 @@
+CREATE TABLE "transfer_limits" (
+  "id" bigserial PRIMARY KEY,
+  "currency" varchar NOT NULL,
+  "owner" varchar,
+  "account_id" bigint,
+  "max_amount" bigint,
+  "daily_amount" bigint,
+  "monthly_amount" bigint,
+  "updated_by" varchar NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "single_scope" CHECK ("owner" IS NULL OR "account_id" IS NULL),
+  CONSTRAINT "amounts_non_negative" CHECK ("max_amount" >= 0 AND "daily_amount" >= 0 AND "monthly_amount" >= 0),
+  CONSTRAINT "scope_key" UNIQUE NULLS NOT DISTINCT ("currency", "owner", "account_id")
+);
+
+CREATE INDEX ON "transfer_limits" ("owner");
+
+CREATE INDEX ON "transfer_limits" ("account_id");
+
+COMMENT ON COLUMN "transfer_limits"."owner" IS 'limits every account of the user in the currency together; a row with neither owner nor account holds the defaults of the currency for accounts';
+
+COMMENT ON COLUMN "transfer_limits"."max_amount" IS 'largest single transfer, null for no limit of its own';
+
+COMMENT ON COLUMN "transfer_limits"."daily_amount" IS 'most that may be sent since midnight UTC, null for no limit of its own';
+
+COMMENT ON COLUMN "transfer_limits"."monthly_amount" IS 'most that may be sent since the start of the month in UTC, null for no limit of its own';
+
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,3 @@
+ALTER TABLE "outbox" ADD COLUMN "lease_expires_at" timestamptz;
+
+COMMENT ON COLUMN "outbox"."lease_expires_at" IS 'set while a relay publishes the event; other relays skip it until it expires';
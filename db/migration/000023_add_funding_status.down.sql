@@ -0,0 +1,23 @@
+DELETE FROM "fundings" WHERE "status" <> 'settled';
+
+ALTER TABLE IF EXISTS "fundings" DROP CONSTRAINT IF EXISTS "fundings_account_id_fkey";
+
+ALTER TABLE IF EXISTS "fundings" DROP CONSTRAINT IF EXISTS "status_supported";
+
+ALTER TABLE IF EXISTS "fundings" DROP CONSTRAINT IF EXISTS "fundings_transfer_id_key";
+
+ALTER TABLE IF EXISTS "fundings" DROP CONSTRAINT IF EXISTS "fundings_pkey";
+
+ALTER TABLE IF EXISTS "fundings" DROP COLUMN IF EXISTS "id";
+
+ALTER TABLE IF EXISTS "fundings" DROP COLUMN IF EXISTS "account_id";
+
+ALTER TABLE IF EXISTS "fundings" DROP COLUMN IF EXISTS "amount";
+
+ALTER TABLE IF EXISTS "fundings" DROP COLUMN IF EXISTS "status";
+
+ALTER TABLE IF EXISTS "fundings" DROP COLUMN IF EXISTS "updated_at";
+
+ALTER TABLE IF EXISTS "fundings" ALTER COLUMN "reference" SET NOT NULL;
+
+ALTER TABLE IF EXISTS "fundings" ADD PRIMARY KEY ("transfer_id");
\ No newline at end of file
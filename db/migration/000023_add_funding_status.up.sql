@@ -0,0 +1,43 @@
+ALTER TABLE "fundings" DROP CONSTRAINT "fundings_pkey";
+
+ALTER TABLE "fundings" ADD COLUMN "id" bigserial PRIMARY KEY;
+
+ALTER TABLE "fundings" ADD COLUMN "account_id" bigint;
+
+ALTER TABLE "fundings" ADD COLUMN "amount" bigint;
+
+ALTER TABLE "fundings" ADD COLUMN "status" varchar NOT NULL DEFAULT 'settled';
+
+ALTER TABLE "fundings" ADD COLUMN "updated_at" timestamptz NOT NULL DEFAULT (now());
+
+UPDATE "fundings" f
+  SET
+    "account_id" = CASE f."kind" WHEN 'deposit' THEN t."to_account_id" ELSE t."from_account_id" END,
+    "amount" = t."amount",
+    "updated_at" = f."created_at"
+FROM "transfers" t
+WHERE t."id" = f."transfer_id";
+
+ALTER TABLE "fundings" ALTER COLUMN "account_id" SET NOT NULL;
+
+ALTER TABLE "fundings" ALTER COLUMN "amount" SET NOT NULL;
+
+ALTER TABLE "fundings" ALTER COLUMN "status" DROP DEFAULT;
+
+ALTER TABLE "fundings" ALTER COLUMN "transfer_id" DROP NOT NULL;
+
+ALTER TABLE "fundings" ALTER COLUMN "reference" DROP NOT NULL;
+
+ALTER TABLE "fundings" ADD CONSTRAINT "fundings_transfer_id_key" UNIQUE ("transfer_id");
+
+ALTER TABLE "fundings" ADD CONSTRAINT "status_supported" CHECK ("status" IN ('pending', 'settled', 'failed'));
+
+CREATE INDEX ON "fundings" ("account_id");
+
+COMMENT ON COLUMN "fundings"."transfer_id" IS 'transfer that moved the money: made when a withdrawal is asked for, and when a deposit is settled';
+
+COMMENT ON COLUMN "fundings"."reference" IS 'what the funding gateway calls the payment, null until it is settled';
+
+COMMENT ON COLUMN "fundings"."status" IS 'pending while the gateway is asked to move the money; a failed withdrawal is given back to the account';
+
+ALTER TABLE "fundings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "fundings_id_idx";
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX ON "fundings" ("id") WHERE "status" = 'pending';
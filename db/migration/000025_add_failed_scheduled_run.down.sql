@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS "scheduled_transfer_runs" DROP CONSTRAINT IF EXISTS "outcome_supported";
+
+UPDATE "scheduled_transfer_runs" SET "outcome" = 'skipped' WHERE "outcome" = 'failed';
+
+ALTER TABLE IF EXISTS "scheduled_transfer_runs" ADD CONSTRAINT "outcome_supported" CHECK ("outcome" IN ('succeeded', 'retrying', 'skipped'));
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "scheduled_transfer_runs" DROP CONSTRAINT "outcome_supported";
+
+ALTER TABLE "scheduled_transfer_runs" ADD CONSTRAINT "outcome_supported" CHECK ("outcome" IN ('succeeded', 'retrying', 'skipped', 'failed'));
@@ -35,6 +35,33 @@ UPDATE accounts
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
--- name: DeleteAccount :exec
-DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+  SET overdraft_limit = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+  SET status = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: GetSystemAccount :one
+SELECT * FROM accounts
+WHERE kind = $1 AND currency = $2
+LIMIT 1;
+
+-- name: CreateSystemAccounts :execrows
+-- Opens the system accounts of every currency that has none yet, such as a
+-- currency added after the migrations that opened them.
+INSERT INTO accounts (
+  owner,
+  balance,
+  currency,
+  kind
+)
+SELECT sqlc.arg(owner)::varchar, 0, c.code, k.kind
+FROM currencies c
+CROSS JOIN (VALUES ('suspense'), ('exchange'), ('clearing')) AS k (kind)
+ON CONFLICT (owner, currency, kind) DO NOTHING;
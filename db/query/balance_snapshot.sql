@@ -0,0 +1,50 @@
+-- name: CreateBalanceSnapshots :execrows
+-- Snapshots the closing balance of the day of every account that existed by
+-- then, adding the entries of the day onto the snapshot of the day before.
+-- Days must be snapshotted in order. A day that was already snapshotted is
+-- left as it was.
+INSERT INTO balance_snapshots (
+  account_id,
+  day,
+  closing_at,
+  balance
+)
+SELECT
+  a.id,
+  sqlc.arg(day)::date,
+  sqlc.arg(closing_at)::timestamptz,
+  COALESCE(prev.balance, 0) + COALESCE((
+    SELECT SUM(e.amount) FROM entries e
+    WHERE e.account_id = a.id
+      AND e.created_at >= COALESCE(prev.closing_at, '-infinity'::timestamptz)
+      AND e.created_at < sqlc.arg(closing_at)::timestamptz
+  ), 0)
+FROM accounts a
+LEFT JOIN LATERAL (
+  SELECT s.balance, s.closing_at FROM balance_snapshots s
+  WHERE s.account_id = a.id AND s.closing_at < sqlc.arg(closing_at)::timestamptz
+  ORDER BY s.closing_at DESC
+  LIMIT 1
+) prev ON true
+WHERE a.created_at < sqlc.arg(closing_at)::timestamptz
+ON CONFLICT (account_id, day) DO NOTHING;
+
+-- name: GetLatestBalanceSnapshotDay :one
+SELECT day FROM balance_snapshots
+ORDER BY day DESC
+LIMIT 1;
+
+-- name: GetAccountBalanceAt :one
+-- Adds the entries written up to at onto the latest snapshot taken by then,
+-- or onto zero when there is none.
+WITH snapshot AS (
+  SELECT balance, closing_at FROM balance_snapshots
+  WHERE account_id = sqlc.arg(account_id) AND closing_at <= sqlc.arg(at)
+  ORDER BY closing_at DESC
+  LIMIT 1
+)
+SELECT (COALESCE((SELECT balance FROM snapshot), 0) + COALESCE(SUM(e.amount), 0))::bigint AS balance
+FROM entries e
+WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at >= COALESCE((SELECT closing_at FROM snapshot), '-infinity'::timestamptz)
+  AND e.created_at < sqlc.arg(at);
@@ -1,9 +1,11 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  journal_id,
+  transfer_id
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -13,15 +15,51 @@ WHERE id = $1 LIMIT 1;
 -- name: ListEntriesFromAccount :many
 SELECT * FROM entries
 WHERE account_id = $1
+ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListAccountStatement :many
+-- running_balance is the account balance right after the entry was applied.
+-- It is computed over every entry of the account before filtering, so it stays
+-- correct for any date range or direction.
+SELECT id, account_id, amount, created_at, running_balance FROM (
+  SELECT
+    e.id,
+    e.account_id,
+    e.amount,
+    e.created_at,
+    (a.balance - SUM(e.amount) OVER (ORDER BY e.id DESC) + e.amount)::bigint AS running_balance
+  FROM entries e
+  JOIN accounts a ON a.id = e.account_id
+  WHERE e.account_id = sqlc.arg(account_id)
+) AS statement
+WHERE (sqlc.narg(from_time)::timestamptz IS NULL OR created_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR created_at < sqlc.narg(to_time))
+  AND (
+    sqlc.narg(direction)::varchar IS NULL
+    OR (sqlc.narg(direction) = 'credit' AND amount > 0)
+    OR (sqlc.narg(direction) = 'debit' AND amount < 0)
+  )
+ORDER BY id
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
 -- name: ListEntries :many
 SELECT * FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: GetAccountLedgerBalance :one
+-- entries_balance is what the balance of the account adds up to from its
+-- entries. Both are read in one statement, so that they are consistent.
+SELECT a.id, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = $1
+GROUP BY a.id;
+
 -- name: UpdateEntry :one
 UPDATE entries
   SET amount = $2
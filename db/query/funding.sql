@@ -0,0 +1,44 @@
+-- name: ClaimPendingFunding :one
+-- Takes the funding that has been pending the longest, provided nothing touched
+-- it since stale_before, skipping the ones other sweepers are claiming. It is
+-- touched in turn, so that they leave it alone for as long again.
+UPDATE fundings
+SET updated_at = now()
+WHERE id = (
+  SELECT id FROM fundings
+  WHERE status = 'pending' AND updated_at < sqlc.arg(stale_before)
+  ORDER BY id
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CreateFunding :one
+INSERT INTO fundings (
+  account_id,
+  amount,
+  kind,
+  status,
+  transfer_id
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetFundingByTransfer :one
+SELECT * FROM fundings
+WHERE transfer_id = $1 LIMIT 1;
+
+-- name: GetFundingForUpdate :one
+SELECT * FROM fundings
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateFunding :one
+UPDATE fundings
+SET
+  status = $2,
+  reference = $3,
+  transfer_id = $4,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
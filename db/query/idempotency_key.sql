@@ -0,0 +1,24 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+  username,
+  idempotency_key,
+  request_hash,
+  transfer_id,
+  response,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (username, idempotency_key) DO UPDATE
+  SET request_hash = EXCLUDED.request_hash,
+      transfer_id = EXCLUDED.transfer_id,
+      response = EXCLUDED.response,
+      created_at = now(),
+      expires_at = EXCLUDED.expires_at
+  WHERE idempotency_keys.expires_at <= now()
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND idempotency_key = $2 AND expires_at > now()
+LIMIT 1;
@@ -0,0 +1,19 @@
+-- name: CreateJournalTransaction :one
+INSERT INTO journal_transactions (
+  kind
+) VALUES (
+  $1
+) RETURNING *;
+
+-- name: ListUnbalancedJournalTransactions :many
+-- Lists the journal transactions written by the current database transaction
+-- whose entries don't add up to zero in a currency. Nothing is listed when the
+-- transaction hasn't written anything yet.
+SELECT j.id, a.currency, SUM(e.amount)::bigint AS total
+FROM journal_transactions j
+JOIN entries e ON e.journal_id = j.id
+JOIN accounts a ON a.id = e.account_id
+WHERE j.tx_id = txid_current_if_assigned()
+GROUP BY j.id, a.currency
+HAVING SUM(e.amount) <> 0
+ORDER BY j.id, a.currency;
@@ -0,0 +1,37 @@
+-- name: ClaimOutboxEvents :many
+-- Claims the oldest events that haven't been published yet until the lease
+-- expires, skipping the ones another relay holds a lease on.
+UPDATE outbox
+SET lease_expires_at = sqlc.arg(lease_expires_at)
+WHERE id IN (
+  SELECT id FROM outbox
+  WHERE published_at IS NULL
+    AND (lease_expires_at IS NULL OR lease_expires_at <= now())
+  ORDER BY id
+  LIMIT sqlc.arg(batch_size)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  event_id,
+  event_type,
+  payload,
+  created_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: MarkOutboxEventsPublished :exec
+UPDATE outbox
+SET published_at = now()
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: ReleaseOutboxEvents :exec
+-- Gives up the lease on events that weren't published, so that the next relay
+-- picks them up without waiting for the lease to expire.
+UPDATE outbox
+SET lease_expires_at = NULL
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+  AND published_at IS NULL;
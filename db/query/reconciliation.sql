@@ -0,0 +1,49 @@
+-- name: ListAccountLedgerBalances :many
+-- Pages through the accounts in ID order with what their entries add up to.
+-- Incremental runs start from the checkpoint of an account, only adding the
+-- entries written after it. last_entry_id is the last entry that was added up;
+-- entries are only written under the lock of their account, so later entries
+-- of an account cannot commit before it.
+SELECT
+  a.id,
+  a.currency,
+  a.balance,
+  (COALESCE(c.balance, 0) + COALESCE(SUM(e.amount), 0))::bigint AS entries_balance,
+  COALESCE(MAX(e.id), c.last_entry_id, 0)::bigint AS last_entry_id
+FROM accounts a
+LEFT JOIN reconciliation_checkpoints c ON c.account_id = a.id AND sqlc.arg(incremental)::boolean
+LEFT JOIN entries e ON e.account_id = a.id AND e.id > COALESCE(c.last_entry_id, 0)
+WHERE a.id > sqlc.arg(after_id)
+GROUP BY a.id, c.balance, c.last_entry_id
+ORDER BY a.id
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListTransferEntryTotals :many
+-- Pages through the transfers in ID order with what their entries take from
+-- the source account and give the destination account.
+SELECT
+  t.id,
+  t.from_account_id,
+  t.to_account_id,
+  t.amount,
+  t.to_amount,
+  COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.from_account_id), 0)::bigint AS from_entries,
+  COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.to_account_id), 0)::bigint AS to_entries
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+WHERE t.id > sqlc.arg(after_id)
+GROUP BY t.id
+ORDER BY t.id
+LIMIT sqlc.arg(page_limit);
+
+-- name: UpsertReconciliationCheckpoint :exec
+INSERT INTO reconciliation_checkpoints (
+  account_id,
+  balance,
+  last_entry_id
+) VALUES (
+  $1, $2, $3
+) ON CONFLICT (account_id) DO UPDATE
+  SET balance = EXCLUDED.balance,
+      last_entry_id = EXCLUDED.last_entry_id,
+      verified_at = now();
@@ -0,0 +1,20 @@
+-- name: CreateRevokedToken :exec
+INSERT INTO revoked_tokens (
+  id,
+  username,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) ON CONFLICT (id) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+-- A token is revoked when its ID was revoked on its own or when it was issued
+-- before the user last revoked all of their tokens.
+SELECT EXISTS (
+  SELECT 1 FROM revoked_tokens
+  WHERE revoked_tokens.id = sqlc.arg(id)
+) OR EXISTS (
+  SELECT 1 FROM users
+  WHERE users.username = sqlc.arg(username)
+    AND users.tokens_revoked_at >= sqlc.arg(issued_at)
+) AS revoked;
@@ -0,0 +1,58 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  schedule,
+  failure_policy,
+  next_run_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListDueScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= now()
+ORDER BY next_run_at, id
+LIMIT $1;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  amount = $2,
+  schedule = $3,
+  failure_policy = $4,
+  status = $5,
+  next_run_at = $6,
+  attempts = 0,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: AdvanceScheduledTransfer :one
+-- Moves a scheduled transfer on after a run, provided it is still due at the
+-- time the run was for. Nothing is returned when another run got there first.
+UPDATE scheduled_transfers
+SET
+  status = sqlc.arg(status),
+  next_run_at = sqlc.arg(next_run_at),
+  attempts = sqlc.arg(attempts),
+  updated_at = now()
+WHERE id = sqlc.arg(id) AND status = 'active' AND next_run_at = sqlc.arg(scheduled_for)
+RETURNING *;
+
+-- name: DeleteScheduledTransfer :exec
+DELETE FROM scheduled_transfers
+WHERE id = $1;
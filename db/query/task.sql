@@ -0,0 +1,55 @@
+-- name: CreateTask :one
+INSERT INTO tasks (
+  type,
+  payload,
+  max_attempts,
+  run_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ClaimTask :one
+-- Takes the task that has been due the longest, skipping the ones other workers
+-- are claiming at the same time. Running tasks whose lease ran out are claimed
+-- again, so that a crashed worker doesn't lose them.
+UPDATE tasks
+SET
+  status = 'running',
+  attempts = attempts + 1,
+  run_at = sqlc.arg(lease_expires_at),
+  updated_at = now()
+WHERE id = (
+  SELECT id FROM tasks
+  WHERE status IN ('pending', 'running') AND run_at <= now()
+  ORDER BY run_at, id
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteTask :exec
+UPDATE tasks
+SET
+  status = 'done',
+  updated_at = now()
+WHERE id = $1;
+
+-- name: RetryTask :one
+UPDATE tasks
+SET
+  status = 'pending',
+  run_at = $2,
+  last_error = $3,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: FailTask :one
+-- Gives up on a task, leaving it in the dead state for someone to look at.
+UPDATE tasks
+SET
+  status = 'dead',
+  last_error = $2,
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
@@ -2,15 +2,24 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate,
+  rate_timestamp,
+  reversal_of
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 ORDER BY id
@@ -31,6 +40,44 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListUserTransfers :many
+-- Keyset paginated feed of every transfer touching an account of the owner,
+-- newest first. Pass the last ID of the previous page as cursor.
+SELECT
+  t.*,
+  (CASE
+    WHEN fa.owner = sqlc.arg(owner) AND ta.owner = sqlc.arg(owner) THEN 'internal'
+    WHEN fa.owner = sqlc.arg(owner) THEN 'outgoing'
+    ELSE 'incoming'
+  END)::varchar AS direction,
+  fa.currency AS from_currency,
+  ta.currency AS to_currency
+FROM transfers t
+JOIN accounts fa ON fa.id = t.from_account_id
+JOIN accounts ta ON ta.id = t.to_account_id
+WHERE (fa.owner = sqlc.arg(owner) OR ta.owner = sqlc.arg(owner))
+  AND (sqlc.narg(cursor)::bigint IS NULL OR t.id < sqlc.narg(cursor))
+  AND (
+    sqlc.narg(counterparty_account_id)::bigint IS NULL
+    OR t.from_account_id = sqlc.narg(counterparty_account_id)
+    OR t.to_account_id = sqlc.narg(counterparty_account_id)
+  )
+  AND (sqlc.narg(min_amount)::bigint IS NULL OR t.amount >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount)::bigint IS NULL OR t.amount <= sqlc.narg(max_amount))
+  AND (sqlc.narg(from_time)::timestamptz IS NULL OR t.created_at >= sqlc.narg(from_time))
+  AND (sqlc.narg(to_time)::timestamptz IS NULL OR t.created_at < sqlc.narg(to_time))
+ORDER BY t.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: SumTransferReversals :one
+-- Totals what the reversals of a transfer took from its destination account
+-- (amount) and gave back to its source account (to_amount).
+SELECT
+  COALESCE(SUM(amount), 0)::bigint AS amount,
+  COALESCE(SUM(to_amount), 0)::bigint AS to_amount
+FROM transfers
+WHERE reversal_of = sqlc.arg(transfer_id)::bigint;
+
 -- name: UpdateTransfer :one
 UPDATE transfers
   SET amount = $2
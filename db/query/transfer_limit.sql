@@ -0,0 +1,71 @@
+-- name: UpsertTransferLimit :one
+INSERT INTO transfer_limits (
+  currency,
+  owner,
+  account_id,
+  max_amount,
+  daily_amount,
+  monthly_amount,
+  updated_by
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) ON CONFLICT ON CONSTRAINT scope_key DO UPDATE
+  SET max_amount = EXCLUDED.max_amount,
+      daily_amount = EXCLUDED.daily_amount,
+      monthly_amount = EXCLUDED.monthly_amount,
+      updated_by = EXCLUDED.updated_by,
+      updated_at = now()
+RETURNING *;
+
+-- name: GetTransferLimit :one
+SELECT * FROM transfer_limits
+WHERE id = $1 LIMIT 1;
+
+-- name: ListTransferLimits :many
+SELECT * FROM transfer_limits
+WHERE (sqlc.narg(currency)::varchar IS NULL OR currency = sqlc.narg(currency))
+  AND (sqlc.narg(owner)::varchar IS NULL OR owner = sqlc.narg(owner))
+  AND (sqlc.narg(account_id)::bigint IS NULL OR account_id = sqlc.narg(account_id))
+ORDER BY id
+LIMIT sqlc.arg(page_limit)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListApplicableTransferLimits :many
+-- Lists the limits of the account, of its owner in its currency and the
+-- defaults of the currency, whichever exist.
+SELECT * FROM transfer_limits
+WHERE currency = sqlc.arg(currency)
+  AND (
+    (owner IS NULL AND account_id IS NULL)
+    OR owner = sqlc.arg(owner)::varchar
+    OR account_id = sqlc.arg(account_id)::bigint
+  );
+
+-- name: GetOutgoingTransferTotals :one
+-- Totals what the account and every account of its owner in the same currency
+-- sent since day_start and since month_start. Reversals give money back, so
+-- they don't count, and what they gave back is taken off the transfers they
+-- reverse: a transfer given back in full, like a declined withdrawal, counts
+-- for nothing.
+SELECT
+  COALESCE(SUM(t.amount - r.given_back) FILTER (
+    WHERE t.from_account_id = sqlc.arg(account_id)::bigint AND t.created_at >= sqlc.arg(day_start)::timestamptz
+  ), 0)::bigint AS account_daily,
+  COALESCE(SUM(t.amount - r.given_back) FILTER (WHERE t.from_account_id = sqlc.arg(account_id)::bigint), 0)::bigint AS account_monthly,
+  COALESCE(SUM(t.amount - r.given_back) FILTER (WHERE t.created_at >= sqlc.arg(day_start)::timestamptz), 0)::bigint AS user_daily,
+  COALESCE(SUM(t.amount - r.given_back), 0)::bigint AS user_monthly
+FROM transfers t
+JOIN accounts a ON a.id = t.from_account_id
+CROSS JOIN LATERAL (
+  SELECT COALESCE(SUM(to_amount), 0) AS given_back
+  FROM transfers
+  WHERE reversal_of = t.id
+) r
+WHERE a.owner = sqlc.arg(owner)
+  AND a.currency = sqlc.arg(currency)
+  AND t.reversal_of IS NULL
+  AND t.created_at >= sqlc.arg(month_start)::timestamptz;
+
+-- name: DeleteTransferLimit :exec
+DELETE FROM transfer_limits
+WHERE id = $1;
@@ -10,4 +10,21 @@ INSERT INTO users (
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateUserTokensRevokedAt :one
+UPDATE users
+SET tokens_revoked_at = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+RETURNING *;
@@ -0,0 +1,27 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email,
+  secret_code,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetLatestVerifyEmail :one
+SELECT * FROM verify_emails
+WHERE username = $1
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: UpdateVerifyEmail :one
+-- Marks the code as used, provided it matches, hasn't been used before and
+-- hasn't expired.
+UPDATE verify_emails
+SET is_used = true
+WHERE
+  id = @id
+  AND secret_code = @secret_code
+  AND is_used = false
+  AND expires_at > now()
+RETURNING *;
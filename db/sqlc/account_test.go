@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 
 	"github.com/leoomi/simplebank/util"
@@ -10,11 +9,19 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, util.RandomMoney())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64) Account {
+	return createRandomAccountWithCurrency(t, balance, util.RandomCurrency())
+}
+
+func createRandomAccountWithCurrency(t *testing.T, balance int64, currency string) Account {
 	user := createRandomUser(t)
 	params := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Balance:  balance,
+		Currency: currency,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), params)
@@ -24,6 +31,9 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, params.Owner, account.Owner)
 	require.Equal(t, params.Balance, account.Balance)
 	require.Equal(t, params.Currency, account.Currency)
+	require.Zero(t, account.OverdraftLimit)
+	require.Equal(t, util.AccountStatusActive, account.Status)
+	require.Equal(t, AccountKindCustomer, account.Kind)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -69,16 +79,38 @@ func TestUpdateAccount(t *testing.T) {
 	require.Equal(t, newAccount.CreatedAt, account.CreatedAt)
 }
 
-func TestDeleteAccont(t *testing.T) {
+func TestUpdateAccountOverdraftLimit(t *testing.T) {
 	newAccount := createRandomAccount(t)
 
-	err := testQueries.DeleteAccount(context.Background(), newAccount.ID)
+	params := UpdateAccountOverdraftLimitParams{
+		ID:             newAccount.ID,
+		OverdraftLimit: util.RandomMoney(),
+	}
+	account, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), params)
+
 	require.NoError(t, err)
+	require.NotEmpty(t, account)
 
-	account, err := testQueries.GetAccount(context.Background(), newAccount.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, account)
+	require.Equal(t, newAccount.ID, account.ID)
+	require.Equal(t, newAccount.Balance, account.Balance)
+	require.Equal(t, params.OverdraftLimit, account.OverdraftLimit)
+}
+
+func TestUpdateAccountStatus(t *testing.T) {
+	newAccount := createRandomAccount(t)
+
+	params := UpdateAccountStatusParams{
+		ID:     newAccount.ID,
+		Status: util.AccountStatusFrozen,
+	}
+	account, err := testQueries.UpdateAccountStatus(context.Background(), params)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, account)
+
+	require.Equal(t, newAccount.ID, account.ID)
+	require.Equal(t, newAccount.Balance, account.Balance)
+	require.Equal(t, params.Status, account.Status)
 }
 
 func TestListAccounts(t *testing.T) {
@@ -102,3 +134,30 @@ func TestListAccounts(t *testing.T) {
 		require.Equal(t, lastAccount.Owner, account.Owner)
 	}
 }
+
+func TestCreateSystemAccounts(t *testing.T) {
+	// a currency added after the migrations opened the system accounts
+	_, err := testDB.ExecContext(context.Background(), `
+		INSERT INTO currencies (code, numeric_code, minor_unit, symbol, enabled)
+		VALUES ('CHF', 756, 2, 'CHF', false)
+		ON CONFLICT DO NOTHING`)
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateSystemAccounts(context.Background(), SystemOwner)
+	require.NoError(t, err)
+
+	for _, kind := range []string{AccountKindSuspense, AccountKindExchange, AccountKindClearing} {
+		account, err := testQueries.GetSystemAccount(context.Background(), GetSystemAccountParams{
+			Kind:     kind,
+			Currency: "CHF",
+		})
+		require.NoError(t, err)
+		require.Equal(t, SystemOwner, account.Owner)
+		require.Zero(t, account.Balance)
+	}
+
+	// accounts that are open already are left alone
+	opened, err := testQueries.CreateSystemAccounts(context.Background(), SystemOwner)
+	require.NoError(t, err)
+	require.Zero(t, opened)
+}
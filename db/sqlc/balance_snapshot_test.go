@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountBalanceAt(t *testing.T) {
+	account, entries := createMultipleRandomEntries(t, 3)
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Amount
+	}
+
+	balance, err := testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        entries[0].CreatedAt,
+	})
+	require.NoError(t, err)
+	require.Zero(t, balance)
+
+	balance, err = testQueries.GetAccountBalanceAt(context.Background(), GetAccountBalanceAtParams{
+		AccountID: account.ID,
+		At:        time.Now().Add(time.Second),
+	})
+	require.NoError(t, err)
+	require.Equal(t, total, balance)
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leoomi/simplebank/util"
+)
+
+// LoadCurrencyRegistry builds a registry from the currencies table, falling back
+// to util.DefaultCurrencies when the table is empty.
+func LoadCurrencyRegistry(ctx context.Context, q Querier) (*util.CurrencyRegistry, error) {
+	rows, err := q.ListCurrencies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list currencies: %w", err)
+	}
+	if len(rows) == 0 {
+		return util.NewCurrencyRegistry(util.DefaultCurrencies), nil
+	}
+
+	currencies := make([]util.Currency, len(rows))
+	for i, row := range rows {
+		currencies[i] = util.Currency{
+			Code:        row.Code,
+			NumericCode: row.NumericCode,
+			MinorUnit:   row.MinorUnit,
+			Symbol:      row.Symbol,
+			Enabled:     row.Enabled,
+		}
+	}
+
+	return util.NewCurrencyRegistry(currencies), nil
+}
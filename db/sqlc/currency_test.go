@@ -0,0 +1,30 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCurrencies(t *testing.T) {
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, currencies)
+
+	seeded := make(map[string]Currency)
+	for i, currency := range currencies {
+		if i > 0 {
+			require.Less(t, currencies[i-1].Code, currency.Code)
+		}
+		seeded[currency.Code] = currency
+	}
+
+	for _, code := range []string{util.CAD, util.EUR, util.USD} {
+		currency, ok := seeded[code]
+		require.True(t, ok)
+		require.True(t, currency.Enabled)
+		require.Equal(t, int32(2), currency.MinorUnit)
+	}
+}
@@ -7,30 +7,43 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  journal_id,
+  transfer_id
 ) VALUES (
-  $1, $2
-) RETURNING id, account_id, amount, created_at
+  $1, $2, $3, $4
+) RETURNING id, account_id, amount, created_at, journal_id, transfer_id
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"accountID"`
-	Amount    int64 `json:"amount"`
+	AccountID  int64         `json:"accountID"`
+	Amount     int64         `json:"amount"`
+	JournalID  int64         `json:"journalID"`
+	TransferID sql.NullInt64 `json:"transferID"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.JournalID,
+		arg.TransferID,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.JournalID,
+		&i.TransferID,
 	)
 	return i, err
 }
@@ -45,8 +58,31 @@ func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
 	return err
 }
 
+const getAccountLedgerBalance = `-- name: GetAccountLedgerBalance :one
+SELECT a.id, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = $1
+GROUP BY a.id
+`
+
+type GetAccountLedgerBalanceRow struct {
+	ID             int64 `json:"id"`
+	Balance        int64 `json:"balance"`
+	EntriesBalance int64 `json:"entriesBalance"`
+}
+
+// entries_balance is what the balance of the account adds up to from its
+// entries. Both are read in one statement, so that they are consistent.
+func (q *Queries) GetAccountLedgerBalance(ctx context.Context, id int64) (GetAccountLedgerBalanceRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountLedgerBalance, id)
+	var i GetAccountLedgerBalanceRow
+	err := row.Scan(&i.ID, &i.Balance, &i.EntriesBalance)
+	return i, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, journal_id, transfer_id FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -58,12 +94,94 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.JournalID,
+		&i.TransferID,
 	)
 	return i, err
 }
 
+const listAccountStatement = `-- name: ListAccountStatement :many
+SELECT id, account_id, amount, created_at, running_balance FROM (
+  SELECT
+    e.id,
+    e.account_id,
+    e.amount,
+    e.created_at,
+    (a.balance - SUM(e.amount) OVER (ORDER BY e.id DESC) + e.amount)::bigint AS running_balance
+  FROM entries e
+  JOIN accounts a ON a.id = e.account_id
+  WHERE e.account_id = $1
+) AS statement
+WHERE ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+  AND (
+    $4::varchar IS NULL
+    OR ($4 = 'credit' AND amount > 0)
+    OR ($4 = 'debit' AND amount < 0)
+  )
+ORDER BY id
+LIMIT $5
+OFFSET $6
+`
+
+type ListAccountStatementParams struct {
+	AccountID  int64          `json:"accountID"`
+	FromTime   sql.NullTime   `json:"fromTime"`
+	ToTime     sql.NullTime   `json:"toTime"`
+	Direction  sql.NullString `json:"direction"`
+	PageLimit  int32          `json:"pageLimit"`
+	PageOffset int32          `json:"pageOffset"`
+}
+
+type ListAccountStatementRow struct {
+	ID             int64     `json:"id"`
+	AccountID      int64     `json:"accountID"`
+	Amount         int64     `json:"amount"`
+	CreatedAt      time.Time `json:"createdAt"`
+	RunningBalance int64     `json:"runningBalance"`
+}
+
+// running_balance is the account balance right after the entry was applied.
+// It is computed over every entry of the account before filtering, so it stays
+// correct for any date range or direction.
+func (q *Queries) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatement,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Direction,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatementRow{}
+	for rows.Next() {
+		var i ListAccountStatementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.RunningBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, journal_id, transfer_id FROM entries
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -88,6 +206,8 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.JournalID,
+			&i.TransferID,
 		); err != nil {
 			return nil, err
 		}
@@ -103,8 +223,9 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 }
 
 const listEntriesFromAccount = `-- name: ListEntriesFromAccount :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, journal_id, transfer_id FROM entries
 WHERE account_id = $1
+ORDER BY id
 LIMIT $2
 OFFSET $3
 `
@@ -129,6 +250,8 @@ func (q *Queries) ListEntriesFromAccount(ctx context.Context, arg ListEntriesFro
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.JournalID,
+			&i.TransferID,
 		); err != nil {
 			return nil, err
 		}
@@ -147,7 +270,7 @@ const updateEntry = `-- name: UpdateEntry :one
 UPDATE entries
   SET amount = $2
 WHERE id = $1
-RETURNING id, account_id, amount, created_at
+RETURNING id, account_id, amount, created_at, journal_id, transfer_id
 `
 
 type UpdateEntryParams struct {
@@ -163,6 +286,8 @@ func (q *Queries) UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.JournalID,
+		&i.TransferID,
 	)
 	return i, err
 }
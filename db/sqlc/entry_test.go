@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/leoomi/simplebank/util"
 	"github.com/stretchr/testify/require"
@@ -15,9 +16,13 @@ func createRandomEntry(t *testing.T) Entry {
 }
 
 func createEntryForAccount(t *testing.T, accountID int64) Entry {
+	journal, err := testQueries.CreateJournalTransaction(context.Background(), JournalTransfer)
+	require.NoError(t, err)
+
 	params := CreateEntryParams{
 		AccountID: accountID,
 		Amount:    util.RandomMoney(),
+		JournalID: journal.ID,
 	}
 	entry, err := testQueries.CreateEntry(context.Background(), params)
 
@@ -25,6 +30,8 @@ func createEntryForAccount(t *testing.T, accountID int64) Entry {
 	require.NotEmpty(t, entry)
 
 	require.Equal(t, params.Amount, entry.Amount)
+	require.Equal(t, journal.ID, entry.JournalID)
+	require.False(t, entry.TransferID.Valid)
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
 
@@ -127,3 +134,54 @@ func TestListEntriesFromAccount(t *testing.T) {
 		require.Equal(t, account.ID, e.AccountID)
 	}
 }
+
+func TestListAccountStatement(t *testing.T) {
+	account, entries := createMultipleRandomEntries(t, 10)
+
+	// bring the account balance in line with its entries
+	var total int64
+	for _, e := range entries {
+		total += e.Amount
+	}
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: total,
+	})
+	require.NoError(t, err)
+
+	params := ListAccountStatementParams{
+		AccountID:  account.ID,
+		PageLimit:  10,
+		PageOffset: 0,
+	}
+	statement, err := testQueries.ListAccountStatement(context.Background(), params)
+	require.NoError(t, err)
+	require.Len(t, statement, 10)
+
+	var runningBalance int64
+	for i, row := range statement {
+		runningBalance += entries[i].Amount
+		require.Equal(t, entries[i].ID, row.ID)
+		require.Equal(t, account.ID, row.AccountID)
+		require.Equal(t, runningBalance, row.RunningBalance)
+	}
+	require.Equal(t, account.Balance, statement[len(statement)-1].RunningBalance)
+
+	// filtering keeps the running balance computed over the whole history
+	params.PageOffset = 5
+	page, err := testQueries.ListAccountStatement(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, statement[5:], page)
+
+	params.PageOffset = 0
+	params.Direction = sql.NullString{String: "debit", Valid: true}
+	debits, err := testQueries.ListAccountStatement(context.Background(), params)
+	require.NoError(t, err)
+	require.Empty(t, debits)
+
+	params.Direction = sql.NullString{}
+	params.FromTime = sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	future, err := testQueries.ListAccountStatement(context.Background(), params)
+	require.NoError(t, err)
+	require.Empty(t, future)
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Domain events raised by the store.
+const (
+	EventTransferCreated      = "transfer.created"
+	EventAccountCreated       = "account.created"
+	EventAccountStatusChanged = "account.status_changed"
+)
+
+// EventTypes lists every event the store raises.
+var EventTypes = []string{EventTransferCreated, EventAccountCreated, EventAccountStatusChanged}
+
+// Event is the envelope of a domain event, as it is published and posted to
+// webhooks. Every copy of the same event has the same ID, so consumers can
+// discard the ones they have already seen.
+type Event struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// newOutboxEvent turns an outbox row back into the event it records.
+func newOutboxEvent(row Outbox) Event {
+	return Event{
+		ID:        row.EventID,
+		Type:      row.EventType,
+		CreatedAt: row.CreatedAt,
+		Data:      row.Payload,
+	}
+}
+
+// emitEvent raises an event inside the transaction of the change it is about.
+// The event is written to the outbox, from which the relay publishes it, and a
+// delivery is recorded for every webhook of the owners that subscribes to it.
+// Nothing is published if the transaction rolls back.
+func emitEvent(ctx context.Context, q Querier, eventType string, owners []string, data interface{}) error {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	row, err := q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventID:   uuid.New(),
+		EventType: eventType,
+		Payload:   rawData,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return deliverToWebhooks(ctx, q, newOutboxEvent(row), owners)
+}
+
+type RelayOutboxParams struct {
+	Limit int32 `json:"limit"`
+	// how long the relay may take to publish the batch before other relays
+	// claim its events again
+	Lease time.Duration `json:"lease"`
+	// called for every event, oldest first; the relay stops at the first error
+	Publish func(event Event) error `json:"-"`
+}
+
+// RelayOutbox hands the oldest unpublished events of the outbox to Publish
+// and marks the ones it accepted as published. The batch is claimed with a
+// lease by a statement of its own and published outside of any transaction, so that
+// slow publishers don't hold locks or connections, and concurrent relays skip
+// the events while the lease holds. It returns how many events were
+// published, along with the error of Publish, if any.
+//
+// Delivery is at least once: an event is only marked once Publish returned,
+// so if the relay crashes, the marking fails or the lease expires before the
+// batch is done, the event is published again rather than lost, possibly by
+// another relay and out of order. Consumers must tell duplicates apart by the
+// ID of the event.
+func (s *SQLStore) RelayOutbox(ctx context.Context, arg RelayOutboxParams) (int, error) {
+	rows, err := s.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{
+		LeaseExpiresAt: time.Now().Add(arg.Lease),
+		BatchSize:      arg.Limit,
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+
+	var published, unpublished []int64
+	var publishErr error
+	for _, row := range rows {
+		if publishErr == nil {
+			publishErr = arg.Publish(newOutboxEvent(row))
+		}
+		if publishErr != nil {
+			unpublished = append(unpublished, row.ID)
+			continue
+		}
+		published = append(published, row.ID)
+	}
+
+	if len(published) > 0 {
+		if err := s.MarkOutboxEventsPublished(ctx, published); err != nil {
+			return 0, err
+		}
+	}
+	if len(unpublished) > 0 {
+		// the lease would expire anyway, so failing to give it up only delays
+		// the next attempt
+		if err := s.ReleaseOutboxEvents(ctx, unpublished); err != nil {
+			return len(published), errors.Join(publishErr, err)
+		}
+	}
+
+	return len(published), publishErr
+}
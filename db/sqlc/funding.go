@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leoomi/simplebank/util"
+)
+
+// Kinds of funding, the ways money enters or leaves the ledger.
+const (
+	FundingDeposit    = "deposit"
+	FundingWithdrawal = "withdrawal"
+)
+
+var (
+	ErrFundingDeclined = errors.New("payment was declined by the funding gateway")
+	ErrFundingPending  = errors.New("funding gateway did not confirm the payment, so it stays pending")
+)
+
+// Statuses of a funding.
+const (
+	FundingPending = "pending"
+	FundingSettled = "settled"
+	FundingFailed  = "failed"
+)
+
+type FundingTxParams struct {
+	AccountID int64  `json:"accountID"`
+	Amount    int64  `json:"amount"`
+	Kind      string `json:"kind"`
+	// asks the funding gateway to move the money once the funding is recorded,
+	// outside of any transaction, and returns what the gateway calls the
+	// payment; an error wrapping ErrFundingDeclined fails the funding, while
+	// any other leaves it pending, since the gateway may have moved the money
+	Settle func(funding Funding) (string, error) `json:"-"`
+}
+
+type FundingTxResult struct {
+	Funding  Funding  `json:"funding"`
+	Transfer Transfer `json:"transfer"`
+	Account  Account  `json:"account"`
+	Entry    Entry    `json:"entry"`
+}
+
+// FundingTx deposits money into an account or withdraws it, with a transfer
+// between the account and the clearing account of its currency. No
+// transaction stays open while the gateway is waited on:
+//
+//   - the funding is recorded as pending, along with the transfer of a
+//     withdrawal, so that the money can't be spent again while it is paid out;
+//   - Settle asks the gateway to move the money;
+//   - SettleFundingTx makes the transfer of a deposit once the gateway has
+//     collected it, or gives a withdrawal back when the gateway declined it.
+//
+// When the gateway gives no answer, the funding stays pending and
+// ErrFundingPending is returned, since the money may have moved all the same.
+// The gateway is told the ID of the funding as reference, so that it can tell
+// a retry from a new payment when the funding sweeper asks it again later.
+func (s *SQLStore) FundingTx(ctx context.Context, arg FundingTxParams) (FundingTxResult, error) {
+	pending, err := s.createFunding(ctx, arg)
+	if err != nil {
+		return pending, err
+	}
+
+	reference, settleErr := arg.Settle(pending.Funding)
+	if settleErr != nil && !errors.Is(settleErr, ErrFundingDeclined) {
+		return pending, fmt.Errorf("%w: %w", ErrFundingPending, settleErr)
+	}
+
+	result, err := s.SettleFundingTx(ctx, SettleFundingTxParams{
+		FundingID: pending.Funding.ID,
+		Reference: reference,
+		Declined:  settleErr != nil,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if result.Transfer.ID == 0 {
+		// a settled withdrawal keeps the transfer it was recorded with
+		result.Transfer, result.Account, result.Entry = pending.Transfer, pending.Account, pending.Entry
+	}
+	return result, settleErr
+}
+
+// createFunding records a pending funding. A withdrawal takes the money out of
+// the account right away, failing when the account doesn't have the funds for
+// it or when it goes over a transfer limit like any other money sent from the
+// account, while a deposit only checks that the account can receive it.
+func (s *SQLStore) createFunding(ctx context.Context, arg FundingTxParams) (FundingTxResult, error) {
+	var result FundingTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Kind != AccountKindCustomer {
+			return ErrSystemAccount
+		}
+
+		result.Account = account
+		var transferID sql.NullInt64
+
+		if arg.Kind == FundingWithdrawal {
+			clearing, err := clearingAccount(ctx, q, account.Currency)
+			if err != nil {
+				return err
+			}
+
+			transferResult, err := makeTransfer(ctx, q, TransferTxParams{
+				FromAccountID: account.ID,
+				ToAccountID:   clearing.ID,
+				Amount:        arg.Amount,
+			}, sql.NullInt64{}, true)
+			if err != nil {
+				return err
+			}
+
+			err = checkTransferLimits(ctx, q, transferResult.FromAccount, transferResult.Transfer, time.Now())
+			if err != nil {
+				return err
+			}
+
+			result.Transfer, result.Account, result.Entry = transferResult.Transfer, transferResult.FromAccount, transferResult.FromEntry
+			transferID = sql.NullInt64{Int64: transferResult.Transfer.ID, Valid: true}
+		} else if account.Status != util.AccountStatusActive {
+			// checked again when the deposit is settled, but the gateway
+			// shouldn't collect money the account can't receive
+			return ErrAccountNotActive
+		}
+
+		result.Funding, err = q.CreateFunding(ctx, CreateFundingParams{
+			AccountID:  account.ID,
+			Amount:     arg.Amount,
+			Kind:       arg.Kind,
+			Status:     FundingPending,
+			TransferID: transferID,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+type SettleFundingTxParams struct {
+	FundingID int64 `json:"fundingID"`
+	// what the gateway calls the payment, once it moved the money
+	Reference string `json:"reference"`
+	// whether the gateway declined to move the money
+	Declined bool `json:"declined"`
+}
+
+// SettleFundingTx finishes a pending funding with what the gateway did. A
+// deposit the gateway collected is transferred into the account, and a
+// withdrawal it declined to pay out is given back to the account with a
+// reversal of its transfer. Fundings that were already finished are returned
+// as they are, so settling one again never moves the money twice; the result
+// only holds the transfer and entry this call made, if any.
+func (s *SQLStore) SettleFundingTx(ctx context.Context, arg SettleFundingTxParams) (FundingTxResult, error) {
+	var result FundingTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		funding, err := q.GetFundingForUpdate(ctx, arg.FundingID)
+		if err != nil {
+			return err
+		}
+
+		result.Funding = funding
+		result.Account, err = q.GetAccount(ctx, funding.AccountID)
+		if err != nil || funding.Status != FundingPending {
+			return err
+		}
+
+		update := UpdateFundingParams{
+			ID:         funding.ID,
+			Status:     FundingSettled,
+			Reference:  sql.NullString{String: arg.Reference, Valid: !arg.Declined},
+			TransferID: funding.TransferID,
+		}
+		if arg.Declined {
+			update.Status = FundingFailed
+		}
+
+		// a settled withdrawal and a declined deposit move no money
+		moves := (funding.Kind == FundingDeposit) != arg.Declined
+		if moves {
+			clearing, err := clearingAccount(ctx, q, result.Account.Currency)
+			if err != nil {
+				return err
+			}
+
+			// the transfer of a declined withdrawal is reversed, linking the money
+			// given back to it
+			var reversalOf sql.NullInt64
+			if funding.Kind == FundingWithdrawal {
+				reversalOf = funding.TransferID
+			}
+
+			transferResult, err := makeTransfer(ctx, q, TransferTxParams{
+				FromAccountID: clearing.ID,
+				ToAccountID:   funding.AccountID,
+				Amount:        funding.Amount,
+			}, reversalOf, true)
+			if err != nil {
+				return err
+			}
+
+			result.Transfer, result.Account, result.Entry = transferResult.Transfer, transferResult.ToAccount, transferResult.ToEntry
+			if funding.Kind == FundingDeposit {
+				update.TransferID = sql.NullInt64{Int64: transferResult.Transfer.ID, Valid: true}
+			}
+		}
+
+		result.Funding, err = q.UpdateFunding(ctx, update)
+		return err
+	})
+
+	return result, err
+}
+
+// clearingAccount returns the system account money entering or leaving the
+// ledger in the currency goes through.
+func clearingAccount(ctx context.Context, q *Queries, currency string) (Account, error) {
+	return q.GetSystemAccount(ctx, GetSystemAccountParams{
+		Kind:     AccountKindClearing,
+		Currency: currency,
+	})
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFundingTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountWithBalance(t, 0)
+
+	settled := 0
+	settle := func(funding Funding) (string, error) {
+		settled++
+		require.Equal(t, FundingPending, funding.Status)
+		return fmt.Sprintf("ref_%d", funding.ID), nil
+	}
+
+	deposit, err := store.FundingTx(context.Background(), FundingTxParams{
+		AccountID: account.ID,
+		Amount:    500,
+		Kind:      FundingDeposit,
+		Settle: func(funding Funding) (string, error) {
+			// a deposit only reaches the account once the gateway collected it
+			pending, err := testQueries.GetAccount(context.Background(), account.ID)
+			require.NoError(t, err)
+			require.Zero(t, pending.Balance)
+			require.False(t, funding.TransferID.Valid)
+
+			return settle(funding)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(500), deposit.Account.Balance)
+	require.Equal(t, int64(500), deposit.Entry.Amount)
+	require.Equal(t, account.ID, deposit.Transfer.ToAccountID)
+	require.Equal(t, FundingDeposit, deposit.Funding.Kind)
+	require.Equal(t, FundingSettled, deposit.Funding.Status)
+	require.Equal(t, deposit.Transfer.ID, deposit.Funding.TransferID.Int64)
+	require.Equal(t, fmt.Sprintf("ref_%d", deposit.Funding.ID), deposit.Funding.Reference.String)
+
+	// the money comes from the clearing account of the currency
+	clearing, err := testQueries.GetAccount(context.Background(), deposit.Transfer.FromAccountID)
+	require.NoError(t, err)
+	require.Equal(t, AccountKindClearing, clearing.Kind)
+	require.Equal(t, account.Currency, clearing.Currency)
+
+	// settling again moves no money
+	again, err := store.SettleFundingTx(context.Background(), SettleFundingTxParams{FundingID: deposit.Funding.ID, Reference: "other"})
+	require.NoError(t, err)
+	require.Equal(t, deposit.Funding, again.Funding)
+	require.Equal(t, int64(500), again.Account.Balance)
+	require.Zero(t, again.Transfer.ID)
+
+	// a withdrawal without the funds for it is never settled
+	_, err = store.FundingTx(context.Background(), FundingTxParams{
+		AccountID: account.ID,
+		Amount:    501,
+		Kind:      FundingWithdrawal,
+		Settle:    settle,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+	require.Equal(t, 1, settled)
+
+	// a withdrawal the gateway declines to pay out is given back
+	var declined Funding
+	_, err = store.FundingTx(context.Background(), FundingTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+		Kind:      FundingWithdrawal,
+		Settle: func(funding Funding) (string, error) {
+			// the money is held while the gateway pays it out
+			pending, err := testQueries.GetAccount(context.Background(), account.ID)
+			require.NoError(t, err)
+			require.Equal(t, int64(400), pending.Balance)
+
+			declined = funding
+			return "", ErrFundingDeclined
+		},
+	})
+	require.ErrorIs(t, err, ErrFundingDeclined)
+
+	declined, err = testQueries.GetFundingByTransfer(context.Background(), declined.TransferID)
+	require.NoError(t, err)
+	require.Equal(t, FundingFailed, declined.Status)
+	require.False(t, declined.Reference.Valid)
+
+	reversed, err := testQueries.SumTransferReversals(context.Background(), declined.TransferID.Int64)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), reversed.ToAmount)
+
+	// a funding that failed isn't settled afterwards
+	again, err = store.SettleFundingTx(context.Background(), SettleFundingTxParams{FundingID: declined.ID, Reference: "late"})
+	require.NoError(t, err)
+	require.Equal(t, FundingFailed, again.Funding.Status)
+	require.Equal(t, int64(500), again.Account.Balance)
+
+	// a declined withdrawal is given back to an account frozen in the meantime
+	frozen := createRandomAccountWithBalance(t, 300)
+	_, err = store.FundingTx(context.Background(), FundingTxParams{
+		AccountID: frozen.ID,
+		Amount:    100,
+		Kind:      FundingWithdrawal,
+		Settle: func(funding Funding) (string, error) {
+			_, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+				ID:     frozen.ID,
+				Status: util.AccountStatusFrozen,
+			})
+			require.NoError(t, err)
+
+			return "", ErrFundingDeclined
+		},
+	})
+	require.ErrorIs(t, err, ErrFundingDeclined)
+
+	frozen, err = testQueries.GetAccount(context.Background(), frozen.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.AccountStatusFrozen, frozen.Status)
+	require.Equal(t, int64(300), frozen.Balance)
+
+	// a withdrawal the gateway doesn't answer for stays pending, holding the
+	// money, since it may have been paid out all the same
+	var unanswered Funding
+	_, err = store.FundingTx(context.Background(), FundingTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+		Kind:      FundingWithdrawal,
+		Settle: func(funding Funding) (string, error) {
+			unanswered = funding
+			return "", errors.New("timeout")
+		},
+	})
+	require.ErrorIs(t, err, ErrFundingPending)
+
+	unanswered, err = testQueries.GetFundingByTransfer(context.Background(), unanswered.TransferID)
+	require.NoError(t, err)
+	require.Equal(t, FundingPending, unanswered.Status)
+
+	again, err = store.SettleFundingTx(context.Background(), SettleFundingTxParams{FundingID: unanswered.ID, Reference: "late"})
+	require.NoError(t, err)
+	require.Equal(t, FundingSettled, again.Funding.Status)
+	require.Equal(t, int64(400), again.Account.Balance)
+
+	withdrawal, err := store.FundingTx(context.Background(), FundingTxParams{
+		AccountID: account.ID,
+		Amount:    200,
+		Kind:      FundingWithdrawal,
+		Settle:    settle,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(200), withdrawal.Account.Balance)
+	require.Equal(t, int64(-200), withdrawal.Entry.Amount)
+	require.Equal(t, clearing.ID, withdrawal.Transfer.ToAccountID)
+	require.Equal(t, FundingSettled, withdrawal.Funding.Status)
+	require.Equal(t, sql.NullInt64{Int64: withdrawal.Transfer.ID, Valid: true}, withdrawal.Funding.TransferID)
+
+	require.NoError(t, store.VerifyAccountBalance(context.Background(), account.ID))
+	require.NoError(t, store.VerifyAccountBalance(context.Background(), clearing.ID))
+
+	// money that came in through the gateway can't be sent back around it
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: deposit.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferIsFunding)
+}
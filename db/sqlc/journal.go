@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnbalancedJournal = errors.New("journal entries don't add up to zero")
+	ErrBalanceMismatch   = errors.New("account balance is not the sum of its entries")
+)
+
+// Kinds of account. Customer accounts hold money of their owner. The others are
+// system accounts, one per currency, owned by SystemOwner.
+const (
+	AccountKindCustomer = "customer"
+	// offsets money the ledger has no other record of, such as the balances
+	// accounts had before it kept journals
+	AccountKindSuspense = "suspense"
+	// offsets money that changes currency in a transfer
+	AccountKindExchange = "exchange"
+	// offsets money deposited or withdrawn through the funding gateway
+	AccountKindClearing = "clearing"
+)
+
+// SystemOwner is the user that owns the system accounts. Usernames of customers
+// are alphanumeric, so no customer can take the name. Nobody can log in as
+// them: their password is no bcrypt hash, and logins refuse them anyway.
+const SystemOwner = "simplebank_ledger"
+
+// Kinds of journal transaction.
+const (
+	// the balances accounts had before the ledger kept journals
+	JournalOpeningBalance = "opening_balance"
+	JournalTransfer       = "transfer"
+	JournalReversal       = "reversal"
+	JournalDeposit        = "deposit"
+	JournalWithdrawal     = "withdrawal"
+)
+
+// checkJournals fails with ErrUnbalancedJournal when a journal transaction
+// written by the database transaction of q doesn't add up to zero in each of
+// its currencies.
+func checkJournals(ctx context.Context, q *Queries) error {
+	unbalanced, err := q.ListUnbalancedJournalTransactions(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(unbalanced) > 0 {
+		journal := unbalanced[0]
+		return fmt.Errorf("%w: journal %d is off by %d %s", ErrUnbalancedJournal, journal.ID, journal.Total, journal.Currency)
+	}
+	return nil
+}
+
+// postExchange balances a transfer between currencies through the exchange
+// accounts: the one of the source currency takes what was sent, and the one of
+// the destination currency gives what was received. The accounts are updated
+// in ID order, so that transfers going both ways cannot deadlock.
+func postExchange(ctx context.Context, q *Queries, journalID int64, transfer Transfer, fromCurrency, toCurrency string) error {
+	from, err := q.GetSystemAccount(ctx, GetSystemAccountParams{
+		Kind:     AccountKindExchange,
+		Currency: fromCurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	to, err := q.GetSystemAccount(ctx, GetSystemAccountParams{
+		Kind:     AccountKindExchange,
+		Currency: toCurrency,
+	})
+	if err != nil {
+		return err
+	}
+
+	postings := []CreateEntryParams{
+		{AccountID: from.ID, Amount: transfer.Amount},
+		{AccountID: to.ID, Amount: -transfer.ToAmount},
+	}
+	if to.ID < from.ID {
+		postings[0], postings[1] = postings[1], postings[0]
+	}
+
+	for _, posting := range postings {
+		posting.JournalID = journalID
+		posting.TransferID = sql.NullInt64{Int64: transfer.ID, Valid: true}
+
+		err = postEntry(ctx, q, posting)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postEntry adds the amount of an entry to the balance of its account and
+// writes the entry. The balance is updated first so that the entry is written
+// under the lock of its account: the entries of an account then commit in ID
+// order, which the reconciliation checkpoints rely on.
+func postEntry(ctx context.Context, q *Queries, arg CreateEntryParams) error {
+	_, err := q.AddToAccountBalance(ctx, AddToAccountBalanceParams{
+		ID:     arg.AccountID,
+		Amount: arg.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateEntry(ctx, arg)
+	return err
+}
+
+// VerifyAccountBalance fails with ErrBalanceMismatch when the balance of an
+// account is not what its entries add up to.
+func (s *SQLStore) VerifyAccountBalance(ctx context.Context, accountID int64) error {
+	ledger, err := s.GetAccountLedgerBalance(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	if ledger.Balance != ledger.EntriesBalance {
+		return fmt.Errorf("%w: account %d has %d, its entries add up to %d", ErrBalanceMismatch, accountID, ledger.Balance, ledger.EntriesBalance)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferTxJournal(t *testing.T) {
+	store := NewStore(testDB)
+
+	// accounts that start empty have a balance their entries add up to
+	account1 := createRandomAccountWithBalance(t, 0)
+	account2 := createRandomAccountWithCurrency(t, 0, account1.Currency)
+	_, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 100,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// both entries are in one journal and point at their transfer
+	require.NotZero(t, result.FromEntry.JournalID)
+	require.Equal(t, result.FromEntry.JournalID, result.ToEntry.JournalID)
+	require.Equal(t, result.Transfer.ID, result.FromEntry.TransferID.Int64)
+	require.Equal(t, result.Transfer.ID, result.ToEntry.TransferID.Int64)
+
+	require.NoError(t, store.VerifyAccountBalance(context.Background(), account1.ID))
+	require.NoError(t, store.VerifyAccountBalance(context.Background(), account2.ID))
+
+	// a balance changed without an entry no longer adds up
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account2.ID,
+		Balance: 20,
+	})
+	require.NoError(t, err)
+	require.ErrorIs(t, store.VerifyAccountBalance(context.Background(), account2.ID), ErrBalanceMismatch)
+}
+
+func TestTransferTxJournalCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithCurrency(t, 1000, util.USD)
+	account2 := createRandomAccountWithCurrency(t, 1000, util.EUR)
+
+	exchangeUSD, err := testQueries.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		Kind:     AccountKindExchange,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, SystemOwner, exchangeUSD.Owner)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      92,
+		ExchangeRate:  "0.92",
+		RateTimestamp: time.Now(),
+	})
+	require.NoError(t, err)
+
+	// the exchange account of each currency balances the journal in it
+	require.NoError(t, store.VerifyAccountBalance(context.Background(), exchangeUSD.ID))
+
+	exchangeEUR, err := testQueries.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		Kind:     AccountKindExchange,
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.VerifyAccountBalance(context.Background(), exchangeEUR.ID))
+}
+
+func TestExecTxUnbalancedJournal(t *testing.T) {
+	store := NewStore(testDB).(*SQLStore)
+
+	account := createRandomAccount(t)
+
+	var entry Entry
+	err := store.execTx(context.Background(), func(q *Queries) error {
+		journal, err := q.CreateJournalTransaction(context.Background(), JournalTransfer)
+		if err != nil {
+			return err
+		}
+
+		entry, err = q.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    10,
+			JournalID: journal.ID,
+		})
+		return err
+	})
+	require.ErrorIs(t, err, ErrUnbalancedJournal)
+
+	// the entry was rolled back with the rest of the transaction
+	_, err = testQueries.GetEntry(context.Background(), entry.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -5,7 +5,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +18,40 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"createdAt"`
+	// how far below zero the balance may go
+	OverdraftLimit int64  `json:"overdraftLimit"`
+	Status         string `json:"status"`
+	// customer accounts hold money of their owner, the others balance the ledger for money entering it or changing currency
+	Kind string `json:"kind"`
+}
+
+type AccountStatusChange struct {
+	ID         int64  `json:"id"`
+	AccountID  int64  `json:"accountID"`
+	FromStatus string `json:"fromStatus"`
+	ToStatus   string `json:"toStatus"`
+	// username of whoever made the change
+	ChangedBy string    `json:"changedBy"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type BalanceSnapshot struct {
+	AccountID int64     `json:"accountID"`
+	Day       time.Time `json:"day"`
+	// end of the day in UTC; the balance adds up the entries written before it
+	ClosingAt time.Time `json:"closingAt"`
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Currency struct {
+	Code        string `json:"code"`
+	NumericCode int32  `json:"numericCode"`
+	// digits after the decimal separator
+	MinorUnit int32  `json:"minorUnit"`
+	Symbol    string `json:"symbol"`
+	Enabled   bool   `json:"enabled"`
 }
 
 type Entry struct {
@@ -22,6 +60,131 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"createdAt"`
+	JournalID int64     `json:"journalID"`
+	// transfer the entry was made for, null for entries of other journals
+	TransferID sql.NullInt64 `json:"transferID"`
+}
+
+type Funding struct {
+	// transfer that moved the money: made when a withdrawal is asked for, and when a deposit is settled
+	TransferID sql.NullInt64 `json:"transferID"`
+	Kind       string        `json:"kind"`
+	// what the funding gateway calls the payment, null until it is settled
+	Reference sql.NullString `json:"reference"`
+	CreatedAt time.Time      `json:"createdAt"`
+	ID        int64          `json:"id"`
+	AccountID int64          `json:"accountID"`
+	Amount    int64          `json:"amount"`
+	// pending while the gateway is asked to move the money; a failed withdrawal is given back to the account
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type IdempotencyKey struct {
+	Username       string          `json:"username"`
+	IdempotencyKey string          `json:"idempotencyKey"`
+	RequestHash    string          `json:"requestHash"`
+	TransferID     int64           `json:"transferID"`
+	Response       json.RawMessage `json:"response"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	ExpiresAt      time.Time       `json:"expiresAt"`
+}
+
+type JournalTransaction struct {
+	ID   int64  `json:"id"`
+	Kind string `json:"kind"`
+	// database transaction that wrote the journal, so that it can be checked to balance before commit
+	TxID      int64     `json:"txID"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Outbox struct {
+	ID        int64           `json:"id"`
+	EventID   uuid.UUID       `json:"eventID"`
+	EventType string          `json:"eventType"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+	// null until the relay has handed the event to the publisher
+	PublishedAt sql.NullTime `json:"publishedAt"`
+	// set while a relay publishes the event; other relays skip it until it expires
+	LeaseExpiresAt sql.NullTime `json:"leaseExpiresAt"`
+}
+
+type Rate struct {
+	BaseCurrency  string `json:"baseCurrency"`
+	QuoteCurrency string `json:"quoteCurrency"`
+	// units of the quote currency bought by one unit of the base currency
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ReconciliationCheckpoint struct {
+	AccountID int64 `json:"accountID"`
+	// balance the entries of the account added up to, up to and including last_entry_id
+	Balance     int64     `json:"balance"`
+	LastEntryID int64     `json:"lastEntryID"`
+	VerifiedAt  time.Time `json:"verifiedAt"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"fromAccountID"`
+	ToAccountID   int64  `json:"toAccountID"`
+	Amount        int64  `json:"amount"`
+	// cron expression of a recurring transfer, empty for a one-off
+	Schedule string `json:"schedule"`
+	// whether a run that fails for lack of funds or an inactive account is retried before being skipped
+	FailurePolicy string    `json:"failurePolicy"`
+	Status        string    `json:"status"`
+	NextRunAt     time.Time `json:"nextRunAt"`
+	// failed attempts at the current run
+	Attempts  int32     `json:"attempts"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64     `json:"id"`
+	ScheduledTransferID int64     `json:"scheduledTransferID"`
+	ScheduledFor        time.Time `json:"scheduledFor"`
+	Outcome             string    `json:"outcome"`
+	// transfer made by the run, null unless it succeeded
+	TransferID sql.NullInt64 `json:"transferID"`
+	Error      string        `json:"error"`
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refreshToken"`
+	UserAgent    string    `json:"userAgent"`
+	ClientIp     string    `json:"clientIp"`
+	IsBlocked    bool      `json:"isBlocked"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type Task struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"maxAttempts"`
+	LastError   string          `json:"lastError"`
+	// when a pending task is due, or when the lease of a running task runs out
+	RunAt     time.Time `json:"runAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type Transfer struct {
@@ -31,6 +194,29 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"createdAt"`
+	// amount credited, in the currency of the destination account
+	ToAmount int64 `json:"toAmount"`
+	// rate used to convert amount into to_amount
+	ExchangeRate  string    `json:"exchangeRate"`
+	RateTimestamp time.Time `json:"rateTimestamp"`
+	// transfer this one gives back, in full or in part
+	ReversalOf sql.NullInt64 `json:"reversalOf"`
+}
+
+type TransferLimit struct {
+	ID       int64  `json:"id"`
+	Currency string `json:"currency"`
+	// limits every account of the user in the currency together; a row with neither owner nor account holds the defaults of the currency for accounts
+	Owner     sql.NullString `json:"owner"`
+	AccountID sql.NullInt64  `json:"accountID"`
+	// largest single transfer, null for no limit of its own
+	MaxAmount sql.NullInt64 `json:"maxAmount"`
+	// most that may be sent since midnight UTC, null for no limit of its own
+	DailyAmount sql.NullInt64 `json:"dailyAmount"`
+	// most that may be sent since the start of the month in UTC, null for no limit of its own
+	MonthlyAmount sql.NullInt64 `json:"monthlyAmount"`
+	UpdatedBy     string        `json:"updatedBy"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
 }
 
 type User struct {
@@ -40,4 +226,49 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"passwordChangedAt"`
 	CreatedAt         time.Time `json:"createdAt"`
+	// tokens issued up to this instant are rejected
+	TokensRevokedAt time.Time `json:"tokensRevokedAt"`
+	Role            string    `json:"role"`
+	IsEmailVerified bool      `json:"isEmailVerified"`
+}
+
+type VerifyEmail struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secretCode"`
+	IsUsed     bool      `json:"isUsed"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+type Webhook struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// key of the HMAC-SHA256 signature sent with every delivery
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"eventTypes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	ID        int64           `json:"id"`
+	WebhookID int64           `json:"webhookID"`
+	EventID   uuid.UUID       `json:"eventID"`
+	EventType string          `json:"eventType"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID         int64 `json:"id"`
+	DeliveryID int64 `json:"deliveryID"`
+	// zero when no response was received
+	ResponseStatus int32     `json:"responseStatus"`
+	Error          string    `json:"error"`
+	DurationMs     int64     `json:"durationMs"`
+	CreatedAt      time.Time `json:"createdAt"`
 }
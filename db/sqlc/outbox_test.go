@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayOutbox(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	isTransferEvent := func(event Event) bool {
+		var transfer Transfer
+		return event.Type == EventTransferCreated &&
+			json.Unmarshal(event.Data, &transfer) == nil &&
+			transfer.ID == result.Transfer.ID
+	}
+
+	// a failing publisher leaves the event in the outbox, while the events
+	// before it are marked as published and the lease on the rest is given up
+	errUnavailable := errors.New("publisher unavailable")
+	for {
+		published, err := store.RelayOutbox(context.Background(), RelayOutboxParams{
+			Limit: 100,
+			Lease: time.Minute,
+			Publish: func(event Event) error {
+				if !isTransferEvent(event) {
+					return nil
+				}
+
+				// the batch is leased, so another relay skips it
+				_, err := store.RelayOutbox(context.Background(), RelayOutboxParams{
+					Limit: 100,
+					Lease: time.Minute,
+					Publish: func(other Event) error {
+						require.NotEqual(t, event.ID, other.ID)
+						return nil
+					},
+				})
+				require.NoError(t, err)
+				return errUnavailable
+			},
+		})
+		if errors.Is(err, errUnavailable) {
+			break
+		}
+		require.NoError(t, err)
+		require.NotZero(t, published, "the event of the transfer was never relayed")
+	}
+
+	var relayed []Event
+	_, err = store.RelayOutbox(context.Background(), RelayOutboxParams{
+		Limit: 100,
+		Publish: func(event Event) error {
+			relayed = append(relayed, event)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, relayed)
+	require.True(t, isTransferEvent(relayed[0]))
+
+	// published events aren't relayed again
+	_, err = store.RelayOutbox(context.Background(), RelayOutboxParams{
+		Limit: 100,
+		Publish: func(event Event) error {
+			require.NotEqual(t, relayed[0].ID, event.ID)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+}
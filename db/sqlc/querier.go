@@ -6,31 +6,162 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddToAccountBalance(ctx context.Context, arg AddToAccountBalanceParams) (Account, error)
+	// Moves a scheduled transfer on after a run, provided it is still due at the
+	// time the run was for. Nothing is returned when another run got there first.
+	AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error)
+	BlockSession(ctx context.Context, arg BlockSessionParams) error
+	BlockUserSessions(ctx context.Context, username string) error
+	// Takes the funding that has been pending the longest, provided nothing touched
+	// it since stale_before, skipping the ones other sweepers are claiming. It is
+	// touched in turn, so that they leave it alone for as long again.
+	ClaimPendingFunding(ctx context.Context, staleBefore time.Time) (Funding, error)
+	// Claims the oldest events that haven't been published yet until the lease
+	// expires, skipping the ones another relay holds a lease on.
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error)
+	// Takes the task that has been due the longest, skipping the ones other workers
+	// are claiming at the same time. Running tasks whose lease ran out are claimed
+	// again, so that a crashed worker doesn't lose them.
+	ClaimTask(ctx context.Context, leaseExpiresAt time.Time) (Task, error)
+	CompleteTask(ctx context.Context, id int64) error
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountStatusChange(ctx context.Context, arg CreateAccountStatusChangeParams) (AccountStatusChange, error)
+	// Snapshots the closing balance of the day of every account that existed by
+	// then, adding the entries of the day onto the snapshot of the day before.
+	// Days must be snapshotted in order. A day that was already snapshotted is
+	// left as it was.
+	CreateBalanceSnapshots(ctx context.Context, arg CreateBalanceSnapshotsParams) (int64, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFunding(ctx context.Context, arg CreateFundingParams) (Funding, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateJournalTransaction(ctx context.Context, kind string) (JournalTransaction, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) error
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	// Opens the system accounts of every currency that has none yet, such as a
+	// currency added after the migrations that opened them.
+	CreateSystemAccounts(ctx context.Context, owner string) (int64, error)
+	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteAccount(ctx context.Context, id int64) error
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
+	CreateWebhookDeliveryAttempt(ctx context.Context, arg CreateWebhookDeliveryAttemptParams) (WebhookDeliveryAttempt, error)
 	DeleteEntry(ctx context.Context, id int64) error
+	DeleteScheduledTransfer(ctx context.Context, id int64) error
 	DeleteTransfer(ctx context.Context, id int64) error
+	DeleteTransferLimit(ctx context.Context, id int64) error
+	DeleteWebhook(ctx context.Context, id int64) error
+	// Gives up on a task, leaving it in the dead state for someone to look at.
+	FailTask(ctx context.Context, arg FailTaskParams) (Task, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	// Adds the entries written up to at onto the latest snapshot taken by then,
+	// or onto zero when there is none.
+	GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	// entries_balance is what the balance of the account adds up to from its
+	// entries. Both are read in one statement, so that they are consistent.
+	GetAccountLedgerBalance(ctx context.Context, id int64) (GetAccountLedgerBalanceRow, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFundingByTransfer(ctx context.Context, transferID sql.NullInt64) (Funding, error)
+	GetFundingForUpdate(ctx context.Context, id int64) (Funding, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetLatestBalanceSnapshotDay(ctx context.Context) (time.Time, error)
+	GetLatestVerifyEmail(ctx context.Context, username string) (VerifyEmail, error)
+	// Totals what the account and every account of its owner in the same currency
+	// sent since day_start and since month_start. Reversals give money back, so
+	// they don't count, and what they gave back is taken off the transfers they
+	// reverse: a transfer given back in full, like a declined withdrawal, counts
+	// for nothing.
+	GetOutgoingTransferTotals(ctx context.Context, arg GetOutgoingTransferTotalsParams) (GetOutgoingTransferTotalsRow, error)
+	GetRate(ctx context.Context, arg GetRateParams) (Rate, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetSystemAccount(ctx context.Context, arg GetSystemAccountParams) (Account, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	GetTransferLimit(ctx context.Context, id int64) (TransferLimit, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	// running_balance is the account balance right after the entry was applied.
+	// It is computed over every entry of the account before filtering, so it stays
+	// correct for any date range or direction.
+	// A token is revoked when its ID was revoked on its own or when it was issued
+	// before the user last revoked all of their tokens.
+	IsTokenRevoked(ctx context.Context, arg IsTokenRevokedParams) (bool, error)
+	// Pages through the accounts in ID order with what their entries add up to.
+	// Incremental runs start from the checkpoint of an account, only adding the
+	// entries written after it. last_entry_id is the last entry that was added up;
+// entries are only written under the lock of their account, so later entries
+// of an account cannot commit before it.
+	ListAccountLedgerBalances(ctx context.Context, arg ListAccountLedgerBalancesParams) ([]ListAccountLedgerBalancesRow, error)
+	ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error)
+	ListAccountStatusChanges(ctx context.Context, arg ListAccountStatusChangesParams) ([]AccountStatusChange, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	// Lists the limits of the account, of its owner in its currency and the
+	// defaults of the currency, whichever exist.
+	ListApplicableTransferLimits(ctx context.Context, arg ListApplicableTransferLimitsParams) ([]TransferLimit, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
+	ListDueScheduledTransfers(ctx context.Context, limit int32) ([]ScheduledTransfer, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
 	ListEntriesFromAccount(ctx context.Context, arg ListEntriesFromAccountParams) ([]Entry, error)
+	// Lists the webhooks of any of the owners that subscribe to the event type.
+	ListEventWebhooks(ctx context.Context, arg ListEventWebhooksParams) ([]Webhook, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	// Pages through the transfers in ID order with what their entries take from
+	// the source account and give the destination account.
+	ListTransferEntryTotals(ctx context.Context, arg ListTransferEntryTotalsParams) ([]ListTransferEntryTotalsRow, error)
+	ListTransferLimits(ctx context.Context, arg ListTransferLimitsParams) ([]TransferLimit, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
 	ListTransfersFrom(ctx context.Context, arg ListTransfersFromParams) ([]Transfer, error)
 	ListTransfersTo(ctx context.Context, arg ListTransfersToParams) ([]Transfer, error)
+	// Lists the journal transactions written by the current database transaction
+	// whose entries don't add up to zero in a currency. Nothing is listed when the
+	// transaction hasn't written anything yet.
+	ListUnbalancedJournalTransactions(ctx context.Context) ([]ListUnbalancedJournalTransactionsRow, error)
+	// Keyset paginated feed of every transfer touching an account of the owner,
+	// newest first. Pass the last ID of the previous page as cursor.
+	ListUserTransfers(ctx context.Context, arg ListUserTransfersParams) ([]ListUserTransfersRow, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookDeliveryAttempts(ctx context.Context, deliveryID int64) ([]WebhookDeliveryAttempt, error)
+	ListWebhooks(ctx context.Context, arg ListWebhooksParams) ([]Webhook, error)
+	MarkOutboxEventsPublished(ctx context.Context, ids []int64) error
+	// Gives up the lease on events that weren't published, so that the next relay
+	// picks them up without waiting for the lease to expire.
+	ReleaseOutboxEvents(ctx context.Context, ids []int64) error
+	RetryTask(ctx context.Context, arg RetryTaskParams) (Task, error)
+	// Totals what the reversals of a transfer took from its destination account
+	// (amount) and gave back to its source account (to_amount).
+	SumTransferReversals(ctx context.Context, transferID int64) (SumTransferReversalsRow, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
 	UpdateEntry(ctx context.Context, arg UpdateEntryParams) (Entry, error)
+	UpdateFunding(ctx context.Context, arg UpdateFundingParams) (Funding, error)
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
 	UpdateTransfer(ctx context.Context, arg UpdateTransferParams) (Transfer, error)
+	UpdateUserEmailVerified(ctx context.Context, username string) (User, error)
+	UpdateUserTokensRevokedAt(ctx context.Context, arg UpdateUserTokensRevokedAtParams) (User, error)
+	// Marks the code as used, provided it matches, hasn't been used before and
+	// hasn't expired.
+	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UpdateWebhookDeliveryStatus(ctx context.Context, arg UpdateWebhookDeliveryStatusParams) (WebhookDelivery, error)
+	UpsertRate(ctx context.Context, arg UpsertRateParams) (Rate, error)
+	UpsertReconciliationCheckpoint(ctx context.Context, arg UpsertReconciliationCheckpointParams) error
+	UpsertTransferLimit(ctx context.Context, arg UpsertTransferLimitParams) (TransferLimit, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertRate(t *testing.T) {
+	base := util.RandomString(3)
+	params := UpsertRateParams{
+		BaseCurrency:  base,
+		QuoteCurrency: util.EUR,
+		Rate:          "0.92",
+		UpdatedAt:     time.Now().Add(-time.Hour),
+	}
+	rate, err := testQueries.UpsertRate(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, params.BaseCurrency, rate.BaseCurrency)
+	require.Equal(t, params.QuoteCurrency, rate.QuoteCurrency)
+	require.Equal(t, params.Rate, rate.Rate)
+	require.WithinDuration(t, params.UpdatedAt, rate.UpdatedAt, time.Second)
+
+	// a newer rate for the same pair replaces the old one
+	params.Rate = "0.935"
+	params.UpdatedAt = time.Now()
+	rate, err = testQueries.UpsertRate(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, params.Rate, rate.Rate)
+
+	stored, err := testQueries.GetRate(context.Background(), GetRateParams{
+		BaseCurrency:  base,
+		QuoteCurrency: util.EUR,
+	})
+	require.NoError(t, err)
+	require.Equal(t, rate, stored)
+}
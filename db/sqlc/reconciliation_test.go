@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliationQueries(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountWithBalance(t, 0)
+	deposit, err := store.FundingTx(context.Background(), FundingTxParams{
+		AccountID: account.ID,
+		Amount:    100,
+		Kind:      FundingDeposit,
+		Settle: func(funding Funding) (string, error) {
+			return "ref", nil
+		},
+	})
+	require.NoError(t, err)
+
+	balances := func(incremental bool) ListAccountLedgerBalancesRow {
+		rows, err := testQueries.ListAccountLedgerBalances(context.Background(), ListAccountLedgerBalancesParams{
+			Incremental: incremental,
+			AfterID:     account.ID - 1,
+			PageLimit:   1,
+		})
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		require.Equal(t, account.ID, rows[0].ID)
+		return rows[0]
+	}
+
+	full := balances(false)
+	require.Equal(t, int64(100), full.Balance)
+	require.Equal(t, int64(100), full.EntriesBalance)
+	require.Equal(t, deposit.Entry.ID, full.LastEntryID)
+
+	err = testQueries.UpsertReconciliationCheckpoint(context.Background(), UpsertReconciliationCheckpointParams{
+		AccountID:   account.ID,
+		Balance:     full.EntriesBalance,
+		LastEntryID: full.LastEntryID,
+	})
+	require.NoError(t, err)
+
+	// an incremental run picks up from the checkpoint, and drift shows in both
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 150})
+	require.NoError(t, err)
+
+	incremental := balances(true)
+	require.Equal(t, int64(150), incremental.Balance)
+	require.Equal(t, int64(100), incremental.EntriesBalance)
+	require.Equal(t, deposit.Entry.ID, incremental.LastEntryID)
+	require.Equal(t, int64(100), balances(false).EntriesBalance)
+
+	transfers, err := testQueries.ListTransferEntryTotals(context.Background(), ListTransferEntryTotalsParams{
+		AfterID:   deposit.Transfer.ID - 1,
+		PageLimit: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, deposit.Transfer.ID, transfers[0].ID)
+	require.Equal(t, int64(-100), transfers[0].FromEntries)
+	require.Equal(t, int64(100), transfers[0].ToEntries)
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrTransferIsReversal = errors.New("a reversal cannot be reversed")
+	ErrTransferReversed   = errors.New("transfer is already fully reversed")
+	ErrReversalTooHigh    = errors.New("amount is more than is left to reverse of the transfer")
+	ErrReversalTooLow     = errors.New("amount is too low to be given back in the currency it was received in")
+	ErrTransferIsFunding  = errors.New("deposits and withdrawals cannot be reversed")
+)
+
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transferID"`
+	// how much to give back to the source account, in its currency; zero gives
+	// back all that is left of the transfer
+	Amount int64 `json:"amount"`
+	// called inside the transaction once the reversal is made, with queries
+	// that run in it; an error undoes the reversal
+	AfterTransfer func(q Querier, result TransferTxResult) error `json:"-"`
+}
+
+// ReverseTransferTx gives back a transfer, in full or in part, with a
+// compensating transfer the other way that is linked to it. The transfer
+// itself is never changed. It is locked while its reversals are totted up, so
+// that concurrent reversals can't give back more than it moved.
+//
+// Amounts are given back at the rate of the transfer, and the reversal that
+// gives back the rest of a transfer takes whatever is left of it from the
+// destination account, so that reversing a transfer in parts takes exactly as
+// much as reversing it in one go.
+func (s *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		transfer, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if transfer.ReversalOf.Valid {
+			return ErrTransferIsReversal
+		}
+
+		// money that left or entered the ledger through the funding gateway can
+		// only come back through it
+		_, err = q.GetFundingByTransfer(ctx, sql.NullInt64{Int64: transfer.ID, Valid: true})
+		if err == nil {
+			return ErrTransferIsFunding
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		reversed, err := q.SumTransferReversals(ctx, transfer.ID)
+		if err != nil {
+			return err
+		}
+
+		left := transfer.Amount - reversed.ToAmount
+		if left <= 0 {
+			return ErrTransferReversed
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = left
+		}
+		if amount > left {
+			return fmt.Errorf("%w: %d is left", ErrReversalTooHigh, left)
+		}
+
+		taken := transfer.ToAmount - reversed.Amount
+		if amount < left {
+			taken = proportion(transfer.ToAmount, amount, transfer.Amount)
+		}
+		if taken <= 0 {
+			return ErrReversalTooLow
+		}
+
+		result, err = makeTransfer(ctx, q, TransferTxParams{
+			FromAccountID: transfer.ToAccountID,
+			ToAccountID:   transfer.FromAccountID,
+			Amount:        taken,
+			ToAmount:      amount,
+			ExchangeRate:  reversalRate(taken, amount),
+			RateTimestamp: transfer.RateTimestamp,
+		}, sql.NullInt64{Int64: transfer.ID, Valid: true}, true)
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterTransfer != nil {
+			return arg.AfterTransfer(q, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// proportion computes total * part / whole, rounded down, without overflowing
+// on the way.
+func proportion(total, part, whole int64) int64 {
+	n := new(big.Int).Mul(big.NewInt(total), big.NewInt(part))
+	return n.Quo(n, big.NewInt(whole)).Int64()
+}
+
+// reversalRate is the rate a reversal converts what it takes into what it
+// gives back at.
+func reversalRate(taken, given int64) string {
+	rate := big.NewRat(given, taken)
+	if rate.IsInt() {
+		return rate.RatString()
+	}
+	return rate.FloatString(10)
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// a partial refund goes the other way and is linked to the transfer
+	refund, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     30,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account2.ID, refund.Transfer.FromAccountID)
+	require.Equal(t, account1.ID, refund.Transfer.ToAccountID)
+	require.Equal(t, int64(30), refund.Transfer.Amount)
+	require.Equal(t, int64(30), refund.Transfer.ToAmount)
+	require.True(t, refund.Transfer.ReversalOf.Valid)
+	require.Equal(t, transfer.Transfer.ID, refund.Transfer.ReversalOf.Int64)
+	require.Equal(t, int64(-30), refund.FromEntry.Amount)
+	require.Equal(t, account1.Balance-70, refund.ToAccount.Balance)
+
+	// more than is left can't be given back
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     80,
+	})
+	require.ErrorIs(t, err, ErrReversalTooHigh)
+
+	// reversals can't be reversed themselves
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: refund.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferIsReversal)
+
+	// without an amount, the rest is given back
+	rest, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: transfer.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(70), rest.Transfer.Amount)
+	require.Equal(t, account1.Balance, rest.ToAccount.Balance)
+	require.Equal(t, account2.Balance, rest.FromAccount.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: transfer.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferReversed)
+
+	// the transfer itself is left as it was
+	original, err := testQueries.GetTransfer(context.Background(), transfer.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), original.Amount)
+	require.False(t, original.ReversalOf.Valid)
+}
+
+func TestReverseTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithCurrency(t, 1000, util.USD)
+	account2 := createRandomAccountWithCurrency(t, 1000, util.EUR)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      92,
+		ExchangeRate:  "0.92",
+		RateTimestamp: time.Now(),
+	})
+	require.NoError(t, err)
+
+	// amounts are given back in the currency they were sent in, taking from the
+	// destination account at the rate of the transfer
+	refund, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     33,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), refund.Transfer.Amount)
+	require.Equal(t, int64(33), refund.Transfer.ToAmount)
+
+	// the last reversal takes exactly what is left
+	rest, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: transfer.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(62), rest.Transfer.Amount)
+	require.Equal(t, int64(67), rest.Transfer.ToAmount)
+	require.Equal(t, account1.Balance, rest.ToAccount.Balance)
+	require.Equal(t, account2.Balance, rest.FromAccount.Balance)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+
+	// only one of several concurrent full reversals goes through
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: transfer.Transfer.ID})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferReversed)
+	}
+	require.Equal(t, 1, succeeded)
+
+	reversed, err := testQueries.SumTransferReversals(context.Background(), transfer.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), reversed.ToAmount)
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTokenRevoked(t *testing.T) {
+	user := createRandomUser(t)
+
+	params := IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now(),
+	}
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), params)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:        params.ID,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), params)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// revoking the same token twice is not an error
+	err = testQueries.CreateRevokedToken(context.Background(), CreateRevokedTokenParams{
+		ID:        params.ID,
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrScheduledRunTaken = errors.New("another run of the scheduled transfer got there first")
+
+// Statuses of a scheduled transfer. Only active ones are run.
+const (
+	ScheduledTransferActive    = "active"
+	ScheduledTransferPaused    = "paused"
+	ScheduledTransferCompleted = "completed"
+	ScheduledTransferFailed    = "failed"
+)
+
+// Policies for runs that fail for lack of funds or because an account is not
+// active.
+const (
+	// the run is tried again a few times before it is skipped
+	FailurePolicyRetry = "retry"
+	// the run is skipped straight away
+	FailurePolicySkip = "skip"
+)
+
+// Outcomes of a run of a scheduled transfer.
+const (
+	ScheduledRunSucceeded = "succeeded"
+	ScheduledRunRetrying  = "retrying"
+	ScheduledRunSkipped   = "skipped"
+	// the run can never succeed, so the scheduled transfer failed for good
+	ScheduledRunFailed = "failed"
+)
+
+type RecordScheduledTransferRunParams struct {
+	ScheduledTransferID int64 `json:"scheduledTransferID"`
+	// next_run_at of the scheduled transfer when the run started
+	ScheduledFor time.Time     `json:"scheduledFor"`
+	Outcome      string        `json:"outcome"`
+	TransferID   sql.NullInt64 `json:"transferID"`
+	Error        string        `json:"error"`
+	// what the scheduled transfer moves on to after the run
+	Status    string    `json:"status"`
+	NextRunAt time.Time `json:"nextRunAt"`
+	Attempts  int32     `json:"attempts"`
+}
+
+// RecordScheduledTransferRun moves a scheduled transfer on after a run and
+// records the outcome of the run. It fails with ErrScheduledRunTaken when
+// another run for the same time got there first, so that running it in the
+// transaction of the transfer undoes the transfer, and each occurrence is paid
+// at most once.
+func RecordScheduledTransferRun(ctx context.Context, q Querier, arg RecordScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	_, err := q.AdvanceScheduledTransfer(ctx, AdvanceScheduledTransferParams{
+		Status:       arg.Status,
+		NextRunAt:    arg.NextRunAt,
+		Attempts:     arg.Attempts,
+		ID:           arg.ScheduledTransferID,
+		ScheduledFor: arg.ScheduledFor,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ScheduledTransferRun{}, ErrScheduledRunTaken
+	}
+	if err != nil {
+		return ScheduledTransferRun{}, err
+	}
+
+	return q.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+		ScheduledTransferID: arg.ScheduledTransferID,
+		ScheduledFor:        arg.ScheduledFor,
+		Outcome:             arg.Outcome,
+		TransferID:          arg.TransferID,
+		Error:               arg.Error,
+	})
+}
+
+// RecordScheduledTransferRunTx records a run that made no transfer, such as one
+// that is retried or skipped.
+func (s *SQLStore) RecordScheduledTransferRunTx(ctx context.Context, arg RecordScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	var run ScheduledTransferRun
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		run, err = RecordScheduledTransferRun(ctx, q, arg)
+		return err
+	})
+
+	return run, err
+}
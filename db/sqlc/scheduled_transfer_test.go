@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, fromAccount, toAccount Account, schedule string) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        10,
+		Schedule:      schedule,
+		FailurePolicy: FailurePolicyRetry,
+		NextRunAt:     time.Now().Add(-time.Minute).UTC().Truncate(time.Microsecond),
+	}
+
+	scheduledTransfer, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, scheduledTransfer.ID)
+	require.Equal(t, arg.Owner, scheduledTransfer.Owner)
+	require.Equal(t, arg.Amount, scheduledTransfer.Amount)
+	require.Equal(t, arg.Schedule, scheduledTransfer.Schedule)
+	require.Equal(t, ScheduledTransferActive, scheduledTransfer.Status)
+	require.WithinDuration(t, arg.NextRunAt, scheduledTransfer.NextRunAt, time.Second)
+	require.Zero(t, scheduledTransfer.Attempts)
+
+	return scheduledTransfer
+}
+
+func TestListDueScheduledTransfers(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, "@daily")
+
+	scheduledTransfers, err := testQueries.ListDueScheduledTransfers(context.Background(), 1000)
+	require.NoError(t, err)
+	require.Contains(t, scheduledTransferIDs(scheduledTransfers), scheduledTransfer.ID)
+
+	// paused transfers are not due
+	_, err = testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:            scheduledTransfer.ID,
+		Amount:        scheduledTransfer.Amount,
+		Schedule:      scheduledTransfer.Schedule,
+		FailurePolicy: scheduledTransfer.FailurePolicy,
+		Status:        ScheduledTransferPaused,
+		NextRunAt:     scheduledTransfer.NextRunAt,
+	})
+	require.NoError(t, err)
+
+	scheduledTransfers, err = testQueries.ListDueScheduledTransfers(context.Background(), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, scheduledTransferIDs(scheduledTransfers), scheduledTransfer.ID)
+}
+
+func TestRecordScheduledTransferRun(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, "@daily")
+	nextRunAt := scheduledTransfer.NextRunAt.Add(24 * time.Hour)
+
+	record := func(q Querier, transferID int64) error {
+		_, err := RecordScheduledTransferRun(context.Background(), q, RecordScheduledTransferRunParams{
+			ScheduledTransferID: scheduledTransfer.ID,
+			ScheduledFor:        scheduledTransfer.NextRunAt,
+			Outcome:             ScheduledRunSucceeded,
+			TransferID:          sql.NullInt64{Int64: transferID, Valid: true},
+			Status:              ScheduledTransferActive,
+			NextRunAt:           nextRunAt,
+		})
+		return err
+	}
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        scheduledTransfer.Amount,
+		AfterTransfer: func(q Querier, result TransferTxResult) error {
+			return record(q, result.Transfer.ID)
+		},
+	})
+	require.NoError(t, err)
+
+	// a second run for the same occurrence is undone along with its transfer
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        scheduledTransfer.Amount,
+		AfterTransfer: func(q Querier, result TransferTxResult) error {
+			return record(q, result.Transfer.ID)
+		},
+	})
+	require.ErrorIs(t, err, ErrScheduledRunTaken)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-scheduledTransfer.Amount, updatedAccount1.Balance)
+
+	updated, err := testQueries.GetScheduledTransfer(context.Background(), scheduledTransfer.ID)
+	require.NoError(t, err)
+	require.WithinDuration(t, nextRunAt, updated.NextRunAt, time.Second)
+
+	// the next occurrence fails and is retried
+	_, err = store.RecordScheduledTransferRunTx(context.Background(), RecordScheduledTransferRunParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		ScheduledFor:        updated.NextRunAt,
+		Outcome:             ScheduledRunRetrying,
+		Error:               ErrInsufficientFunds.Error(),
+		Status:              ScheduledTransferActive,
+		NextRunAt:           updated.NextRunAt.Add(time.Hour),
+		Attempts:            1,
+	})
+	require.NoError(t, err)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               5,
+		Offset:              0,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Equal(t, ScheduledRunRetrying, runs[0].Outcome)
+	require.False(t, runs[0].TransferID.Valid)
+	require.Equal(t, ScheduledRunSucceeded, runs[1].Outcome)
+	require.Equal(t, result.Transfer.ID, runs[1].TransferID.Int64)
+
+	// deleting a scheduled transfer drops its runs
+	require.NoError(t, testQueries.DeleteScheduledTransfer(context.Background(), scheduledTransfer.ID))
+	runs, err = testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		Limit:               5,
+		Offset:              0,
+	})
+	require.NoError(t, err)
+	require.Empty(t, runs)
+}
+
+func scheduledTransferIDs(scheduledTransfers []ScheduledTransfer) []int64 {
+	ids := make([]int64, len(scheduledTransfers))
+	for i, scheduledTransfer := range scheduledTransfers {
+		ids[i] = scheduledTransfer.ID
+	}
+	return ids
+}
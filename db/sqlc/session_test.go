@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, username string) Session {
+	params := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     username,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    "go-test",
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	session, err := testQueries.CreateSession(context.Background(), params)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, params.ID, session.ID)
+	require.Equal(t, params.Username, session.Username)
+	require.Equal(t, params.RefreshToken, session.RefreshToken)
+	require.Equal(t, params.UserAgent, session.UserAgent)
+	require.Equal(t, params.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, params.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomSession(t, user.Username)
+}
+
+func TestGetSession(t *testing.T) {
+	user := createRandomUser(t)
+	newSession := createRandomSession(t, user.Username)
+
+	session, err := testQueries.GetSession(context.Background(), newSession.ID)
+	require.NoError(t, err)
+	require.Equal(t, newSession, session)
+}
@@ -3,12 +3,43 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/leoomi/simplebank/util"
+)
+
+var (
+	ErrIdempotencyKeyInUse = errors.New("idempotency key is already in use")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrCurrencyMismatch    = errors.New("accounts have different currencies and no exchange rate was given")
+	ErrAccountNotActive    = errors.New("account is not active")
+	ErrSystemAccount       = errors.New("system accounts are only used by the ledger itself")
+	ErrStatusTransition    = errors.New("account cannot change to this status")
+	ErrBalanceNotZero      = errors.New("account balance must be zero to close it")
+	ErrStatusChanged       = errors.New("account status changed in the meantime")
+	ErrEmailVerified       = errors.New("email address is already verified")
+	ErrVerifyEmailTooSoon  = errors.New("a verification code was sent too recently")
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	RevokeAllTokensTx(ctx context.Context, arg RevokeAllTokensTxParams) error
+	ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (ChangeAccountStatusTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ResendVerifyEmailTx(ctx context.Context, arg ResendVerifyEmailTxParams) (VerifyEmail, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	ReplayWebhookDeliveryTx(ctx context.Context, deliveryID int64) (WebhookDelivery, error)
+	RelayOutbox(ctx context.Context, arg RelayOutboxParams) (int, error)
+	RecordScheduledTransferRunTx(ctx context.Context, arg RecordScheduledTransferRunParams) (ScheduledTransferRun, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (TransferTxResult, error)
+	VerifyAccountBalance(ctx context.Context, accountID int64) error
+	FundingTx(ctx context.Context, arg FundingTxParams) (FundingTxResult, error)
+	SettleFundingTx(ctx context.Context, arg SettleFundingTxParams) (FundingTxResult, error)
 }
 
 type SQLStore struct {
@@ -31,6 +62,9 @@ func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
 
 	q := New(tx)
 	err = fn(q)
+	if err == nil {
+		err = checkJournals(ctx, q)
+	}
 
 	if err != nil {
 		if rollbackErr := tx.Rollback(); rollbackErr != nil {
@@ -47,6 +81,23 @@ type TransferTxParams struct {
 	FromAccountID int64 `json:"fromAccountID"`
 	ToAccountID   int64 `json:"toAccountID"`
 	Amount        int64 `json:"amount"`
+	// amount credited to the destination account when its currency differs from
+	// the source account; transfers within a currency leave the conversion empty
+	ToAmount      int64     `json:"toAmount"`
+	ExchangeRate  string    `json:"exchangeRate"`
+	RateTimestamp time.Time `json:"rateTimestamp"`
+	// when set, the result is stored under the key inside the same transaction
+	Idempotency *IdempotencyParams `json:"-"`
+	// called inside the transaction once the transfer is made, with queries that
+	// run in it; an error undoes the transfer
+	AfterTransfer func(q Querier, result TransferTxResult) error `json:"-"`
+}
+
+type IdempotencyParams struct {
+	Username    string
+	Key         string
+	RequestHash string
+	ExpiresAt   time.Time
 }
 
 type TransferTxResult struct {
@@ -57,65 +108,456 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"To_entry"`
 }
 
+// TransferTx makes a transfer between customer accounts, failing when it goes
+// over a transfer limit of its source account or of the owner of that account.
 func (s *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := s.execTx(ctx, func(q *Queries) error {
 		var err error
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams(arg))
+		result, err = makeTransfer(ctx, q, arg, sql.NullInt64{}, false)
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+		err = checkTransferLimits(ctx, q, result.FromAccount, result.Transfer, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if arg.Idempotency != nil {
+			err = storeIdempotencyKey(ctx, q, *arg.Idempotency, result)
+			if err != nil {
+				return err
+			}
+		}
+
+		if arg.AfterTransfer != nil {
+			return arg.AfterTransfer(q, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// makeTransfer moves the money of a transfer between the accounts, with a
+// journal of the entries that record it, and emits the event that announces
+// it. A transfer that gives back another one is linked to it by reversalOf.
+// Only transfers the ledger makes itself set systemAccounts, which lets them
+// move money in and out of system accounts; any other transfer touching one
+// fails with ErrSystemAccount.
+func makeTransfer(ctx context.Context, q *Queries, arg TransferTxParams, reversalOf sql.NullInt64, systemAccounts bool) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	fromAccount, toAccount, err := lockTransferAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	if !systemAccounts && (fromAccount.Kind != AccountKindCustomer || toAccount.Kind != AccountKindCustomer) {
+		return result, ErrSystemAccount
+	}
+
+	// a reversal gives money back to where it came from, which it may still do
+	// once that account is frozen
+	toStatusOK := toAccount.Status == util.AccountStatusActive ||
+		(reversalOf.Valid && toAccount.Status == util.AccountStatusFrozen)
+	if fromAccount.Status != util.AccountStatusActive || !toStatusOK {
+		return result, ErrAccountNotActive
+	}
+
+	if len(arg.ExchangeRate) == 0 && fromAccount.Currency != toAccount.Currency {
+		return result, ErrCurrencyMismatch
+	}
+
+	// system accounts offset money coming into the ledger, so they may go as far
+	// below zero as it takes
+	if fromAccount.Kind == AccountKindCustomer && fromAccount.Balance-arg.Amount < -fromAccount.OverdraftLimit {
+		return result, ErrInsufficientFunds
+	}
+
+	toAmount, exchangeRate, rateTimestamp := arg.ToAmount, arg.ExchangeRate, arg.RateTimestamp
+	if len(exchangeRate) == 0 {
+		toAmount, exchangeRate, rateTimestamp = arg.Amount, "1", time.Now()
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      toAmount,
+		ExchangeRate:  exchangeRate,
+		RateTimestamp: rateTimestamp,
+		ReversalOf:    reversalOf,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	kind := JournalTransfer
+	switch {
+	case reversalOf.Valid:
+		kind = JournalReversal
+	case fromAccount.Kind == AccountKindClearing:
+		kind = JournalDeposit
+	case toAccount.Kind == AccountKindClearing:
+		kind = JournalWithdrawal
+	}
+
+	journal, err := q.CreateJournalTransaction(ctx, kind)
+	if err != nil {
+		return result, err
+	}
+
+	transferID := sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.FromAccountID,
+		Amount:     -arg.Amount,
+		JournalID:  journal.ID,
+		TransferID: transferID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.ToAccountID,
+		Amount:     toAmount,
+		JournalID:  journal.ID,
+		TransferID: transferID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if arg.FromAccountID < arg.ToAccountID {
+		var changeBalancesResult ChangeBalancesResult
+		changeBalancesResult, err = ChangeBalances(ctx, q, ChangeBalancesParams{
+			Account1ID: arg.FromAccountID,
+			Amount1:    -arg.Amount,
+			Account2ID: arg.ToAccountID,
+			Amount2:    toAmount,
+		})
+		result.FromAccount = changeBalancesResult.Account1
+		result.ToAccount = changeBalancesResult.Account2
+	} else {
+		var changeBalancesResult ChangeBalancesResult
+		changeBalancesResult, err = ChangeBalances(ctx, q, ChangeBalancesParams{
+			Account2ID: arg.FromAccountID,
+			Amount2:    -arg.Amount,
+			Account1ID: arg.ToAccountID,
+			Amount1:    toAmount,
+		})
+		result.FromAccount = changeBalancesResult.Account2
+		result.ToAccount = changeBalancesResult.Account1
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	if fromAccount.Currency != toAccount.Currency {
+		err = postExchange(ctx, q, journal.ID, result.Transfer, fromAccount.Currency, toAccount.Currency)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	err = emitEvent(ctx, q, EventTransferCreated, []string{fromAccount.Owner, toAccount.Owner}, result.Transfer)
+	return result, err
+}
+
+// lockTransferAccounts locks both accounts of a transfer, always in ID order so
+// that concurrent transfers between the same accounts cannot deadlock, and
+// returns the locked source and destination accounts.
+func lockTransferAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (fromAccount, toAccount Account, err error) {
+	if fromAccountID > toAccountID {
+		toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+		if err != nil {
+			return
+		}
+		fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+		return
+	}
+
+	fromAccount, err = q.GetAccountForUpdate(ctx, fromAccountID)
+	if err != nil {
+		return
+	}
+	toAccount, err = q.GetAccountForUpdate(ctx, toAccountID)
+	return
+}
+
+func storeIdempotencyKey(ctx context.Context, q *Queries, params IdempotencyParams, result TransferTxResult) error {
+	response, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:       params.Username,
+		IdempotencyKey: params.Key,
+		RequestHash:    params.RequestHash,
+		TransferID:     result.Transfer.ID,
+		Response:       response,
+		ExpiresAt:      params.ExpiresAt,
+	})
+	if err == sql.ErrNoRows {
+		// the key exists and has not expired yet, so the upsert was skipped
+		return ErrIdempotencyKeyInUse
+	}
+
+	return err
+}
+
+type RevokeAllTokensTxParams struct {
+	Username  string    `json:"username"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+// RevokeAllTokensTx rejects every token the user was issued up to RevokedAt and
+// blocks all of their sessions, so that no refresh token can mint new ones.
+func (s *SQLStore) RevokeAllTokensTx(ctx context.Context, arg RevokeAllTokensTxParams) error {
+	return s.execTx(ctx, func(q *Queries) error {
+		_, err := q.UpdateUserTokensRevokedAt(ctx, UpdateUserTokensRevokedAtParams{
+			Username:        arg.Username,
+			TokensRevokedAt: arg.RevokedAt,
 		})
 		if err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		return q.BlockUserSessions(ctx, arg.Username)
+	})
+}
+
+type CreateUserTxParams struct {
+	CreateUserParams
+	SecretCode string    `json:"secretCode"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// called inside the transaction once both rows exist, with queries that run
+	// in it; an error undoes the signup
+	AfterCreate func(q Querier, user User, verifyEmail VerifyEmail) error `json:"-"`
+}
+
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verifyEmail"`
+}
+
+// CreateUserTx signs a user up together with the code that verifies their
+// email address.
+func (s *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:   result.User.Username,
+			Email:      result.User.Email,
+			SecretCode: arg.SecretCode,
+			ExpiresAt:  arg.ExpiresAt,
 		})
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			var changeBalancesResult ChangeBalancesResult
-			changeBalancesResult, err = ChangeBalances(ctx, q, ChangeBalancesParams{
-				Account1ID: arg.FromAccountID,
-				Amount1:    -arg.Amount,
-				Account2ID: arg.ToAccountID,
-				Amount2:    arg.Amount,
-			})
-			result.FromAccount = changeBalancesResult.Account1
-			result.ToAccount = changeBalancesResult.Account2
-		} else {
-			var changeBalancesResult ChangeBalancesResult
-			changeBalancesResult, err = ChangeBalances(ctx, q, ChangeBalancesParams{
-				Account2ID: arg.FromAccountID,
-				Amount2:    -arg.Amount,
-				Account1ID: arg.ToAccountID,
-				Amount1:    arg.Amount,
-			})
-			result.FromAccount = changeBalancesResult.Account2
-			result.ToAccount = changeBalancesResult.Account1
+		if arg.AfterCreate != nil {
+			return arg.AfterCreate(q, result.User, result.VerifyEmail)
 		}
+		return nil
+	})
+
+	return result, err
+}
+
+type VerifyEmailTxParams struct {
+	EmailID    int64  `json:"emailID"`
+	SecretCode string `json:"secretCode"`
+}
+
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verifyEmail"`
+}
+
+// VerifyEmailTx uses up a verification code and marks the email of its user as
+// verified. It fails with sql.ErrNoRows when the code is wrong, already used or
+// expired.
+func (s *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
 
+		result.VerifyEmail, err = q.UpdateVerifyEmail(ctx, UpdateVerifyEmailParams{
+			ID:         arg.EmailID,
+			SecretCode: arg.SecretCode,
+		})
 		if err != nil {
 			return err
 		}
 
+		result.User, err = q.UpdateUserEmailVerified(ctx, result.VerifyEmail.Username)
 		return err
 	})
 
 	return result, err
 }
 
+type ResendVerifyEmailTxParams struct {
+	Username   string    `json:"username"`
+	SecretCode string    `json:"secretCode"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// no code is sent when the last one was sent after this
+	LastSentBefore time.Time `json:"lastSentBefore"`
+	// called inside the transaction once the code exists, with queries that run
+	// in it; an error undoes the code
+	AfterCreate func(q Querier, user User, verifyEmail VerifyEmail) error `json:"-"`
+}
+
+// ResendVerifyEmailTx creates a new code that verifies the email address of a
+// user, for when the one sent on signup expired or got lost. It fails with
+// ErrEmailVerified once the address is verified, and with ErrVerifyEmailTooSoon
+// when the last code was sent after LastSentBefore. The user is locked, so that
+// concurrent requests can't send more codes than that allows.
+func (s *SQLStore) ResendVerifyEmailTx(ctx context.Context, arg ResendVerifyEmailTxParams) (VerifyEmail, error) {
+	var result VerifyEmail
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		if user.IsEmailVerified {
+			return ErrEmailVerified
+		}
+
+		last, err := q.GetLatestVerifyEmail(ctx, user.Username)
+		if err == nil && last.CreatedAt.After(arg.LastSentBefore) {
+			return ErrVerifyEmailTooSoon
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		result, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:   user.Username,
+			Email:      user.Email,
+			SecretCode: arg.SecretCode,
+			ExpiresAt:  arg.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterCreate != nil {
+			return arg.AfterCreate(q, user, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// accountStatusTransitions lists the statuses an account may change to from
+// each status. Closed accounts can only be reopened.
+var accountStatusTransitions = map[string][]string{
+	util.AccountStatusActive: {util.AccountStatusFrozen, util.AccountStatusClosed},
+	util.AccountStatusFrozen: {util.AccountStatusActive, util.AccountStatusClosed},
+	util.AccountStatusClosed: {util.AccountStatusActive},
+}
+
+type ChangeAccountStatusTxParams struct {
+	AccountID int64 `json:"accountID"`
+	// status the change was authorized from
+	FromStatus string `json:"fromStatus"`
+	Status     string `json:"status"`
+	ChangedBy  string `json:"changedBy"`
+	Reason     string `json:"reason"`
+}
+
+type ChangeAccountStatusTxResult struct {
+	Account      Account             `json:"account"`
+	StatusChange AccountStatusChange `json:"statusChange"`
+}
+
+// ChangeAccountStatusTx moves an account to another status and records who did
+// it and why. The account is locked, so that a concurrent transfer can't leave
+// money on an account while it is being closed. Who may change the status
+// depends on the status the account is in, so the change fails with
+// ErrStatusChanged unless the account is still in FromStatus once locked.
+// System accounts always stay active, since the ledger can't do without them.
+func (s *SQLStore) ChangeAccountStatusTx(ctx context.Context, arg ChangeAccountStatusTxParams) (ChangeAccountStatusTxResult, error) {
+	var result ChangeAccountStatusTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Kind != AccountKindCustomer {
+			return ErrSystemAccount
+		}
+
+		if account.Status != arg.FromStatus {
+			return fmt.Errorf("%w: %s, not %s", ErrStatusChanged, account.Status, arg.FromStatus)
+		}
+
+		if !canChangeStatus(account.Status, arg.Status) {
+			return fmt.Errorf("%w: %s to %s", ErrStatusTransition, account.Status, arg.Status)
+		}
+
+		if arg.Status == util.AccountStatusClosed && account.Balance != 0 {
+			return ErrBalanceNotZero
+		}
+
+		result.Account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:     arg.AccountID,
+			Status: arg.Status,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.StatusChange, err = q.CreateAccountStatusChange(ctx, CreateAccountStatusChangeParams{
+			AccountID:  arg.AccountID,
+			FromStatus: account.Status,
+			ToStatus:   arg.Status,
+			ChangedBy:  arg.ChangedBy,
+			Reason:     arg.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		return emitEvent(ctx, q, EventAccountStatusChanged, []string{account.Owner}, result.StatusChange)
+	})
+
+	return result, err
+}
+
+func canChangeStatus(from, to string) bool {
+	for _, status := range accountStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
 type ChangeBalancesParams struct {
 	Account1ID int64
 	Amount1    int64
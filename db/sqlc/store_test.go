@@ -2,8 +2,13 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/leoomi/simplebank/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,8 +20,8 @@ type routineResult struct {
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
 	amount := int64(5)
 
 	resultChan := make(chan routineResult)
@@ -97,8 +102,8 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxAlternateSourceDestination(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
 	amount := int64(10)
 
 	errs := make(chan error)
@@ -136,3 +141,653 @@ func TestTransferTxAlternateSourceDestination(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxIdempotencyKey(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, util.RandomMoney(), account1.Currency)
+	amount := int64(10)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+		Idempotency: &IdempotencyParams{
+			Username:    account1.Owner,
+			Key:         util.RandomString(16),
+			RequestHash: util.RandomString(32),
+			ExpiresAt:   time.Now().Add(time.Hour),
+		},
+	}
+
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	record, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       arg.Idempotency.Username,
+		IdempotencyKey: arg.Idempotency.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.Idempotency.RequestHash, record.RequestHash)
+	require.Equal(t, result.Transfer.ID, record.TransferID)
+
+	var stored TransferTxResult
+	err = json.Unmarshal(record.Response, &stored)
+	require.NoError(t, err)
+	require.Equal(t, result.Transfer.ID, stored.Transfer.ID)
+	require.Equal(t, result.FromAccount.Balance, stored.FromAccount.Balance)
+
+	// a second transaction with the same key must roll back entirely
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+
+	updatedFromAccount, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, updatedFromAccount.Balance)
+}
+
+func TestTransferTxExpiredIdempotencyKey(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, util.RandomMoney(), account1.Currency)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Idempotency: &IdempotencyParams{
+			Username:    account1.Owner,
+			Key:         util.RandomString(16),
+			RequestHash: util.RandomString(32),
+			ExpiresAt:   time.Now().Add(-time.Minute),
+		},
+	}
+
+	_, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       arg.Idempotency.Username,
+		IdempotencyKey: arg.Idempotency.Key,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// an expired key can be claimed again
+	arg.Idempotency.ExpiresAt = time.Now().Add(time.Hour)
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	record, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       arg.Idempotency.Username,
+		IdempotencyKey: arg.Idempotency.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, result.Transfer.ID, record.TransferID)
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	amount := int64(10)
+	account1 := createRandomAccountWithBalance(t, 5*amount)
+	account2 := createRandomAccountWithCurrency(t, util.RandomMoney(), account1.Currency)
+
+	errs := make(chan error)
+
+	n := 10
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+			continue
+		}
+		succeeded++
+	}
+	require.Equal(t, 5, succeeded)
+
+	updatedFromAccount, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedFromAccount.Balance)
+
+	updatedToAccount, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance+5*amount, updatedToAccount.Balance)
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	amount := int64(10)
+	account1 := createRandomAccountWithBalance(t, 0)
+	account2 := createRandomAccountWithCurrency(t, util.RandomMoney(), account1.Currency)
+
+	account1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 3 * amount,
+	})
+	require.NoError(t, err)
+
+	errs := make(chan error)
+
+	n := 5
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+			continue
+		}
+		succeeded++
+	}
+	require.Equal(t, 3, succeeded)
+
+	updatedFromAccount, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, -account1.OverdraftLimit, updatedFromAccount.Balance)
+}
+
+func TestTransferTxConcurrentNeverOverdraws(t *testing.T) {
+	store := NewStore(testDB)
+
+	amount := int64(10)
+	account1 := createRandomAccountWithBalance(t, amount)
+	account2 := createRandomAccountWithCurrency(t, amount, account1.Currency)
+
+	resultChan := make(chan routineResult)
+
+	n := 20
+	for i := 0; i < n; i++ {
+		fromAccountID := account1.ID
+		toAccountID := account2.ID
+		if i%2 == 1 {
+			fromAccountID, toAccountID = toAccountID, fromAccountID
+		}
+
+		go func() {
+			result, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: fromAccountID,
+				ToAccountID:   toAccountID,
+				Amount:        amount,
+			})
+
+			resultChan <- routineResult{err, result}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		result := <-resultChan
+		if result.err != nil {
+			require.ErrorIs(t, result.err, ErrInsufficientFunds)
+			continue
+		}
+		require.GreaterOrEqual(t, result.result.FromAccount.Balance, int64(0))
+		require.GreaterOrEqual(t, result.result.ToAccount.Balance, int64(0))
+	}
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+
+	require.GreaterOrEqual(t, updatedAccount1.Balance, int64(0))
+	require.GreaterOrEqual(t, updatedAccount2.Balance, int64(0))
+	require.Equal(t, account1.Balance+account2.Balance, updatedAccount1.Balance+updatedAccount2.Balance)
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithCurrency(t, 1000, util.USD)
+	account2 := createRandomAccountWithCurrency(t, 1000, util.EUR)
+
+	rateTimestamp := time.Now().Add(-time.Minute)
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      92,
+		ExchangeRate:  "0.92",
+		RateTimestamp: rateTimestamp,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(100), result.Transfer.Amount)
+	require.Equal(t, int64(92), result.Transfer.ToAmount)
+	require.Equal(t, "0.92", result.Transfer.ExchangeRate)
+	require.WithinDuration(t, rateTimestamp, result.Transfer.RateTimestamp, time.Second)
+
+	// each entry is in the currency of its own account
+	require.Equal(t, int64(-100), result.FromEntry.Amount)
+	require.Equal(t, int64(92), result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-100, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+92, result.ToAccount.Balance)
+}
+
+func TestTransferTxCurrencyMismatch(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithCurrency(t, 1000, util.USD)
+	account2 := createRandomAccountWithCurrency(t, 1000, util.EUR)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount.Balance)
+}
+
+func TestTransferTxAccountNotActive(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccountWithCurrency(t, util.RandomMoney(), account1.Currency)
+
+	_, err := testQueries.UpdateAccountStatus(context.Background(), UpdateAccountStatusParams{
+		ID:     account2.ID,
+		Status: util.AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+}
+
+func TestTransferTxSystemAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountWithCurrency(t, 1000, util.USD)
+	clearing, err := testQueries.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		Kind:     AccountKindClearing,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: clearing.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrSystemAccount)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   clearing.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrSystemAccount)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+}
+
+func TestTransferTxAfterTransfer(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
+
+	var task Task
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		AfterTransfer: func(q Querier, result TransferTxResult) (err error) {
+			task, err = q.CreateTask(context.Background(), CreateTaskParams{
+				Type:        "task:test",
+				Payload:     json.RawMessage(`{}`),
+				MaxAttempts: 1,
+				RunAt:       time.Now().Add(time.Hour),
+			})
+			return err
+		},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, task.ID)
+	require.NoError(t, testQueries.CompleteTask(context.Background(), task.ID))
+
+	// a failing callback undoes the transfer
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		AfterTransfer: func(q Querier, result TransferTxResult) error {
+			return sql.ErrConnDone
+		},
+	})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FromAccount.Balance, account.Balance)
+}
+
+func TestChangeAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB)
+	admin := createRandomUser(t)
+	account := createRandomAccountWithBalance(t, 0)
+
+	result, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID:  account.ID,
+		FromStatus: util.AccountStatusActive,
+		Status:     util.AccountStatusFrozen,
+		ChangedBy:  admin.Username,
+		Reason:     "suspicious activity",
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.AccountStatusFrozen, result.Account.Status)
+	require.Equal(t, account.ID, result.StatusChange.AccountID)
+	require.Equal(t, util.AccountStatusActive, result.StatusChange.FromStatus)
+	require.Equal(t, util.AccountStatusFrozen, result.StatusChange.ToStatus)
+	require.Equal(t, admin.Username, result.StatusChange.ChangedBy)
+	require.Equal(t, "suspicious activity", result.StatusChange.Reason)
+	require.NotZero(t, result.StatusChange.CreatedAt)
+
+	// the freeze was authorized for an active account, not a frozen one
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID:  account.ID,
+		FromStatus: util.AccountStatusActive,
+		Status:     util.AccountStatusClosed,
+		ChangedBy:  account.Owner,
+		Reason:     "closed on request",
+	})
+	require.ErrorIs(t, err, ErrStatusChanged)
+
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID:  account.ID,
+		FromStatus: util.AccountStatusFrozen,
+		Status:     util.AccountStatusClosed,
+		ChangedBy:  admin.Username,
+		Reason:     "closed on request",
+	})
+	require.NoError(t, err)
+
+	// closed accounts can only be reopened
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID:  account.ID,
+		FromStatus: util.AccountStatusClosed,
+		Status:     util.AccountStatusFrozen,
+		ChangedBy:  admin.Username,
+	})
+	require.ErrorIs(t, err, ErrStatusTransition)
+
+	changes, err := testQueries.ListAccountStatusChanges(context.Background(), ListAccountStatusChangesParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, util.AccountStatusClosed, changes[0].ToStatus)
+	require.Equal(t, util.AccountStatusFrozen, changes[1].ToStatus)
+}
+
+func TestChangeAccountStatusTxCloseWithBalance(t *testing.T) {
+	store := NewStore(testDB)
+	account := createRandomAccountWithBalance(t, 10)
+
+	_, err := store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID:  account.ID,
+		FromStatus: util.AccountStatusActive,
+		Status:     util.AccountStatusClosed,
+		ChangedBy:  account.Owner,
+		Reason:     "no longer needed",
+	})
+	require.ErrorIs(t, err, ErrBalanceNotZero)
+
+	account, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.AccountStatusActive, account.Status)
+}
+
+func TestChangeAccountStatusTxSystemAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	clearing, err := testQueries.GetSystemAccount(context.Background(), GetSystemAccountParams{
+		Kind:     AccountKindClearing,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ChangeAccountStatusTx(context.Background(), ChangeAccountStatusTxParams{
+		AccountID:  clearing.ID,
+		FromStatus: clearing.Status,
+		Status:     util.AccountStatusFrozen,
+		ChangedBy:  "admin",
+		Reason:     "suspicious activity",
+	})
+	require.ErrorIs(t, err, ErrSystemAccount)
+
+	clearing, err = testQueries.GetAccount(context.Background(), clearing.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.AccountStatusActive, clearing.Status)
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	hashedPassword, err := util.HashedPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username: util.RandomOwner(),
+			Password: hashedPassword,
+			FullName: util.RandomOwner(),
+			Email:    util.RandomEmail(),
+		},
+		SecretCode: util.RandomString(32),
+		ExpiresAt:  time.Now().Add(time.Minute),
+	}
+
+	var created VerifyEmail
+	arg.AfterCreate = func(q Querier, user User, verifyEmail VerifyEmail) error {
+		created = verifyEmail
+		return nil
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, result.VerifyEmail, created)
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.SecretCode, result.VerifyEmail.SecretCode)
+	require.False(t, result.VerifyEmail.IsUsed)
+
+	// a failing callback undoes the signup
+	arg.Username = util.RandomOwner()
+	arg.Email = util.RandomEmail()
+	arg.AfterCreate = func(q Querier, user User, verifyEmail VerifyEmail) error {
+		return sql.ErrConnDone
+	}
+	_, err = store.CreateUserTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	_, err = testQueries.GetUser(context.Background(), arg.Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiresAt:  time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: "wrong",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+
+	// codes can only be used once
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiresAt:  time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestResendVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	expired, err := testQueries.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+		ExpiresAt:  time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	// the expired code was sent just now, so another one has to wait
+	_, err = store.ResendVerifyEmailTx(context.Background(), ResendVerifyEmailTxParams{
+		Username:       user.Username,
+		SecretCode:     util.RandomString(32),
+		ExpiresAt:      time.Now().Add(time.Minute),
+		LastSentBefore: time.Now().Add(-time.Minute),
+	})
+	require.ErrorIs(t, err, ErrVerifyEmailTooSoon)
+
+	verifyEmail, err := store.ResendVerifyEmailTx(context.Background(), ResendVerifyEmailTxParams{
+		Username:       user.Username,
+		SecretCode:     util.RandomString(32),
+		ExpiresAt:      time.Now().Add(time.Minute),
+		LastSentBefore: time.Now().Add(time.Second),
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, expired.ID, verifyEmail.ID)
+	require.Equal(t, user.Email, verifyEmail.Email)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    expired.ID,
+		SecretCode: expired.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.NoError(t, err)
+	require.True(t, result.User.IsEmailVerified)
+
+	_, err = store.ResendVerifyEmailTx(context.Background(), ResendVerifyEmailTxParams{
+		Username:       user.Username,
+		SecretCode:     util.RandomString(32),
+		ExpiresAt:      time.Now().Add(time.Minute),
+		LastSentBefore: time.Now().Add(time.Second),
+	})
+	require.ErrorIs(t, err, ErrEmailVerified)
+}
+
+func TestRevokeAllTokensTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user.Username)
+
+	issuedBefore := time.Now().Add(-time.Minute)
+	err := store.RevokeAllTokensTx(context.Background(), RevokeAllTokensTxParams{
+		Username:  user.Username,
+		RevokedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: issuedBefore,
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+}
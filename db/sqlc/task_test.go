@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createDueTask creates a task that has been due for longer than any other, so
+// that it is the next one claimed.
+func createDueTask(t *testing.T) Task {
+	arg := CreateTaskParams{
+		Type:        "task:test",
+		Payload:     json.RawMessage(`{"key": "value"}`),
+		MaxAttempts: 3,
+		RunAt:       time.Unix(0, 0),
+	}
+
+	task, err := testQueries.CreateTask(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, task.ID)
+	require.Equal(t, arg.Type, task.Type)
+	require.JSONEq(t, string(arg.Payload), string(task.Payload))
+	require.Equal(t, "pending", task.Status)
+	require.Zero(t, task.Attempts)
+	require.Equal(t, arg.MaxAttempts, task.MaxAttempts)
+	require.Empty(t, task.LastError)
+
+	return task
+}
+
+func TestClaimTask(t *testing.T) {
+	task := createDueTask(t)
+
+	leaseExpiresAt := time.Now().Add(time.Minute)
+	claimed, err := testQueries.ClaimTask(context.Background(), leaseExpiresAt)
+	require.NoError(t, err)
+	require.Equal(t, task.ID, claimed.ID)
+	require.Equal(t, "running", claimed.Status)
+	require.Equal(t, int32(1), claimed.Attempts)
+	require.WithinDuration(t, leaseExpiresAt, claimed.RunAt, time.Second)
+
+	err = testQueries.CompleteTask(context.Background(), claimed.ID)
+	require.NoError(t, err)
+}
+
+func TestClaimTaskSkipsLockedTasks(t *testing.T) {
+	task1 := createDueTask(t)
+	task2 := createDueTask(t)
+
+	// hold the claim of the first task open in a transaction
+	tx, err := testDB.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	claimed1, err := New(tx).ClaimTask(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, task1.ID, claimed1.ID)
+
+	claimed2, err := testQueries.ClaimTask(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, task2.ID, claimed2.ID)
+
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, testQueries.CompleteTask(context.Background(), task2.ID))
+
+	claimed1, err = testQueries.ClaimTask(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, task1.ID, claimed1.ID)
+	require.NoError(t, testQueries.CompleteTask(context.Background(), task1.ID))
+}
+
+func TestClaimTaskWithExpiredLease(t *testing.T) {
+	task := createDueTask(t)
+
+	// the worker that claimed it first never finished
+	_, err := testQueries.ClaimTask(context.Background(), time.Unix(0, 0))
+	require.NoError(t, err)
+
+	claimed, err := testQueries.ClaimTask(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, task.ID, claimed.ID)
+	require.Equal(t, int32(2), claimed.Attempts)
+
+	require.NoError(t, testQueries.CompleteTask(context.Background(), task.ID))
+}
+
+func TestRetryTask(t *testing.T) {
+	task := createDueTask(t)
+
+	_, err := testQueries.ClaimTask(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	runAt := time.Now().Add(time.Hour)
+	retried, err := testQueries.RetryTask(context.Background(), RetryTaskParams{
+		ID:        task.ID,
+		RunAt:     runAt,
+		LastError: "connection refused",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pending", retried.Status)
+	require.Equal(t, int32(1), retried.Attempts)
+	require.Equal(t, "connection refused", retried.LastError)
+	require.WithinDuration(t, runAt, retried.RunAt, time.Second)
+
+	require.NoError(t, testQueries.CompleteTask(context.Background(), task.ID))
+}
+
+func TestFailTask(t *testing.T) {
+	task := createDueTask(t)
+
+	_, err := testQueries.ClaimTask(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	failed, err := testQueries.FailTask(context.Background(), FailTaskParams{
+		ID:        task.ID,
+		LastError: "gave up",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "dead", failed.Status)
+	require.Equal(t, "gave up", failed.LastError)
+}
@@ -7,26 +7,44 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate,
+  rate_timestamp,
+  reversal_of
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rate_timestamp, reversal_of
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"fromAccountID"`
-	ToAccountID   int64 `json:"toAccountID"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64         `json:"fromAccountID"`
+	ToAccountID   int64         `json:"toAccountID"`
+	Amount        int64         `json:"amount"`
+	ToAmount      int64         `json:"toAmount"`
+	ExchangeRate  string        `json:"exchangeRate"`
+	RateTimestamp time.Time     `json:"rateTimestamp"`
+	ReversalOf    sql.NullInt64 `json:"reversalOf"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.RateTimestamp,
+		arg.ReversalOf,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,6 +52,10 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.RateTimestamp,
+		&i.ReversalOf,
 	)
 	return i, err
 }
@@ -49,7 +71,7 @@ func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rate_timestamp, reversal_of FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -62,12 +84,39 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.RateTimestamp,
+		&i.ReversalOf,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rate_timestamp, reversal_of FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.RateTimestamp,
+		&i.ReversalOf,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rate_timestamp, reversal_of FROM transfers
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -93,6 +142,10 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.RateTimestamp,
+			&i.ReversalOf,
 		); err != nil {
 			return nil, err
 		}
@@ -108,7 +161,7 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 }
 
 const listTransfersFrom = `-- name: ListTransfersFrom :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rate_timestamp, reversal_of FROM transfers
 WHERE from_account_id = $1
 ORDER BY id
 LIMIT $2
@@ -136,6 +189,10 @@ func (q *Queries) ListTransfersFrom(ctx context.Context, arg ListTransfersFromPa
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.RateTimestamp,
+			&i.ReversalOf,
 		); err != nil {
 			return nil, err
 		}
@@ -151,7 +208,7 @@ func (q *Queries) ListTransfersFrom(ctx context.Context, arg ListTransfersFromPa
 }
 
 const listTransfersTo = `-- name: ListTransfersTo :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rate_timestamp, reversal_of FROM transfers
 WHERE to_account_id = $1
 ORDER BY id
 LIMIT $2
@@ -179,6 +236,10 @@ func (q *Queries) ListTransfersTo(ctx context.Context, arg ListTransfersToParams
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.RateTimestamp,
+			&i.ReversalOf,
 		); err != nil {
 			return nil, err
 		}
@@ -193,11 +254,134 @@ func (q *Queries) ListTransfersTo(ctx context.Context, arg ListTransfersToParams
 	return items, nil
 }
 
+const listUserTransfers = `-- name: ListUserTransfers :many
+SELECT
+  t.id, t.from_account_id, t.to_account_id, t.amount, t.created_at, t.to_amount, t.exchange_rate, t.rate_timestamp, t.reversal_of,
+  (CASE
+    WHEN fa.owner = $1 AND ta.owner = $1 THEN 'internal'
+    WHEN fa.owner = $1 THEN 'outgoing'
+    ELSE 'incoming'
+  END)::varchar AS direction,
+  fa.currency AS from_currency,
+  ta.currency AS to_currency
+FROM transfers t
+JOIN accounts fa ON fa.id = t.from_account_id
+JOIN accounts ta ON ta.id = t.to_account_id
+WHERE (fa.owner = $1 OR ta.owner = $1)
+  AND ($2::bigint IS NULL OR t.id < $2)
+  AND (
+    $3::bigint IS NULL
+    OR t.from_account_id = $3
+    OR t.to_account_id = $3
+  )
+  AND ($4::bigint IS NULL OR t.amount >= $4)
+  AND ($5::bigint IS NULL OR t.amount <= $5)
+  AND ($6::timestamptz IS NULL OR t.created_at >= $6)
+  AND ($7::timestamptz IS NULL OR t.created_at < $7)
+ORDER BY t.id DESC
+LIMIT $8
+`
+
+type ListUserTransfersParams struct {
+	Owner                 string        `json:"owner"`
+	Cursor                sql.NullInt64 `json:"cursor"`
+	CounterpartyAccountID sql.NullInt64 `json:"counterpartyAccountID"`
+	MinAmount             sql.NullInt64 `json:"minAmount"`
+	MaxAmount             sql.NullInt64 `json:"maxAmount"`
+	FromTime              sql.NullTime  `json:"fromTime"`
+	ToTime                sql.NullTime  `json:"toTime"`
+	PageLimit             int32         `json:"pageLimit"`
+}
+
+type ListUserTransfersRow struct {
+	ID            int64         `json:"id"`
+	FromAccountID int64         `json:"fromAccountID"`
+	ToAccountID   int64         `json:"toAccountID"`
+	Amount        int64         `json:"amount"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	ToAmount      int64         `json:"toAmount"`
+	ExchangeRate  string        `json:"exchangeRate"`
+	RateTimestamp time.Time     `json:"rateTimestamp"`
+	ReversalOf    sql.NullInt64 `json:"reversalOf"`
+	Direction     string        `json:"direction"`
+	FromCurrency  string        `json:"fromCurrency"`
+	ToCurrency    string        `json:"toCurrency"`
+}
+
+// Keyset paginated feed of every transfer touching an account of the owner,
+// newest first. Pass the last ID of the previous page as cursor.
+func (q *Queries) ListUserTransfers(ctx context.Context, arg ListUserTransfersParams) ([]ListUserTransfersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserTransfers,
+		arg.Owner,
+		arg.Cursor,
+		arg.CounterpartyAccountID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.FromTime,
+		arg.ToTime,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserTransfersRow{}
+	for rows.Next() {
+		var i ListUserTransfersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.RateTimestamp,
+			&i.ReversalOf,
+			&i.Direction,
+			&i.FromCurrency,
+			&i.ToCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumTransferReversals = `-- name: SumTransferReversals :one
+SELECT
+  COALESCE(SUM(amount), 0)::bigint AS amount,
+  COALESCE(SUM(to_amount), 0)::bigint AS to_amount
+FROM transfers
+WHERE reversal_of = $1::bigint
+`
+
+type SumTransferReversalsRow struct {
+	Amount   int64 `json:"amount"`
+	ToAmount int64 `json:"toAmount"`
+}
+
+// Totals what the reversals of a transfer took from its destination account
+// (amount) and gave back to its source account (to_amount).
+func (q *Queries) SumTransferReversals(ctx context.Context, transferID int64) (SumTransferReversalsRow, error) {
+	row := q.db.QueryRowContext(ctx, sumTransferReversals, transferID)
+	var i SumTransferReversalsRow
+	err := row.Scan(&i.Amount, &i.ToAmount)
+	return i, err
+}
+
 const updateTransfer = `-- name: UpdateTransfer :one
 UPDATE transfers
   SET amount = $2
 WHERE id = $1
-RETURNING id, from_account_id, to_account_id, amount, created_at
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, rate_timestamp, reversal_of
 `
 
 type UpdateTransferParams struct {
@@ -214,6 +398,10 @@ func (q *Queries) UpdateTransfer(ctx context.Context, arg UpdateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.RateTimestamp,
+		&i.ReversalOf,
 	)
 	return i, err
 }
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTransferLimitExceeded = errors.New("transfer is larger than allowed")
+	ErrDailyLimitExceeded    = errors.New("transfer goes over the daily limit")
+	ErrMonthlyLimitExceeded  = errors.New("transfer goes over the monthly limit")
+)
+
+// checkTransferLimits fails when a transfer that was just made from account
+// goes over a limit of the account or of its owner. The limits of an account
+// fall back to the defaults of its currency one by one; its owner is only
+// limited by limits of their own. Daily and monthly totals start at midnight
+// UTC and include the transfer.
+//
+// The account is locked by the transfer, and the owner is locked before their
+// totals are added up, so that concurrent transfers can't both fit under a
+// limit they go over together.
+func checkTransferLimits(ctx context.Context, q *Queries, account Account, transfer Transfer, now time.Time) error {
+	limits, err := q.ListApplicableTransferLimits(ctx, ListApplicableTransferLimitsParams{
+		Currency:  account.Currency,
+		Owner:     account.Owner,
+		AccountID: account.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	var defaults, accountLimit, userLimit TransferLimit
+	for _, limit := range limits {
+		switch {
+		case limit.AccountID.Valid:
+			accountLimit = limit
+		case limit.Owner.Valid:
+			userLimit = limit
+		default:
+			defaults = limit
+		}
+	}
+
+	accountLimit.MaxAmount = orDefault(accountLimit.MaxAmount, defaults.MaxAmount)
+	accountLimit.DailyAmount = orDefault(accountLimit.DailyAmount, defaults.DailyAmount)
+	accountLimit.MonthlyAmount = orDefault(accountLimit.MonthlyAmount, defaults.MonthlyAmount)
+
+	if exceeds(transfer.Amount, accountLimit.MaxAmount) {
+		return fmt.Errorf("%w: at most %d %s per transfer from the account", ErrTransferLimitExceeded, accountLimit.MaxAmount.Int64, account.Currency)
+	}
+	if exceeds(transfer.Amount, userLimit.MaxAmount) {
+		return fmt.Errorf("%w: at most %d %s per transfer from the user", ErrTransferLimitExceeded, userLimit.MaxAmount.Int64, account.Currency)
+	}
+
+	limitsUser := userLimit.DailyAmount.Valid || userLimit.MonthlyAmount.Valid
+	if !limitsUser && !accountLimit.DailyAmount.Valid && !accountLimit.MonthlyAmount.Valid {
+		return nil
+	}
+
+	if limitsUser {
+		_, err = q.GetUserForUpdate(ctx, account.Owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	year, month, day := now.UTC().Date()
+	totals, err := q.GetOutgoingTransferTotals(ctx, GetOutgoingTransferTotalsParams{
+		AccountID:  account.ID,
+		DayStart:   time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
+		Owner:      account.Owner,
+		Currency:   account.Currency,
+		MonthStart: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case exceeds(totals.AccountDaily, accountLimit.DailyAmount):
+		return fmt.Errorf("%w: at most %d %s a day from the account", ErrDailyLimitExceeded, accountLimit.DailyAmount.Int64, account.Currency)
+	case exceeds(totals.UserDaily, userLimit.DailyAmount):
+		return fmt.Errorf("%w: at most %d %s a day from the user", ErrDailyLimitExceeded, userLimit.DailyAmount.Int64, account.Currency)
+	case exceeds(totals.AccountMonthly, accountLimit.MonthlyAmount):
+		return fmt.Errorf("%w: at most %d %s a month from the account", ErrMonthlyLimitExceeded, accountLimit.MonthlyAmount.Int64, account.Currency)
+	case exceeds(totals.UserMonthly, userLimit.MonthlyAmount):
+		return fmt.Errorf("%w: at most %d %s a month from the user", ErrMonthlyLimitExceeded, userLimit.MonthlyAmount.Int64, account.Currency)
+	}
+	return nil
+}
+
+func orDefault(limit, defaultLimit sql.NullInt64) sql.NullInt64 {
+	if limit.Valid {
+		return limit
+	}
+	return defaultLimit
+}
+
+func exceeds(amount int64, limit sql.NullInt64) bool {
+	return limit.Valid && amount > limit.Int64
+}
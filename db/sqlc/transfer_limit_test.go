@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertTransferLimit(t *testing.T) {
+	account := createRandomAccount(t)
+
+	arg := UpsertTransferLimitParams{
+		Currency:  account.Currency,
+		AccountID: sql.NullInt64{Int64: account.ID, Valid: true},
+		MaxAmount: sql.NullInt64{Int64: 500, Valid: true},
+		UpdatedBy: util.RandomOwner(),
+	}
+	limit, err := testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.MaxAmount, limit.MaxAmount)
+	require.False(t, limit.Owner.Valid)
+	require.False(t, limit.DailyAmount.Valid)
+
+	// setting the limits of the same account again replaces them
+	arg.MaxAmount = sql.NullInt64{}
+	arg.DailyAmount = sql.NullInt64{Int64: 900, Valid: true}
+	replaced, err := testQueries.UpsertTransferLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, limit.ID, replaced.ID)
+	require.False(t, replaced.MaxAmount.Valid)
+	require.Equal(t, arg.DailyAmount, replaced.DailyAmount)
+
+	err = testQueries.DeleteTransferLimit(context.Background(), limit.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetTransferLimit(context.Background(), limit.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 0, account1.Currency)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Currency:    account1.Currency,
+		AccountID:   sql.NullInt64{Int64: account1.ID, Valid: true},
+		MaxAmount:   sql.NullInt64{Int64: 500, Valid: true},
+		DailyAmount: sql.NullInt64{Int64: 700, Valid: true},
+		UpdatedBy:   util.RandomOwner(),
+	})
+	require.NoError(t, err)
+
+	var last Transfer
+	transfer := func(from, to Account, amount int64) error {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        amount,
+		})
+		last = result.Transfer
+		return err
+	}
+
+	require.ErrorIs(t, transfer(account1, account2, 600), ErrTransferLimitExceeded)
+	require.NoError(t, transfer(account1, account2, 400))
+	require.ErrorIs(t, transfer(account1, account2, 400), ErrDailyLimitExceeded)
+	require.NoError(t, transfer(account1, account2, 300))
+
+	// transfers over a limit are rolled back
+	account1, err = testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(300), account1.Balance)
+
+	// what a reversal gives back no longer counts
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: last.ID,
+		Amount:     100,
+	})
+	require.NoError(t, err)
+	require.NoError(t, transfer(account1, account2, 100))
+	require.ErrorIs(t, transfer(account1, account2, 1), ErrDailyLimitExceeded)
+
+	// the owner of the destination account is limited on their own
+	_, err = testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Currency:      account2.Currency,
+		Owner:         sql.NullString{String: account2.Owner, Valid: true},
+		MonthlyAmount: sql.NullInt64{Int64: 200, Valid: true},
+		UpdatedBy:     util.RandomOwner(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, transfer(account2, account1, 200))
+	require.ErrorIs(t, transfer(account2, account1, 1), ErrMonthlyLimitExceeded)
+}
+
+func TestFundingTxLimits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountWithBalance(t, 1000)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Currency:    account.Currency,
+		AccountID:   sql.NullInt64{Int64: account.ID, Valid: true},
+		MaxAmount:   sql.NullInt64{Int64: 500, Valid: true},
+		DailyAmount: sql.NullInt64{Int64: 700, Valid: true},
+		UpdatedBy:   util.RandomOwner(),
+	})
+	require.NoError(t, err)
+
+	settled, decline := 0, false
+	fund := func(kind string, amount int64) error {
+		_, err := store.FundingTx(context.Background(), FundingTxParams{
+			AccountID: account.ID,
+			Amount:    amount,
+			Kind:      kind,
+			Settle: func(funding Funding) (string, error) {
+				settled++
+				if decline {
+					return "", ErrFundingDeclined
+				}
+				return "ref", nil
+			},
+		})
+		return err
+	}
+
+	// withdrawals are limited like transfers, before the gateway pays them out
+	require.ErrorIs(t, fund(FundingWithdrawal, 501), ErrTransferLimitExceeded)
+	decline = true
+	require.ErrorIs(t, fund(FundingWithdrawal, 500), ErrFundingDeclined)
+
+	// a declined withdrawal is given back in full, so it doesn't count
+	decline = false
+	require.NoError(t, fund(FundingWithdrawal, 500))
+	require.ErrorIs(t, fund(FundingWithdrawal, 201), ErrDailyLimitExceeded)
+	require.Equal(t, 2, settled)
+
+	// deposits send nothing from the account
+	require.NoError(t, fund(FundingDeposit, 800))
+}
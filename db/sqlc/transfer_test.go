@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/leoomi/simplebank/util"
 	"github.com/stretchr/testify/require"
@@ -17,10 +18,14 @@ func createRandomTransfer(t *testing.T) Transfer {
 }
 
 func createTransferForAccount(t *testing.T, fromID, toID int64) Transfer {
+	amount := util.RandomMoney()
 	params := CreateTransferParams{
 		FromAccountID: fromID,
 		ToAccountID:   toID,
-		Amount:        util.RandomMoney(),
+		Amount:        amount,
+		ToAmount:      amount,
+		ExchangeRate:  "1",
+		RateTimestamp: time.Now(),
 	}
 	transfer, err := testQueries.CreateTransfer(context.Background(), params)
 
@@ -30,6 +35,9 @@ func createTransferForAccount(t *testing.T, fromID, toID int64) Transfer {
 	require.Equal(t, params.Amount, transfer.Amount)
 	require.Equal(t, params.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, params.ToAccountID, transfer.ToAccountID)
+	require.Equal(t, params.ToAmount, transfer.ToAmount)
+	require.Equal(t, params.ExchangeRate, transfer.ExchangeRate)
+	require.WithinDuration(t, params.RateTimestamp, transfer.RateTimestamp, time.Second)
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
 
@@ -154,3 +162,52 @@ func TestListTransfersToAcount(t *testing.T) {
 		require.Equal(t, account.ID, transfer.ToAccountID)
 	}
 }
+
+func TestListUserTransfers(t *testing.T) {
+	account1, account2, transfers := createMultipleRandomTransfers(t, 10)
+	account3 := createRandomAccount(t)
+	incoming := createTransferForAccount(t, account3.ID, account1.ID)
+
+	params := ListUserTransfersParams{
+		Owner:     account1.Owner,
+		PageLimit: 5,
+	}
+	firstPage, err := testQueries.ListUserTransfers(context.Background(), params)
+	require.NoError(t, err)
+	require.Len(t, firstPage, 5)
+
+	require.Equal(t, incoming.ID, firstPage[0].ID)
+	require.Equal(t, "incoming", firstPage[0].Direction)
+	for i, transfer := range firstPage[1:] {
+		require.Equal(t, transfers[len(transfers)-1-i].ID, transfer.ID)
+		require.Equal(t, "outgoing", transfer.Direction)
+	}
+
+	params.Cursor = sql.NullInt64{Int64: firstPage[len(firstPage)-1].ID, Valid: true}
+	secondPage, err := testQueries.ListUserTransfers(context.Background(), params)
+	require.NoError(t, err)
+	require.Len(t, secondPage, 5)
+	require.Less(t, secondPage[0].ID, firstPage[len(firstPage)-1].ID)
+
+	params.Cursor = sql.NullInt64{}
+	params.CounterpartyAccountID = sql.NullInt64{Int64: account3.ID, Valid: true}
+	filtered, err := testQueries.ListUserTransfers(context.Background(), params)
+	require.NoError(t, err)
+	require.Len(t, filtered, 1)
+	require.Equal(t, incoming.ID, filtered[0].ID)
+
+	// other users never see the transfers
+	params = ListUserTransfersParams{
+		Owner:     createRandomUser(t).Username,
+		PageLimit: 5,
+	}
+	others, err := testQueries.ListUserTransfers(context.Background(), params)
+	require.NoError(t, err)
+	require.Empty(t, others)
+
+	params.Owner = account2.Owner
+	params.MinAmount = sql.NullInt64{Int64: 10001, Valid: true}
+	tooLarge, err := testQueries.ListUserTransfers(context.Background(), params)
+	require.NoError(t, err)
+	require.Empty(t, tooLarge)
+}
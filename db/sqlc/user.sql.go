@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -17,7 +18,7 @@ INSERT INTO users (
   email
 ) VALUES (
   $1, $2, $3, $4
-) RETURNING username, password, full_name, email, password_changed_at, created_at
+) RETURNING username, password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role, is_email_verified
 `
 
 type CreateUserParams struct {
@@ -42,12 +43,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+		&i.IsEmailVerified,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, password, full_name, email, password_changed_at, created_at FROM users
+SELECT username, password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role, is_email_verified FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -61,6 +65,85 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT username, password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role, is_email_verified FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.Password,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+	)
+	return i, err
+}
+
+const updateUserEmailVerified = `-- name: UpdateUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+RETURNING username, password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role, is_email_verified
+`
+
+func (q *Queries) UpdateUserEmailVerified(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserEmailVerified, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.Password,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+	)
+	return i, err
+}
+
+const updateUserTokensRevokedAt = `-- name: UpdateUserTokensRevokedAt :one
+UPDATE users
+SET tokens_revoked_at = $2
+WHERE username = $1
+RETURNING username, password, full_name, email, password_changed_at, created_at, tokens_revoked_at, role, is_email_verified
+`
+
+type UpdateUserTokensRevokedAtParams struct {
+	Username        string    `json:"username"`
+	TokensRevokedAt time.Time `json:"tokensRevokedAt"`
+}
+
+func (q *Queries) UpdateUserTokensRevokedAt(ctx context.Context, arg UpdateUserTokensRevokedAtParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserTokensRevokedAt, arg.Username, arg.TokensRevokedAt)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.Password,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TokensRevokedAt,
+		&i.Role,
+		&i.IsEmailVerified,
 	)
 	return i, err
 }
@@ -26,6 +26,8 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, params.Password, user.Password)
 	require.Equal(t, params.FullName, user.FullName)
 	require.Equal(t, params.Email, user.Email)
+	require.Equal(t, util.DepositorRole, user.Role)
+	require.False(t, user.IsEmailVerified)
 
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
@@ -51,4 +53,5 @@ func TestGetUser(t *testing.T) {
 	require.Equal(t, newUser.Email, user.Email)
 	require.Equal(t, newUser.CreatedAt, user.CreatedAt)
 	require.Equal(t, newUser.PasswordChangedAt, user.PasswordChangedAt)
+	require.Equal(t, newUser.Role, user.Role)
 }
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a webhook delivery.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// TaskDeliverWebhook is the type of the background tasks that post a delivery
+// to its webhook. The worker package runs them, but they are enqueued here so
+// that an event is only delivered if the change that raised it is committed.
+const TaskDeliverWebhook = "task:deliver_webhook"
+
+// webhookMaxAttempts is how many times a delivery is posted before it is
+// marked as failed. With the backoff of the task queue that spans a few hours.
+const webhookMaxAttempts = 12
+
+// PayloadDeliverWebhook is the payload of the tasks that deliver webhooks.
+type PayloadDeliverWebhook struct {
+	DeliveryID int64 `json:"deliveryID"`
+}
+
+// deliverToWebhooks records a delivery of the event for every webhook of the
+// owners that subscribes to it, and enqueues the tasks that post them.
+func deliverToWebhooks(ctx context.Context, q Querier, event Event, owners []string) error {
+	webhooks, err := q.ListEventWebhooks(ctx, ListEventWebhooksParams{
+		Owners:    owners,
+		EventType: event.Type,
+	})
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		delivery, err := q.CreateWebhookDelivery(ctx, CreateWebhookDeliveryParams{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			EventType: event.Type,
+			Payload:   payload,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := enqueueWebhookDelivery(ctx, q, delivery.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func enqueueWebhookDelivery(ctx context.Context, q Querier, deliveryID int64) error {
+	payload, err := json.Marshal(PayloadDeliverWebhook{DeliveryID: deliveryID})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateTask(ctx, CreateTaskParams{
+		Type:        TaskDeliverWebhook,
+		Payload:     payload,
+		MaxAttempts: webhookMaxAttempts,
+		RunAt:       time.Now(),
+	})
+	return err
+}
+
+// CreateAccountTx opens an account and emits the event that announces it.
+func (s *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return emitEvent(ctx, q, EventAccountCreated, []string{account.Owner}, account)
+	})
+
+	return account, err
+}
+
+// ReplayWebhookDeliveryTx sets a delivery back to pending and enqueues it
+// again, keeping the attempts made so far.
+func (s *SQLStore) ReplayWebhookDeliveryTx(ctx context.Context, deliveryID int64) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		delivery, err = q.UpdateWebhookDeliveryStatus(ctx, UpdateWebhookDeliveryStatusParams{
+			ID:     deliveryID,
+			Status: WebhookDeliveryPending,
+		})
+		if err != nil {
+			return err
+		}
+
+		return enqueueWebhookDelivery(ctx, q, delivery.ID)
+	})
+
+	return delivery, err
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebhook(t *testing.T, owner string, eventTypes ...string) Webhook {
+	arg := CreateWebhookParams{
+		Owner:      owner,
+		Url:        "https://example.com/hooks/" + util.RandomString(8),
+		Secret:     util.RandomString(32),
+		EventTypes: eventTypes,
+	}
+
+	webhook, err := testQueries.CreateWebhook(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, webhook.ID)
+	require.Equal(t, arg.Owner, webhook.Owner)
+	require.Equal(t, arg.Url, webhook.Url)
+	require.Equal(t, arg.Secret, webhook.Secret)
+	require.Equal(t, arg.EventTypes, webhook.EventTypes)
+	require.NotZero(t, webhook.CreatedAt)
+
+	return webhook
+}
+
+func TestListEventWebhooks(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	webhook1 := createRandomWebhook(t, user1.Username, EventTransferCreated)
+	webhook2 := createRandomWebhook(t, user2.Username, EventAccountCreated, EventTransferCreated)
+	createRandomWebhook(t, user2.Username, EventAccountStatusChanged)
+
+	webhooks, err := testQueries.ListEventWebhooks(context.Background(), ListEventWebhooksParams{
+		Owners:    []string{user1.Username, user2.Username},
+		EventType: EventTransferCreated,
+	})
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	require.Equal(t, webhook1.ID, webhooks[0].ID)
+	require.Equal(t, webhook2.ID, webhooks[1].ID)
+}
+
+func TestTransferTxEmitsEvent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithCurrency(t, 1000, account1.Currency)
+	webhook := createRandomWebhook(t, account2.Owner, EventTransferCreated)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	delivery := deliveries[0]
+	require.Equal(t, EventTransferCreated, delivery.EventType)
+	require.Equal(t, WebhookDeliveryPending, delivery.Status)
+
+	var event Event
+	require.NoError(t, json.Unmarshal(delivery.Payload, &event))
+	require.Equal(t, delivery.EventID, event.ID)
+	require.Equal(t, EventTransferCreated, event.Type)
+
+	var transfer Transfer
+	require.NoError(t, json.Unmarshal(event.Data, &transfer))
+	require.Equal(t, result.Transfer.ID, transfer.ID)
+
+	// failed deliveries can be replayed, keeping the attempts made so far
+	_, err = testQueries.CreateWebhookDeliveryAttempt(context.Background(), CreateWebhookDeliveryAttemptParams{
+		DeliveryID: delivery.ID,
+		Error:      "connection refused",
+	})
+	require.NoError(t, err)
+	_, err = testQueries.UpdateWebhookDeliveryStatus(context.Background(), UpdateWebhookDeliveryStatusParams{
+		ID:     delivery.ID,
+		Status: WebhookDeliveryFailed,
+	})
+	require.NoError(t, err)
+
+	replayed, err := store.ReplayWebhookDeliveryTx(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryPending, replayed.Status)
+
+	attempts, err := testQueries.ListWebhookDeliveryAttempts(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	require.Equal(t, "connection refused", attempts[0].Error)
+
+	// deleting the webhook drops its deliveries
+	require.NoError(t, testQueries.DeleteWebhook(context.Background(), webhook.ID))
+	_, err = testQueries.GetWebhookDelivery(context.Background(), delivery.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateAccountTxEmitsEvent(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	webhook := createRandomWebhook(t, user.Username, EventAccountCreated)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     5,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, EventAccountCreated, deliveries[0].EventType)
+	require.Contains(t, string(deliveries[0].Payload), account.Owner)
+}
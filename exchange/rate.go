@@ -0,0 +1,82 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/leoomi/simplebank/util"
+)
+
+var (
+	ErrRateNotFound    = errors.New("exchange rate not found")
+	ErrInvalidRate     = errors.New("exchange rate is invalid")
+	ErrAmountTooLow    = errors.New("amount is too low to be converted")
+	ErrAmountTooHigh   = errors.New("amount is too high to be converted")
+	ErrUnknownCurrency = errors.New("currency of exchange rate is unknown")
+)
+
+// Rate is how many units of the quote currency one unit of the base currency
+// buys, as of Timestamp. Value is kept as a decimal string so that it is stored
+// exactly as the provider published it.
+type Rate struct {
+	Base      string    `json:"base"`
+	Quote     string    `json:"quote"`
+	Value     string    `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RateProvider looks up the current rate between two currencies.
+type RateProvider interface {
+	GetRate(ctx context.Context, base, quote string) (Rate, error)
+}
+
+// Convert converts an amount of the base currency, in minor units, into minor
+// units of the quote currency. The rate is between major units, so the amount
+// is scaled by the difference between the minor units of the two currencies,
+// which currencies looks up. The result is rounded down so that a conversion
+// never credits more than the rate allows.
+func (r Rate) Convert(amount int64, currencies *util.CurrencyRegistry) (int64, error) {
+	value, ok := new(big.Rat).SetString(r.Value)
+	if !ok || value.Sign() <= 0 {
+		return 0, fmt.Errorf("%w: %s/%s %q", ErrInvalidRate, r.Base, r.Quote, r.Value)
+	}
+
+	base, ok := currencies.Lookup(r.Base)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, r.Base)
+	}
+	quote, ok := currencies.Lookup(r.Quote)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownCurrency, r.Quote)
+	}
+
+	exponent := int64(quote.MinorUnit - base.MinorUnit)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(abs(exponent)), nil)
+	if exponent >= 0 {
+		value.Mul(value, new(big.Rat).SetInt(scale))
+	} else {
+		value.Quo(value, new(big.Rat).SetInt(scale))
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), value)
+	result := new(big.Int).Quo(converted.Num(), converted.Denom())
+
+	if !result.IsInt64() {
+		return 0, ErrAmountTooHigh
+	}
+	if result.Sign() <= 0 {
+		return 0, ErrAmountTooLow
+	}
+
+	return result.Int64(), nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,64 @@
+package exchange
+
+import (
+	"math"
+	"testing"
+
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateConvert(t *testing.T) {
+	currencies := util.NewCurrencyRegistry(append([]util.Currency{
+		{Code: "JPY", MinorUnit: 0, Enabled: true},
+		{Code: "KWD", MinorUnit: 3, Enabled: true},
+	}, util.DefaultCurrencies...))
+
+	testCases := []struct {
+		name   string
+		base   string
+		quote  string
+		value  string
+		amount int64
+		result int64
+		err    error
+	}{
+		{name: "Identity", value: "1", amount: 1234, result: 1234},
+		{name: "RoundsDown", value: "0.92", amount: 1999, result: 1839},
+		{name: "ManyDecimals", value: "1.3456789", amount: 100, result: 134},
+		{name: "TooLow", value: "0.0001", amount: 10, err: ErrAmountTooLow},
+		{name: "TooHigh", value: "2", amount: math.MaxInt64, err: ErrAmountTooHigh},
+		{name: "Invalid", value: "abc", amount: 10, err: ErrInvalidRate},
+		{name: "Negative", value: "-1", amount: 10, err: ErrInvalidRate},
+		{name: "Zero", value: "0", amount: 10, err: ErrInvalidRate},
+		// 10.00 USD at 150.5 JPY each is 1505 yen, which have no minor units
+		{name: "ToFewerMinorUnits", base: "USD", quote: "JPY", value: "150.5", amount: 1000, result: 1505},
+		// 1505 JPY at 0.0066 USD each is 9.933 USD, rounded down to the cent
+		{name: "ToMoreMinorUnits", base: "JPY", quote: "USD", value: "0.0066", amount: 1505, result: 993},
+		// 1.500 KWD at 3.25 USD each is 4.875 USD, rounded down to the cent
+		{name: "FromThreeMinorUnits", base: "KWD", quote: "USD", value: "3.25", amount: 1500, result: 487},
+		{name: "ToThreeMinorUnits", base: "USD", quote: "KWD", value: "0.307", amount: 1000, result: 3070},
+		{name: "UnderOneCent", base: "JPY", quote: "USD", value: "0.0066", amount: 1, err: ErrAmountTooLow},
+		{name: "UnknownCurrency", base: "USD", quote: "XYZ", value: "1", amount: 10, err: ErrUnknownCurrency},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			rate := Rate{Base: "USD", Quote: "EUR", Value: tc.value}
+			if len(tc.base) > 0 {
+				rate.Base, rate.Quote = tc.base, tc.quote
+			}
+
+			result, err := rate.Convert(tc.amount, currencies)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.result, result)
+		})
+	}
+}
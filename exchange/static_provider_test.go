@@ -0,0 +1,46 @@
+package exchange
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	data := `[
+		{"base": "USD", "quote": "EUR", "value": "0.92", "timestamp": "2023-03-31T12:00:00Z"},
+		{"base": "EUR", "quote": "USD", "value": "1.087", "timestamp": "2023-03-31T12:00:00Z"}
+	]`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	provider, err := NewFileProvider(path)
+	require.NoError(t, err)
+
+	rate, err := provider.GetRate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "0.92", rate.Value)
+	require.Equal(t, time.Date(2023, time.March, 31, 12, 0, 0, 0, time.UTC), rate.Timestamp)
+
+	rate, err = provider.GetRate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "1.087", rate.Value)
+
+	_, err = provider.GetRate(context.Background(), "USD", "CAD")
+	require.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestFileProviderInvalidFile(t *testing.T) {
+	_, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	_, err = NewFileProvider(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package exchange
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+)
+
+// StoreProvider serves the rates kept in the rates table.
+type StoreProvider struct {
+	store db.Querier
+}
+
+func NewStoreProvider(store db.Querier) *StoreProvider {
+	return &StoreProvider{store: store}
+}
+
+func (p *StoreProvider) GetRate(ctx context.Context, base, quote string) (Rate, error) {
+	rate, err := p.store.GetRate(ctx, db.GetRateParams{
+		BaseCurrency:  base,
+		QuoteCurrency: quote,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Rate{}, fmt.Errorf("%w: %s/%s", ErrRateNotFound, base, quote)
+		}
+		return Rate{}, err
+	}
+
+	return Rate{
+		Base:      rate.BaseCurrency,
+		Quote:     rate.QuoteCurrency,
+		Value:     rate.Rate,
+		Timestamp: rate.UpdatedAt,
+	}, nil
+}
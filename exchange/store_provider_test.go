@@ -0,0 +1,44 @@
+package exchange
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	stored := db.Rate{
+		BaseCurrency:  "USD",
+		QuoteCurrency: "EUR",
+		Rate:          "0.92",
+		UpdatedAt:     time.Now().Truncate(time.Second),
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetRate(gomock.Any(), gomock.Eq(db.GetRateParams{BaseCurrency: "USD", QuoteCurrency: "EUR"})).
+		Times(1).
+		Return(stored, nil)
+	store.EXPECT().
+		GetRate(gomock.Any(), gomock.Eq(db.GetRateParams{BaseCurrency: "USD", QuoteCurrency: "CAD"})).
+		Times(1).
+		Return(db.Rate{}, sql.ErrNoRows)
+
+	provider := NewStoreProvider(store)
+
+	rate, err := provider.GetRate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, Rate{Base: "USD", Quote: "EUR", Value: "0.92", Timestamp: stored.UpdatedAt}, rate)
+
+	_, err = provider.GetRate(context.Background(), "USD", "CAD")
+	require.ErrorIs(t, err, ErrRateNotFound)
+}
@@ -0,0 +1,61 @@
+package funding
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeGateway settles every payment it is asked to, keeping them in memory, so
+// that deposits and withdrawals work without a real gateway.
+type FakeGateway struct {
+	mu          sync.Mutex
+	deposits    []Payment
+	withdrawals []Payment
+	// returned by Deposit and Withdraw when set
+	Err error
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{}
+}
+
+func (g *FakeGateway) Deposit(ctx context.Context, payment Payment) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Err != nil {
+		return "", g.Err
+	}
+
+	g.deposits = append(g.deposits, payment)
+	return fmt.Sprintf("fake_deposit_%d", len(g.deposits)), nil
+}
+
+func (g *FakeGateway) Withdraw(ctx context.Context, payment Payment) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Err != nil {
+		return "", g.Err
+	}
+
+	g.withdrawals = append(g.withdrawals, payment)
+	return fmt.Sprintf("fake_withdrawal_%d", len(g.withdrawals)), nil
+}
+
+// Deposits returns the deposits settled so far, oldest first.
+func (g *FakeGateway) Deposits() []Payment {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]Payment{}, g.deposits...)
+}
+
+// Withdrawals returns the withdrawals settled so far, oldest first.
+func (g *FakeGateway) Withdrawals() []Payment {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]Payment{}, g.withdrawals...)
+}
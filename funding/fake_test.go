@@ -0,0 +1,30 @@
+package funding
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeGateway(t *testing.T) {
+	gateway := NewFakeGateway()
+	payment := Payment{AccountID: 1, Amount: 100, Currency: "USD", Reference: "7"}
+
+	reference, err := gateway.Deposit(context.Background(), payment)
+	require.NoError(t, err)
+	require.Equal(t, "fake_deposit_1", reference)
+
+	reference, err = gateway.Withdraw(context.Background(), payment)
+	require.NoError(t, err)
+	require.Equal(t, "fake_withdrawal_1", reference)
+
+	require.Equal(t, []Payment{payment}, gateway.Deposits())
+	require.Equal(t, []Payment{payment}, gateway.Withdrawals())
+
+	// a declined payment is not recorded
+	gateway.Err = ErrDeclined
+	_, err = gateway.Deposit(context.Background(), payment)
+	require.ErrorIs(t, err, ErrDeclined)
+	require.Len(t, gateway.Deposits(), 1)
+}
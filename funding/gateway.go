@@ -0,0 +1,29 @@
+package funding
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrDeclined = errors.New("payment was declined by the funding gateway")
+
+// Payment is money that enters the bank for a deposit, or leaves it for a
+// withdrawal.
+type Payment struct {
+	AccountID int64  `json:"accountID"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	// what the bank calls the payment, so that the gateway can tell a retry
+	// from a new payment
+	Reference string `json:"reference"`
+}
+
+// Gateway moves money between the bank and the outside world, such as a card
+// processor or a payment rail. Both methods return what the gateway calls the
+// payment.
+type Gateway interface {
+	// Deposit collects a payment from outside the bank.
+	Deposit(ctx context.Context, payment Payment) (string, error)
+	// Withdraw pays a payment out of the bank.
+	Withdraw(ctx context.Context, payment Payment) (string, error)
+}
@@ -0,0 +1,43 @@
+package funding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+)
+
+// gatewayTimeout bounds how long the gateway is waited on for a payment.
+const gatewayTimeout = 30 * time.Second
+
+// Settle asks the gateway to move the money of a pending funding held in
+// currency, and returns what the gateway calls the payment. The gateway is
+// called with a context of its own that only times out, so that a client that
+// goes away doesn't cut a payment off halfway. A declined payment is reported
+// as db.ErrFundingDeclined; any other error leaves it unknown whether the
+// money moved.
+func Settle(gateway Gateway, pending db.Funding, currency string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+	defer cancel()
+
+	payment := Payment{
+		AccountID: pending.AccountID,
+		Amount:    pending.Amount,
+		Currency:  currency,
+		Reference: strconv.FormatInt(pending.ID, 10),
+	}
+
+	pay := gateway.Deposit
+	if pending.Kind == db.FundingWithdrawal {
+		pay = gateway.Withdraw
+	}
+
+	reference, err := pay(ctx, payment)
+	if errors.Is(err, ErrDeclined) {
+		return "", fmt.Errorf("%w: %w", db.ErrFundingDeclined, err)
+	}
+	return reference, err
+}
@@ -0,0 +1,31 @@
+package funding
+
+import (
+	"errors"
+	"testing"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettle(t *testing.T) {
+	gateway := NewFakeGateway()
+	withdrawal := db.Funding{ID: 7, AccountID: 1, Amount: 100, Kind: db.FundingWithdrawal}
+
+	reference, err := Settle(gateway, withdrawal, "USD")
+	require.NoError(t, err)
+	require.Equal(t, "fake_withdrawal_1", reference)
+	require.Equal(t, []Payment{{AccountID: 1, Amount: 100, Currency: "USD", Reference: "7"}}, gateway.Withdrawals())
+	require.Empty(t, gateway.Deposits())
+
+	// only a decline fails the funding
+	gateway.Err = ErrDeclined
+	_, err = Settle(gateway, withdrawal, "USD")
+	require.ErrorIs(t, err, db.ErrFundingDeclined)
+	require.ErrorIs(t, err, ErrDeclined)
+
+	gateway.Err = errors.New("connection reset")
+	_, err = Settle(gateway, withdrawal, "USD")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, db.ErrFundingDeclined)
+}
@@ -0,0 +1,102 @@
+package funding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+)
+
+const (
+	defaultSweepInterval = time.Minute
+	// how long a funding is left alone before it is settled again; longer than
+	// the gateway is waited on, so that a request still settling it is done
+	fundingLease = 5 * time.Minute
+)
+
+// Sweeper settles the fundings left pending, because the gateway gave no
+// answer or the funding couldn't be settled afterwards. It asks the gateway
+// again with the same reference, which the gateway tells apart from a new
+// payment, and settles the funding with what it answers.
+type Sweeper struct {
+	store         db.Store
+	gateway       Gateway
+	sweepInterval time.Duration
+}
+
+func NewSweeper(config util.Config, store db.Store, gateway Gateway) *Sweeper {
+	sweeper := &Sweeper{
+		store:         store,
+		gateway:       gateway,
+		sweepInterval: config.FundingSweepInterval,
+	}
+
+	if sweeper.sweepInterval <= 0 {
+		sweeper.sweepInterval = defaultSweepInterval
+	}
+
+	return sweeper
+}
+
+// Start settles pending fundings until ctx is done, looking for them every
+// sweep interval.
+func (s *Sweeper) Start(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := s.sweep(ctx, time.Now()); err != nil {
+			log.Printf("cannot sweep pending fundings: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.sweepInterval):
+		}
+	}
+}
+
+// sweep settles every funding that was pending for longer than the lease by
+// now. Fundings that can't be settled yet are claimed all the same, so they
+// are only tried again once the lease runs out.
+func (s *Sweeper) sweep(ctx context.Context, now time.Time) error {
+	staleBefore := now.Add(-fundingLease)
+
+	for ctx.Err() == nil {
+		pending, err := s.store.ClaimPendingFunding(ctx, staleBefore)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot claim pending funding: %w", err)
+		}
+
+		if err := s.settle(ctx, pending); err != nil {
+			log.Printf("cannot settle funding [%d]: %s", pending.ID, err)
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (s *Sweeper) settle(ctx context.Context, pending db.Funding) error {
+	account, err := s.store.GetAccount(ctx, pending.AccountID)
+	if err != nil {
+		return err
+	}
+
+	reference, err := Settle(s.gateway, pending, account.Currency)
+	declined := errors.Is(err, db.ErrFundingDeclined)
+	if err != nil && !declined {
+		return fmt.Errorf("gateway gave no answer: %w", err)
+	}
+
+	_, err = s.store.SettleFundingTx(ctx, db.SettleFundingTxParams{
+		FundingID: pending.ID,
+		Reference: reference,
+		Declined:  declined,
+	})
+	return err
+}
@@ -0,0 +1,125 @@
+package funding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweep(t *testing.T) {
+	now := time.Date(2024, time.March, 14, 9, 30, 0, 0, time.UTC)
+	account := db.Account{ID: 1, Currency: "USD", Kind: db.AccountKindCustomer}
+	pending := db.Funding{ID: 7, AccountID: account.ID, Amount: 100, Kind: db.FundingWithdrawal, Status: db.FundingPending}
+
+	// expectClaims stubs ClaimPendingFunding to hand out fundings, in order,
+	// and then to find no more
+	expectClaims := func(store *mockdb.MockStore, fundings ...db.Funding) {
+		calls := make([]*gomock.Call, 0, len(fundings)+1)
+		for _, funding := range fundings {
+			calls = append(calls, store.EXPECT().
+				ClaimPendingFunding(gomock.Any(), gomock.Eq(now.Add(-fundingLease))).
+				Times(1).
+				Return(funding, nil))
+		}
+		calls = append(calls, store.EXPECT().
+			ClaimPendingFunding(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.Funding{}, sql.ErrNoRows))
+		gomock.InOrder(calls...)
+	}
+
+	testCases := []struct {
+		name       string
+		gatewayErr error
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, err error, gateway *FakeGateway)
+	}{
+		{
+			name: "Settled",
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClaims(store, pending)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					SettleFundingTx(gomock.Any(), gomock.Eq(db.SettleFundingTxParams{
+						FundingID: pending.ID,
+						Reference: "fake_withdrawal_1",
+					})).
+					Times(1)
+			},
+			check: func(t *testing.T, err error, gateway *FakeGateway) {
+				require.NoError(t, err)
+				require.Equal(t, []Payment{{AccountID: 1, Amount: 100, Currency: "USD", Reference: "7"}}, gateway.Withdrawals())
+			},
+		},
+		{
+			name:       "Declined",
+			gatewayErr: ErrDeclined,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClaims(store, pending)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().
+					SettleFundingTx(gomock.Any(), gomock.Eq(db.SettleFundingTxParams{
+						FundingID: pending.ID,
+						Declined:  true,
+					})).
+					Times(1)
+			},
+			check: func(t *testing.T, err error, gateway *FakeGateway) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// the funding is left pending, and the next one is still settled
+			name:       "NoAnswer",
+			gatewayErr: context.DeadlineExceeded,
+			buildStubs: func(store *mockdb.MockStore) {
+				expectClaims(store, pending, pending)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+				store.EXPECT().SettleFundingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error, gateway *FakeGateway) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "DatabaseDown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ClaimPendingFunding(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Funding{}, sql.ErrConnDone)
+				store.EXPECT().SettleFundingTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error, gateway *FakeGateway) {
+				require.True(t, errors.Is(err, sql.ErrConnDone))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			gateway := NewFakeGateway()
+			gateway.Err = tc.gatewayErr
+
+			sweeper := NewSweeper(util.Config{}, store, gateway)
+			err := sweeper.sweep(context.Background(), now)
+			tc.check(t, err, gateway)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+// publicMethods may be called without a token.
+var publicMethods = map[string]bool{
+	pb.SimpleBank_CreateUser_FullMethodName: true,
+	pb.SimpleBank_LoginUser_FullMethodName:  true,
+}
+
+// authenticatedMethods may only be called with a valid access token. Methods
+// in neither list are refused, so that a new method is not served until it is
+// put in one of them.
+var authenticatedMethods = map[string]bool{
+	pb.SimpleBank_CreateAccount_FullMethodName:  true,
+	pb.SimpleBank_GetAccount_FullMethodName:     true,
+	pb.SimpleBank_ListAccounts_FullMethodName:   true,
+	pb.SimpleBank_CreateTransfer_FullMethodName: true,
+}
+
+type payloadContextKey struct{}
+
+// authInterceptor authenticates gRPC calls to the methods that need it, like
+// the HTTP authMiddleware does, and hands the payload of the token to the
+// handler through the context.
+func (s *Server) authInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	switch {
+	case publicMethods[info.FullMethod]:
+		return handler(ctx, req)
+	case authenticatedMethods[info.FullMethod]:
+		payload, err := s.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, payloadContextKey{}, payload), req)
+	default:
+		return nil, status.Errorf(codes.PermissionDenied, "method %s is not allowed", info.FullMethod)
+	}
+}
+
+// authPayload returns the payload of the token the call was authenticated
+// with. Calls served through the in-process gateway don't go through
+// interceptors, so those are authenticated here instead.
+func (s *Server) authPayload(ctx context.Context) (*token.Payload, error) {
+	if payload, ok := ctx.Value(payloadContextKey{}).(*token.Payload); ok {
+		return payload, nil
+	}
+
+	return s.authenticate(ctx)
+}
+
+// authenticate verifies the bearer token a call was made with and checks it
+// hasn't been revoked.
+func (s *Server) authenticate(ctx context.Context) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+
+	values := md.Get(authorizationHeaderKey)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization header not provided")
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization header provided")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization type provided %s", authorizationType)
+	}
+
+	payload, err := s.tokenMaker.VerifyToken(fields[1], token.TokenTypeAccess)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	revoked, err := s.revocations.IsRevoked(ctx, payload)
+	if err != nil {
+		return nil, internalError("cannot check token revocation", err)
+	}
+	if revoked {
+		return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+	}
+
+	return payload, nil
+}
+
+// requireVerifiedEmail makes sure the user has verified their email address
+// before they may open accounts or move money.
+func (s *Server) requireVerifiedEmail(ctx context.Context, username string) error {
+	user, err := s.store.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.Error(codes.NotFound, "user not found")
+		}
+		return internalError("failed to get user", err)
+	}
+
+	if !user.IsEmailVerified {
+		return status.Error(codes.FailedPrecondition, "email address must be verified first")
+	}
+
+	return nil
+}
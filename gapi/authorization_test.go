@@ -0,0 +1,87 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMethodsHaveAccess(t *testing.T) {
+	methods := map[string]bool{}
+	for _, method := range pb.SimpleBank_ServiceDesc.Methods {
+		fullMethod := fmt.Sprintf("/%s/%s", pb.SimpleBank_ServiceDesc.ServiceName, method.MethodName)
+		methods[fullMethod] = true
+
+		require.True(t, publicMethods[fullMethod] != authenticatedMethods[fullMethod],
+			"%s must be either public or authenticated", fullMethod)
+	}
+
+	for fullMethod := range publicMethods {
+		require.True(t, methods[fullMethod], "%s is not a method of the service", fullMethod)
+	}
+	for fullMethod := range authenticatedMethods {
+		require.True(t, methods[fullMethod], "%s is not a method of the service", fullMethod)
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	authenticated := metadata.NewIncomingContext(context.Background(), metadata.MD{
+		authorizationHeaderKey: []string{fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken)},
+	})
+
+	testCases := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		code   codes.Code
+	}{
+		{name: "Public", ctx: context.Background(), method: pb.SimpleBank_LoginUser_FullMethodName, code: codes.OK},
+		{name: "Authenticated", ctx: authenticated, method: pb.SimpleBank_GetAccount_FullMethodName, code: codes.OK},
+		{name: "NoAuthorization", ctx: context.Background(), method: pb.SimpleBank_GetAccount_FullMethodName, code: codes.Unauthenticated},
+		{name: "UnknownMethod", ctx: authenticated, method: "/pb.SimpleBank/DropAccounts", code: codes.PermissionDenied},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			handled := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				handled = true
+				if authenticatedMethods[tc.method] {
+					// the handler gets the payload the interceptor verified
+					payload, ok := ctx.Value(payloadContextKey{}).(*token.Payload)
+					require.True(t, ok)
+					require.Equal(t, user.Username, payload.Username)
+				}
+				return nil, nil
+			}
+
+			_, err := server.authInterceptor(tc.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+			require.Equal(t, tc.code, status.Code(err))
+			require.Equal(t, tc.code == codes.OK, handled)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package gapi
+
+import (
+	"strconv"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
+		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
+		CreatedAt:         timestamppb.New(user.CreatedAt),
+	}
+}
+
+func (s *Server) convertAccount(account db.Account) *pb.Account {
+	return &pb.Account{
+		Id:                    account.ID,
+		Owner:                 account.Owner,
+		Balance:               account.Balance,
+		Currency:              account.Currency,
+		OverdraftLimit:        account.OverdraftLimit,
+		CreatedAt:             timestamppb.New(account.CreatedAt),
+		BalanceDecimal:        s.formatAmount(account.Balance, account.Currency),
+		OverdraftLimitDecimal: s.formatAmount(account.OverdraftLimit, account.Currency),
+		Status:                account.Status,
+	}
+}
+
+func (s *Server) convertTransfer(transfer db.Transfer, fromCurrency, toCurrency string) *pb.Transfer {
+	return &pb.Transfer{
+		Id:              transfer.ID,
+		FromAccountId:   transfer.FromAccountID,
+		ToAccountId:     transfer.ToAccountID,
+		Amount:          transfer.Amount,
+		ToAmount:        transfer.ToAmount,
+		ExchangeRate:    transfer.ExchangeRate,
+		RateTimestamp:   timestamppb.New(transfer.RateTimestamp),
+		CreatedAt:       timestamppb.New(transfer.CreatedAt),
+		AmountDecimal:   s.formatAmount(transfer.Amount, fromCurrency),
+		ToAmountDecimal: s.formatAmount(transfer.ToAmount, toCurrency),
+	}
+}
+
+func (s *Server) convertEntry(entry db.Entry, currency string) *pb.Entry {
+	return &pb.Entry{
+		Id:            entry.ID,
+		AccountId:     entry.AccountID,
+		Amount:        entry.Amount,
+		CreatedAt:     timestamppb.New(entry.CreatedAt),
+		AmountDecimal: s.formatAmount(entry.Amount, currency),
+	}
+}
+
+// formatAmount renders an amount in minor units as a decimal string in the
+// given currency. Currencies missing from the registry are rendered as is.
+func (s *Server) formatAmount(amount int64, code string) string {
+	currency, ok := s.currencies.Lookup(code)
+	if !ok {
+		return strconv.FormatInt(amount, 10)
+	}
+
+	return currency.FormatAmount(amount)
+}
@@ -0,0 +1,39 @@
+package gapi
+
+import (
+	"errors"
+	"log"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func fieldViolation(field string, err error) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: err.Error(),
+	}
+}
+
+// invalidArgumentError reports every invalid field of a request at once.
+func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
+	badRequest := &errdetails.BadRequest{FieldViolations: violations}
+	statusInvalid := status.New(codes.InvalidArgument, "invalid parameters")
+
+	statusDetails, err := statusInvalid.WithDetails(badRequest)
+	if err != nil {
+		return statusInvalid.Err()
+	}
+
+	return statusDetails.Err()
+}
+
+var errMissingField = errors.New("must be provided")
+
+// internalError logs err and reports that the call failed without saying why,
+// so that database errors and the like never reach the client.
+func internalError(msg string, err error) error {
+	log.Printf("%s: %s", msg, err)
+	return status.Error(codes.Internal, msg)
+}
@@ -0,0 +1,45 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/leoomi/simplebank/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NewGatewayHandler serves the SimpleBank service as JSON over HTTP, with the
+// routes declared in proto/gateway.yaml. Requests are handed to the server
+// in-process, so the gateway shares the validation, authorization and error
+// codes of the gRPC API. Its JSON is that of the gRPC API too, with snake_case
+// fields and gRPC statuses as errors, rather than that of the REST API.
+func (s *Server) NewGatewayHandler(ctx context.Context) (http.Handler, error) {
+	jsonOption := runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			UseProtoNames:   true,
+			EmitUnpopulated: true,
+		},
+		UnmarshalOptions: protojson.UnmarshalOptions{
+			DiscardUnknown: true,
+		},
+	})
+
+	grpcMux := runtime.NewServeMux(jsonOption, runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher))
+	if err := pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, s); err != nil {
+		return nil, err
+	}
+
+	return grpcMux, nil
+}
+
+// incomingHeaderMatcher forwards the Idempotency-Key header on top of the
+// headers the gateway forwards by default.
+func incomingHeaderMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == "Idempotency-Key" {
+		return idempotencyKeyHeader, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
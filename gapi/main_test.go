@@ -0,0 +1,106 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/leoomi/simplebank/auth"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		IdempotencyKeyTTL:    time.Hour,
+		RevocationCacheTTL:   time.Minute,
+		VerifyEmailDuration:  15 * time.Minute,
+	}
+
+	// authenticated calls consult the revocation list and the server loads its
+	// currencies on start; tests that care about either register their own
+	// expectation before creating the server
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().
+			IsTokenRevoked(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			Return(false, nil)
+		mockStore.EXPECT().
+			ListCurrencies(gomock.Any()).
+			AnyTimes().
+			Return([]db.Currency{}, nil)
+	}
+
+	server, err := NewServer(config, store, auth.NewRevocationList(store, config.RevocationCacheTTL))
+	require.NoError(t, err)
+
+	return server
+}
+
+// newTestClient serves the server over an in-memory connection, so that calls
+// go through the interceptors like they would in production.
+func newTestClient(t *testing.T, server *Server) pb.SimpleBankClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := server.NewGRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewSimpleBankClient(conn)
+}
+
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, role string, duration time.Duration) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, role, token.TokenTypeAccess, duration)
+	require.NoError(t, err)
+
+	bearerToken := fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken)
+	md := metadata.MD{
+		authorizationHeaderKey: []string{bearerToken},
+	}
+
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Currency: util.RandomCurrency(),
+		Balance:  util.RandomMoney(),
+		Status:   util.AccountStatusActive,
+		Kind:     db.AccountKindCustomer,
+	}
+}
+
+// stubVerifiedUsers makes every user look up return a user whose email address
+// is verified, for tests of calls that only serve such users.
+func stubVerifiedUsers(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, IsEmailVerified: true}, nil
+		})
+}
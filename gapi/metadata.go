@@ -0,0 +1,43 @@
+package gapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	grpcGatewayUserAgentHeader = "grpcgateway-user-agent"
+	userAgentHeader            = "user-agent"
+	xForwardedForHeader        = "x-forwarded-for"
+)
+
+type clientMetadata struct {
+	UserAgent string
+	ClientIP  string
+}
+
+// extractMetadata finds out who is calling, whether the call came in over
+// gRPC or through the gateway.
+func extractMetadata(ctx context.Context) clientMetadata {
+	mtdt := clientMetadata{}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgents := md.Get(grpcGatewayUserAgentHeader); len(userAgents) > 0 {
+			mtdt.UserAgent = userAgents[0]
+		} else if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 {
+			mtdt.UserAgent = userAgents[0]
+		}
+
+		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 {
+			mtdt.ClientIP = clientIPs[0]
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && len(mtdt.ClientIP) == 0 {
+		mtdt.ClientIP = p.Addr.String()
+	}
+
+	return mtdt
+}
@@ -0,0 +1,54 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/lib/pq"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	payload, err := s.authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := s.validateCreateAccountRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	if err := s.requireVerifiedEmail(ctx, payload.Username); err != nil {
+		return nil, err
+	}
+
+	arg := db.CreateAccountParams{
+		Owner:    payload.Username,
+		Currency: req.GetCurrency(),
+		Balance:  0,
+	}
+
+	account, err := s.store.CreateAccountTx(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation", "unique_violation":
+				return nil, status.Errorf(codes.PermissionDenied, "cannot create account: %s", err)
+			}
+		}
+		return nil, internalError("failed to create account", err)
+	}
+
+	return &pb.CreateAccountResponse{Account: s.convertAccount(account)}, nil
+}
+
+func (s *Server) validateCreateAccountRequest(req *pb.CreateAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateCurrency(s.currencies, req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,101 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/transfers"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const idempotencyKeyHeader = "idempotency-key"
+
+func (s *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	payload, err := s.authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := s.validateCreateTransferRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	if err := s.requireVerifiedEmail(ctx, payload.Username); err != nil {
+		return nil, err
+	}
+
+	result, err := s.transfers.Create(ctx, payload, transfers.CreateParams{
+		FromAccountID:  req.GetFromAccountId(),
+		ToAccountID:    req.GetToAccountId(),
+		Amount:         req.GetAmount(),
+		Currency:       req.GetCurrency(),
+		IdempotencyKey: idempotencyKeyFromContext(ctx),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, transfers.ErrAccountNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, transfers.ErrAccountCurrencyMismatch), errors.Is(err, transfers.ErrIdempotencyKeyTooLong):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, auth.ErrNotAuthorized), errors.Is(err, auth.ErrUnknownRole):
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case errors.Is(err, transfers.ErrIdempotencyKeyReused),
+			errors.Is(err, exchange.ErrRateNotFound), errors.Is(err, exchange.ErrAmountTooLow), errors.Is(err, exchange.ErrAmountTooHigh),
+			errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrCurrencyMismatch), errors.Is(err, db.ErrAccountNotActive), errors.Is(err, db.ErrSystemAccount),
+			errors.Is(err, db.ErrTransferLimitExceeded), errors.Is(err, db.ErrDailyLimitExceeded), errors.Is(err, db.ErrMonthlyLimitExceeded):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, internalError("failed to transfer", err)
+	}
+
+	return s.convertTransferTxResult(result), nil
+}
+
+func (s *Server) validateCreateTransferRequest(req *pb.CreateTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateID(req.GetFromAccountId()); err != nil {
+		violations = append(violations, fieldViolation("from_account_id", err))
+	}
+	if err := validateID(req.GetToAccountId()); err != nil {
+		violations = append(violations, fieldViolation("to_account_id", err))
+	}
+	if req.GetAmount() <= 0 {
+		violations = append(violations, fieldViolation("amount", fmt.Errorf("must be greater than 0")))
+	}
+	if err := validateCurrency(s.currencies, req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+
+	return violations
+}
+
+func (s *Server) convertTransferTxResult(result db.TransferTxResult) *pb.CreateTransferResponse {
+	fromCurrency, toCurrency := result.FromAccount.Currency, result.ToAccount.Currency
+
+	return &pb.CreateTransferResponse{
+		Transfer:    s.convertTransfer(result.Transfer, fromCurrency, toCurrency),
+		FromAccount: s.convertAccount(result.FromAccount),
+		ToAccount:   s.convertAccount(result.ToAccount),
+		FromEntry:   s.convertEntry(result.FromEntry, fromCurrency),
+		ToEntry:     s.convertEntry(result.ToEntry, toCurrency),
+	}
+}
+
+func idempotencyKeyFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if keys := md.Get(idempotencyKeyHeader); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
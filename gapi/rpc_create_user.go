@@ -0,0 +1,76 @@
+package gapi
+
+import (
+	"context"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
+	"github.com/lib/pq"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if violations := validateCreateUserRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	hashedPassword, err := util.HashedPassword(req.GetPassword())
+	if err != nil {
+		return nil, internalError("failed to hash password", err)
+	}
+
+	secretCode, err := util.NewSecretCode()
+	if err != nil {
+		return nil, internalError("failed to create verification code", err)
+	}
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username: req.GetUsername(),
+			Password: hashedPassword,
+			FullName: req.GetFullName(),
+			Email:    req.GetEmail(),
+		},
+		SecretCode: secretCode,
+		ExpiresAt:  time.Now().Add(s.config.VerifyEmailDuration),
+		AfterCreate: func(q db.Querier, user db.User, verifyEmail db.VerifyEmail) error {
+			return s.distributor.DistributeTaskSendVerifyEmail(ctx, q, &worker.PayloadSendVerifyEmail{
+				Username:   user.Username,
+				EmailID:    verifyEmail.ID,
+				SecretCode: verifyEmail.SecretCode,
+			})
+		},
+	}
+
+	result, err := s.store.CreateUserTx(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return nil, status.Errorf(codes.AlreadyExists, "username already exists: %s", err)
+		}
+		return nil, internalError("failed to create user", err)
+	}
+
+	return &pb.CreateUserResponse{User: convertUser(result.User)}, nil
+}
+
+func validateCreateUserRequest(req *pb.CreateUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		violations = append(violations, fieldViolation("username", err))
+	}
+	if err := validatePassword(req.GetPassword()); err != nil {
+		violations = append(violations, fieldViolation("password", err))
+	}
+	if err := validateFullName(req.GetFullName()); err != nil {
+		violations = append(violations, fieldViolation("full_name", err))
+	}
+	if err := validateEmail(req.GetEmail()); err != nil {
+		violations = append(violations, fieldViolation("email", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,59 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/token"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	payload, err := s.authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateID(req.GetId()); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("id", err)})
+	}
+
+	account, err := s.getAuthorizedAccount(ctx, payload, req.GetId(), auth.ActionReadAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetAccountResponse{Account: s.convertAccount(account)}, nil
+}
+
+// getAuthorizedAccount loads an account and makes sure the token holder may
+// perform act on it.
+func (s *Server) getAuthorizedAccount(ctx context.Context, payload *token.Payload, accountID int64, act auth.Action) (db.Account, error) {
+	account, err := s.loadAccount(ctx, accountID)
+	if err != nil {
+		return account, err
+	}
+
+	if err := auth.Authorize(payload, account.Owner, act); err != nil {
+		return account, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	return account, nil
+}
+
+func (s *Server) loadAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return account, status.Errorf(codes.NotFound, "account %d not found", accountID)
+		}
+		return account, internalError("failed to get account", err)
+	}
+
+	return account, nil
+}
@@ -0,0 +1,64 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	payload, err := s.authPayload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if violations := validateListAccountsRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	owner := payload.Username
+	if len(req.GetOwner()) > 0 {
+		owner = req.GetOwner()
+	}
+	if err := auth.Authorize(payload, owner, auth.ActionReadAccount); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	arg := db.ListAccountsParams{
+		Owner:  owner,
+		Limit:  req.GetPageSize(),
+		Offset: req.GetPageSize() * (req.GetPageId() - 1),
+	}
+	accounts, err := s.store.ListAccounts(ctx, arg)
+	if err != nil {
+		return nil, internalError("failed to list accounts", err)
+	}
+
+	res := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, len(accounts))}
+	for i, account := range accounts {
+		res.Accounts[i] = s.convertAccount(account)
+	}
+
+	return res, nil
+}
+
+func validateListAccountsRequest(req *pb.ListAccountsRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if len(req.GetOwner()) > 0 {
+		if err := validateUsername(req.GetOwner()); err != nil {
+			violations = append(violations, fieldViolation("owner", err))
+		}
+	}
+	if err := validateID(int64(req.GetPageId())); err != nil {
+		violations = append(violations, fieldViolation("page_id", err))
+	}
+	if err := validateRange(req.GetPageSize(), 5, 10); err != nil {
+		violations = append(violations, fieldViolation("page_size", err))
+	}
+
+	return violations
+}
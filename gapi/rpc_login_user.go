@@ -0,0 +1,84 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if violations := validateLoginUserRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	// the owner of the system accounts is never logged in as, whatever its
+	// password is set to
+	if req.GetUsername() == db.SystemOwner {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	user, err := s.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, internalError("failed to find user", err)
+	}
+
+	if err := util.CheckPassword(user.Password, req.GetPassword()); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "incorrect password")
+	}
+
+	accessToken, accessPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccess, s.config.AccessTokenDuration)
+	if err != nil {
+		return nil, internalError("failed to create access token", err)
+	}
+
+	refreshToken, refreshPayload, err := s.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeRefresh, s.config.RefreshTokenDuration)
+	if err != nil {
+		return nil, internalError("failed to create refresh token", err)
+	}
+
+	mtdt := extractMetadata(ctx)
+	session, err := s.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    mtdt.UserAgent,
+		ClientIp:     mtdt.ClientIP,
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		return nil, internalError("failed to create session", err)
+	}
+
+	res := &pb.LoginUserResponse{
+		User:                  convertUser(user),
+		SessionId:             session.ID.String(),
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		AccessTokenExpiresAt:  timestamppb.New(accessPayload.ExpiredAt),
+		RefreshTokenExpiresAt: timestamppb.New(refreshPayload.ExpiredAt),
+	}
+	return res, nil
+}
+
+func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		violations = append(violations, fieldViolation("username", err))
+	}
+	if err := validatePassword(req.GetPassword()); err != nil {
+		violations = append(violations, fieldViolation("password", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,74 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/pb"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/transfers"
+	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server serves gRPC requests for our banking service.
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	config      util.Config
+	store       db.Store
+	tokenMaker  token.Maker
+	revocations *auth.RevocationList
+	currencies  *util.CurrencyRegistry
+	transfers   *transfers.Service
+	distributor worker.TaskDistributor
+}
+
+// NewServer creates a server that checks tokens against revocations, which is
+// shared with the other servers so that a token revoked through one of them is
+// turned away by all of them right away.
+func NewServer(config util.Config, store db.Store, revocations *auth.RevocationList) (*Server, error) {
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+	server := &Server{
+		config:      config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		revocations: revocations,
+		distributor: worker.NewPGTaskDistributor(),
+	}
+	var rates exchange.RateProvider = exchange.NewStoreProvider(store)
+	if len(config.ExchangeRatesFile) > 0 {
+		rates, err = exchange.NewFileProvider(config.ExchangeRatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load exchange rates: %w", err)
+		}
+	}
+
+	if len(config.CurrenciesFile) > 0 {
+		server.currencies, err = util.LoadCurrencyRegistry(config.CurrenciesFile)
+	} else {
+		server.currencies, err = db.LoadCurrencyRegistry(context.Background(), store)
+	}
+	if err != nil {
+		return nil, err
+	}
+	server.transfers = transfers.NewService(config, store, rates, server.currencies, server.distributor)
+
+	return server, nil
+}
+
+// NewGRPCServer creates a grpc.Server with the banking service registered.
+func (s *Server) NewGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.authInterceptor))
+	pb.RegisterSimpleBankServer(grpcServer, s)
+	reflection.Register(grpcServer)
+
+	return grpcServer
+}
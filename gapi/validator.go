@@ -0,0 +1,72 @@
+package gapi
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+
+	"github.com/leoomi/simplebank/util"
+)
+
+// The validators below enforce the same rules as the binding tags of the HTTP
+// request structs in the api package.
+
+var isAlphanumeric = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
+
+func validateUsername(value string) error {
+	if len(value) == 0 {
+		return errMissingField
+	}
+	if !isAlphanumeric(value) {
+		return fmt.Errorf("must contain only letters or digits")
+	}
+	return nil
+}
+
+func validatePassword(value string) error {
+	if len(value) < 6 {
+		return fmt.Errorf("must contain at least 6 characters")
+	}
+	return nil
+}
+
+func validateFullName(value string) error {
+	if len(value) == 0 {
+		return errMissingField
+	}
+	return nil
+}
+
+func validateEmail(value string) error {
+	if len(value) == 0 {
+		return errMissingField
+	}
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("is not a valid email address")
+	}
+	return nil
+}
+
+func validateID(value int64) error {
+	if value < 1 {
+		return fmt.Errorf("must be a positive integer")
+	}
+	return nil
+}
+
+func validateCurrency(registry *util.CurrencyRegistry, value string) error {
+	if len(value) == 0 {
+		return errMissingField
+	}
+	if !registry.IsSupported(value) {
+		return fmt.Errorf("unsupported currency %s", value)
+	}
+	return nil
+}
+
+func validateRange(value, min, max int32) error {
+	if value < min || value > max {
+		return fmt.Errorf("must be between %d and %d", min, max)
+	}
+	return nil
+}
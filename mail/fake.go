@@ -0,0 +1,43 @@
+package mail
+
+import "sync"
+
+// Email is a message recorded by FakeMailer.
+type Email struct {
+	Subject string
+	Content string
+	To      []string
+}
+
+// FakeMailer keeps the emails it is asked to send in memory, so that tests can
+// look at them.
+type FakeMailer struct {
+	mu     sync.Mutex
+	emails []Email
+	// returned by SendEmail when set
+	Err error
+}
+
+func NewFakeMailer() *FakeMailer {
+	return &FakeMailer{}
+}
+
+func (m *FakeMailer) SendEmail(subject string, content string, to []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+
+	m.emails = append(m.emails, Email{Subject: subject, Content: content, To: to})
+	return nil
+}
+
+// Sent returns the emails sent so far, oldest first.
+func (m *FakeMailer) Sent() []Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Email{}, m.emails...)
+}
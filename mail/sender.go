@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer sends HTML emails.
+type Mailer interface {
+	SendEmail(subject string, content string, to []string) error
+}
+
+// SMTPMailer sends emails through an SMTP server, authenticating only when a
+// password is configured.
+type SMTPMailer struct {
+	address  string
+	from     mail.Address
+	password string
+}
+
+func NewSMTPMailer(address, fromName, fromAddress, password string) *SMTPMailer {
+	return &SMTPMailer{
+		address:  address,
+		from:     mail.Address{Name: fromName, Address: fromAddress},
+		password: password,
+	}
+}
+
+func (m *SMTPMailer) SendEmail(subject string, content string, to []string) error {
+	var auth smtp.Auth
+	if len(m.password) > 0 {
+		host, _, err := net.SplitHostPort(m.address)
+		if err != nil {
+			return fmt.Errorf("invalid smtp address: %w", err)
+		}
+		auth = smtp.PlainAuth("", m.from.Address, m.password, host)
+	}
+
+	msg := buildMessage(m.from, subject, content, to)
+	if err := smtp.SendMail(m.address, auth, m.from.Address, to, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+func buildMessage(from mail.Address, subject string, content string, to []string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(content)
+
+	return msg.Bytes()
+}
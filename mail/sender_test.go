@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMessage(t *testing.T) {
+	from := mail.Address{Name: "Simple Bank", Address: "no-reply@simplebank.local"}
+	msg := string(buildMessage(from, "Olá", "<p>hello</p>", []string{"a@example.com", "b@example.com"}))
+
+	header, body, ok := strings.Cut(msg, "\r\n\r\n")
+	require.True(t, ok)
+	require.Equal(t, "<p>hello</p>", body)
+	require.Contains(t, header, `From: "Simple Bank" <no-reply@simplebank.local>`)
+	require.Contains(t, header, "To: a@example.com, b@example.com")
+	require.Contains(t, header, "Subject: =?utf-8?q?Ol=C3=A1?=")
+	require.Contains(t, header, `Content-Type: text/html; charset="utf-8"`)
+}
+
+func TestSendVerifyEmail(t *testing.T) {
+	mailer := NewFakeMailer()
+
+	link := VerifyEmailLink("http://localhost:8080/users/verify_email", 42, "s3cret")
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, "42", parsed.Query().Get("email_id"))
+	require.Equal(t, "s3cret", parsed.Query().Get("secret_code"))
+
+	err = SendVerifyEmail(mailer, "<Alice>", "alice@example.com", link)
+	require.NoError(t, err)
+
+	emails := mailer.Sent()
+	require.Len(t, emails, 1)
+	require.Equal(t, []string{"alice@example.com"}, emails[0].To)
+	require.Contains(t, emails[0].Content, "&lt;Alice&gt;")
+	require.Contains(t, emails[0].Content, "email_id=42&amp;secret_code=s3cret")
+}
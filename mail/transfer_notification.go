@@ -0,0 +1,17 @@
+package mail
+
+import (
+	"fmt"
+	"html"
+)
+
+// SendTransferNotification tells the owner of an account that amount was
+// transferred to it.
+func SendTransferNotification(mailer Mailer, fullName, email, amount string, accountID int64) error {
+	subject := "You received a transfer"
+	content := fmt.Sprintf(`Hello %s,<br/>
+%s was transferred to your account #%d.<br/>
+`, html.EscapeString(fullName), html.EscapeString(amount), accountID)
+
+	return mailer.SendEmail(subject, content, []string{email})
+}
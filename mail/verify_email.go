@@ -0,0 +1,29 @@
+package mail
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+)
+
+// VerifyEmailLink is the link a new user follows to prove they own their email
+// address.
+func VerifyEmailLink(baseURL string, emailID int64, secretCode string) string {
+	query := url.Values{}
+	query.Set("email_id", strconv.FormatInt(emailID, 10))
+	query.Set("secret_code", secretCode)
+
+	return baseURL + "?" + query.Encode()
+}
+
+// SendVerifyEmail welcomes a new user and asks them to follow link.
+func SendVerifyEmail(mailer Mailer, fullName, email, link string) error {
+	subject := "Welcome to Simple Bank"
+	content := fmt.Sprintf(`Hello %s,<br/>
+Thank you for registering with us!<br/>
+Please <a href="%s">click here</a> to verify your email address.<br/>
+`, html.EscapeString(fullName), html.EscapeString(link))
+
+	return mailer.SendEmail(subject, content, []string{email})
+}
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"log"
+	"net"
+	"net/http"
+	"os"
 
 	"github.com/leoomi/simplebank/api"
+	"github.com/leoomi/simplebank/auth"
 	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/funding"
+	"github.com/leoomi/simplebank/gapi"
+	"github.com/leoomi/simplebank/outbox"
+	"github.com/leoomi/simplebank/reconcile"
+	"github.com/leoomi/simplebank/transfers"
 	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
 	_ "github.com/lib/pq"
 )
 
@@ -22,12 +34,178 @@ func main() {
 	}
 
 	store := db.NewStore(conn)
-	server, err := api.NewServer(config, store)
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcile(store, os.Args[2:])
+		return
+	}
+
+	openSystemAccounts(store)
+
+	// both servers share the revocation list, so that a logout through one is
+	// seen by the other without waiting for the cache to expire
+	revocations := auth.NewRevocationList(store, config.RevocationCacheTTL)
+
+	grpcServer, err := gapi.NewServer(config, store, revocations)
+	if err != nil {
+		log.Fatal("cannot create gRPC server:", err)
+	}
+
+	go runTaskProcessor(config, store)
+	go runOutboxRelay(config, store)
+	go runScheduler(config, store)
+	go runSnapshotter(config, store)
+	go runFundingSweeper(config, store)
+	go runGrpcServer(config, grpcServer)
+	runHTTPServer(config, store, revocations, grpcServer)
+}
+
+// openSystemAccounts opens the system accounts of currencies added since the
+// migrations opened them, before anything loads the currencies and moves money
+// in them.
+func openSystemAccounts(store db.Store) {
+	opened, err := store.CreateSystemAccounts(context.Background(), db.SystemOwner)
+	if err != nil {
+		log.Fatal("cannot open system accounts:", err)
+	}
+	if opened > 0 {
+		log.Printf("opened %d system accounts", opened)
+	}
+}
+
+// runTaskProcessor runs the tasks the servers enqueue, such as sending emails,
+// in the background.
+func runTaskProcessor(config util.Config, store db.Store) {
+	processor, err := worker.NewTaskProcessor(config, store)
+	if err != nil {
+		log.Fatal("cannot create task processor:", err)
+	}
+
+	log.Printf("start task processor")
+	processor.Start(context.Background())
+}
+
+// runOutboxRelay publishes the domain events the store writes to the outbox,
+// appending them to OUTBOX_FILE, or to the log when it isn't set.
+func runOutboxRelay(config util.Config, store db.Store) {
+	w := log.Writer()
+	if len(config.OutboxFile) > 0 {
+		file, err := os.OpenFile(config.OutboxFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatal("cannot open outbox file:", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	log.Printf("start outbox relay")
+	outbox.NewRelay(config, store, outbox.NewLogPublisher(w)).Start(context.Background())
+}
+
+// runScheduler makes the scheduled and recurring transfers once they are due.
+func runScheduler(config util.Config, store db.Store) {
+	scheduler, err := transfers.NewScheduler(config, store)
+	if err != nil {
+		log.Fatal("cannot create transfer scheduler:", err)
+	}
+
+	log.Printf("start transfer scheduler")
+	scheduler.Start(context.Background())
+}
+
+// runReconcile checks that the balances and transfers agree with the entries
+// of the ledger and writes a report of what doesn't, exiting with status 1 when
+// anything was found.
+func runReconcile(store db.Store, args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	format := flags.String("format", reconcile.FormatJSON, "format of the report, json or csv")
+	output := flags.String("output", "", "file to write the report to instead of stdout")
+	incremental := flags.Bool("incremental", false, "only add up the entries written since the checkpoint of each account")
+	checkpoint := flags.Bool("checkpoint", false, "record the balances that add up as checkpoints for incremental runs")
+	flags.Parse(args)
+
+	if *format != reconcile.FormatJSON && *format != reconcile.FormatCSV {
+		log.Fatalf("unknown report format %q", *format)
+	}
+
+	report, err := reconcile.NewReconciler(store).Run(context.Background(), reconcile.Options{
+		Incremental: *incremental,
+		Checkpoint:  *checkpoint,
+	})
+	if err != nil {
+		log.Fatal("cannot reconcile ledger:", err)
+	}
+
+	w := os.Stdout
+	if len(*output) > 0 {
+		w, err = os.Create(*output)
+		if err != nil {
+			log.Fatal("cannot create report file:", err)
+		}
+	}
+
+	err = report.Write(w, *format)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Fatal("cannot write report:", err)
+	}
+
+	if len(report.Discrepancies) > 0 {
+		log.Printf("found %d discrepancies in %d accounts and %d transfers", len(report.Discrepancies), report.AccountsChecked, report.TransfersChecked)
+		os.Exit(1)
+	}
+}
+
+// runSnapshotter writes the closing balances of every account once a day has
+// closed.
+func runSnapshotter(config util.Config, store db.Store) {
+	log.Printf("start balance snapshotter")
+	worker.NewSnapshotter(config, store).Start(context.Background())
+}
+
+// runFundingSweeper settles the deposits and withdrawals the funding gateway
+// gave no answer for once it does.
+func runFundingSweeper(config util.Config, store db.Store) {
+	log.Printf("start funding sweeper")
+	// no real gateway is wired up yet, so payments are only pretended
+	funding.NewSweeper(config, store, funding.NewFakeGateway()).Start(context.Background())
+}
+
+func runGrpcServer(config util.Config, server *gapi.Server) {
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
+	if err != nil {
+		log.Fatal("cannot create gRPC listener:", err)
+	}
+
+	log.Printf("start gRPC server at %s", listener.Addr().String())
+	err = server.NewGRPCServer().Serve(listener)
+	if err != nil {
+		log.Fatal("cannot start gRPC server:", err)
+	}
+}
+
+// runHTTPServer serves the gateway of the gRPC API under /v1 and the Gin
+// routes everywhere else.
+func runHTTPServer(config util.Config, store db.Store, revocations *auth.RevocationList, grpcServer *gapi.Server) {
+	ginServer, err := api.NewServer(config, store, revocations)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
 
-	err = server.Start(config.ServerAddress)
+	gateway, err := grpcServer.NewGatewayHandler(context.Background())
+	if err != nil {
+		log.Fatal("cannot create gateway:", err)
+	}
+
+	// the gateway serves the gRPC API as JSON, with the field names and errors of
+	// gRPC; the REST API at the root is the one HTTP clients are meant to use
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", gateway)
+	mux.Handle("/", ginServer)
+
+	log.Printf("start HTTP server at %s", config.ServerAddress)
+	err = http.ListenAndServe(config.ServerAddress, mux)
 	if err != nil {
 		log.Fatal("cannot start server:", err)
 	}
@@ -0,0 +1,67 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+)
+
+// EventPublisher hands domain events over to whatever consumes them. Events
+// may be published more than once, so consumers should discard the IDs they
+// have already seen.
+type EventPublisher interface {
+	Publish(ctx context.Context, event db.Event) error
+}
+
+// LogPublisher writes every event as a line of JSON, to a file or to the log.
+type LogPublisher struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewLogPublisher(w io.Writer) *LogPublisher {
+	return &LogPublisher{encoder: json.NewEncoder(w)}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event db.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.encoder.Encode(event)
+}
+
+// MemoryPublisher keeps the events it is asked to publish in memory, so that
+// tests can look at them.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []db.Event
+	// returned by Publish when set
+	Err error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, event db.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Published returns the events published so far, oldest first.
+func (p *MemoryPublisher) Published() []db.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]db.Event{}, p.events...)
+}
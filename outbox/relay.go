@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+	// how long a batch may take to publish before other relays publish its
+	// events again
+	relayLease = time.Minute
+)
+
+// Relay publishes the events the store writes to the outbox. Since events are
+// written in the transaction of the change they are about, and only marked as
+// published once the publisher accepted them, every committed change is
+// published at least once, and nothing is published for changes that were
+// rolled back.
+type Relay struct {
+	store        db.Store
+	publisher    EventPublisher
+	batchSize    int32
+	pollInterval time.Duration
+}
+
+func NewRelay(config util.Config, store db.Store, publisher EventPublisher) *Relay {
+	relay := &Relay{
+		store:        store,
+		publisher:    publisher,
+		batchSize:    config.OutboxBatchSize,
+		pollInterval: config.OutboxPollInterval,
+	}
+
+	if relay.batchSize <= 0 {
+		relay.batchSize = defaultBatchSize
+	}
+	if relay.pollInterval <= 0 {
+		relay.pollInterval = defaultPollInterval
+	}
+
+	return relay
+}
+
+// Start relays events until ctx is done. It keeps publishing batches while
+// they come back full, and waits for the poll interval otherwise.
+func (r *Relay) Start(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.relayBatch(ctx)
+		if err != nil {
+			log.Printf("cannot relay outbox events: %s", err)
+		}
+		if err == nil && published == int(r.batchSize) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// relayBatch publishes the next batch of events and tells how many of them
+// were published.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	return r.store.RelayOutbox(ctx, db.RelayOutboxParams{
+		Limit: r.batchSize,
+		Lease: relayLease,
+		Publish: func(event db.Event) error {
+			return r.publisher.Publish(ctx, event)
+		},
+	})
+}
@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomEvent() db.Event {
+	return db.Event{
+		ID:        uuid.New(),
+		Type:      db.EventTransferCreated,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      json.RawMessage(`{"id":1}`),
+	}
+}
+
+// relayEvents stubs RelayOutbox to hand events to the publisher like the
+// store does, stopping at the first error.
+func relayEvents(events []db.Event) func(context.Context, db.RelayOutboxParams) (int, error) {
+	return func(_ context.Context, arg db.RelayOutboxParams) (int, error) {
+		for i, event := range events {
+			if err := arg.Publish(event); err != nil {
+				return i, err
+			}
+		}
+		return len(events), nil
+	}
+}
+
+func TestRelayBatch(t *testing.T) {
+	events := []db.Event{randomEvent(), randomEvent()}
+
+	testCases := []struct {
+		name       string
+		publishErr error
+		check      func(t *testing.T, published int, err error, publisher *MemoryPublisher)
+	}{
+		{
+			name: "OK",
+			check: func(t *testing.T, published int, err error, publisher *MemoryPublisher) {
+				require.NoError(t, err)
+				require.Equal(t, 2, published)
+				require.Equal(t, events, publisher.Published())
+			},
+		},
+		{
+			name:       "PublisherDown",
+			publishErr: errors.New("connection refused"),
+			check: func(t *testing.T, published int, err error, publisher *MemoryPublisher) {
+				require.EqualError(t, err, "connection refused")
+				require.Zero(t, published)
+				require.Empty(t, publisher.Published())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				RelayOutbox(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(ctx context.Context, arg db.RelayOutboxParams) (int, error) {
+					require.Equal(t, int32(defaultBatchSize), arg.Limit)
+					require.Equal(t, relayLease, arg.Lease)
+					return relayEvents(events)(ctx, arg)
+				})
+
+			publisher := NewMemoryPublisher()
+			publisher.Err = tc.publishErr
+			relay := NewRelay(util.Config{}, store, publisher)
+
+			published, err := relay.relayBatch(context.Background())
+			tc.check(t, published, err, publisher)
+		})
+	}
+}
+
+func TestRelayStart(t *testing.T) {
+	config := util.Config{
+		OutboxBatchSize:    2,
+		OutboxPollInterval: time.Hour,
+	}
+	events := []db.Event{randomEvent(), randomEvent(), randomEvent()}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a full batch is followed by another one right away, while a partial one
+	// means the outbox is drained
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().RelayOutbox(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(relayEvents(events[:2])),
+		store.EXPECT().
+			RelayOutbox(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(ctx context.Context, arg db.RelayOutboxParams) (int, error) {
+				defer cancel()
+				return relayEvents(events[2:])(ctx, arg)
+			}),
+	)
+
+	publisher := NewMemoryPublisher()
+	NewRelay(config, store, publisher).Start(ctx)
+	require.Equal(t, events, publisher.Published())
+}
+
+func TestLogPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewLogPublisher(&buf)
+
+	event1, event2 := randomEvent(), randomEvent()
+	require.NoError(t, publisher.Publish(context.Background(), event1))
+	require.NoError(t, publisher.Publish(context.Background(), event2))
+
+	decoder := json.NewDecoder(&buf)
+	for _, expected := range []db.Event{event1, event2} {
+		var event db.Event
+		require.NoError(t, decoder.Decode(&event))
+		require.Equal(t, expected.ID, event.ID)
+		require.Equal(t, expected.Type, event.Type)
+		require.True(t, expected.CreatedAt.Equal(event.CreatedAt))
+		require.JSONEq(t, string(expected.Data), string(event.Data))
+	}
+}
@@ -0,0 +1,145 @@
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+)
+
+const defaultBatchSize = 500
+
+// Options of a reconciliation run.
+type Options struct {
+	// start each account from its checkpoint instead of its first entry
+	Incremental bool
+	// record the balances found correct as checkpoints for incremental runs
+	Checkpoint bool
+}
+
+// Reconciler checks that the ledger agrees with itself: that the balance of
+// every account is what its entries add up to, and that every transfer has the
+// entries that move its money.
+type Reconciler struct {
+	store     db.Store
+	batchSize int32
+}
+
+func NewReconciler(store db.Store) *Reconciler {
+	return &Reconciler{
+		store:     store,
+		batchSize: defaultBatchSize,
+	}
+}
+
+// Run goes through every account and transfer in batches and reports what
+// doesn't add up. An incremental run only adds up the entries written since
+// the checkpoint of each account, so it won't notice entries changed before
+// it; a full run should confirm what it finds, and check now and then that the
+// checkpoints still hold.
+func (r *Reconciler) Run(ctx context.Context, opts Options) (Report, error) {
+	report := Report{
+		StartedAt:     time.Now().UTC(),
+		Incremental:   opts.Incremental,
+		Discrepancies: []Discrepancy{},
+	}
+
+	err := r.checkAccounts(ctx, opts, &report)
+	if err != nil {
+		return report, err
+	}
+
+	err = r.checkTransfers(ctx, &report)
+	if err != nil {
+		return report, err
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
+
+func (r *Reconciler) checkAccounts(ctx context.Context, opts Options, report *Report) error {
+	var afterID int64
+	for {
+		accounts, err := r.store.ListAccountLedgerBalances(ctx, db.ListAccountLedgerBalancesParams{
+			Incremental: opts.Incremental,
+			AfterID:     afterID,
+			PageLimit:   r.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			report.AccountsChecked++
+
+			if account.Balance != account.EntriesBalance {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind:      DiscrepancyAccountBalance,
+					AccountID: account.ID,
+					Expected:  account.EntriesBalance,
+					Actual:    account.Balance,
+				})
+				continue
+			}
+
+			if opts.Checkpoint {
+				err = r.store.UpsertReconciliationCheckpoint(ctx, db.UpsertReconciliationCheckpointParams{
+					AccountID:   account.ID,
+					Balance:     account.EntriesBalance,
+					LastEntryID: account.LastEntryID,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(accounts) < int(r.batchSize) {
+			return nil
+		}
+		afterID = accounts[len(accounts)-1].ID
+	}
+}
+
+func (r *Reconciler) checkTransfers(ctx context.Context, report *Report) error {
+	var afterID int64
+	for {
+		transfers, err := r.store.ListTransferEntryTotals(ctx, db.ListTransferEntryTotalsParams{
+			AfterID:   afterID,
+			PageLimit: r.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, transfer := range transfers {
+			report.TransfersChecked++
+
+			if transfer.FromEntries != -transfer.Amount {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind:       DiscrepancyTransferEntries,
+					AccountID:  transfer.FromAccountID,
+					TransferID: transfer.ID,
+					Expected:   -transfer.Amount,
+					Actual:     transfer.FromEntries,
+				})
+			}
+
+			if transfer.ToEntries != transfer.ToAmount {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind:       DiscrepancyTransferEntries,
+					AccountID:  transfer.ToAccountID,
+					TransferID: transfer.ID,
+					Expected:   transfer.ToAmount,
+					Actual:     transfer.ToEntries,
+				})
+			}
+		}
+
+		if len(transfers) < int(r.batchSize) {
+			return nil
+		}
+		afterID = transfers[len(transfers)-1].ID
+	}
+}
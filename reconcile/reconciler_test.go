@@ -0,0 +1,79 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcilerRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	reconciler := NewReconciler(store)
+	reconciler.batchSize = 2
+
+	// accounts come in two batches, the second one short
+	store.EXPECT().
+		ListAccountLedgerBalances(gomock.Any(), gomock.Eq(db.ListAccountLedgerBalancesParams{Incremental: true, AfterID: 0, PageLimit: 2})).
+		Times(1).
+		Return([]db.ListAccountLedgerBalancesRow{
+			{ID: 1, Balance: 100, EntriesBalance: 100, LastEntryID: 7},
+			{ID: 2, Balance: 250, EntriesBalance: 200, LastEntryID: 9},
+		}, nil)
+	store.EXPECT().
+		ListAccountLedgerBalances(gomock.Any(), gomock.Eq(db.ListAccountLedgerBalancesParams{Incremental: true, AfterID: 2, PageLimit: 2})).
+		Times(1).
+		Return([]db.ListAccountLedgerBalancesRow{
+			{ID: 3, Balance: 0, EntriesBalance: 0},
+		}, nil)
+
+	// only the balances that add up are checkpointed
+	store.EXPECT().
+		UpsertReconciliationCheckpoint(gomock.Any(), gomock.Eq(db.UpsertReconciliationCheckpointParams{AccountID: 1, Balance: 100, LastEntryID: 7})).
+		Times(1)
+	store.EXPECT().
+		UpsertReconciliationCheckpoint(gomock.Any(), gomock.Eq(db.UpsertReconciliationCheckpointParams{AccountID: 3})).
+		Times(1)
+
+	store.EXPECT().
+		ListTransferEntryTotals(gomock.Any(), gomock.Eq(db.ListTransferEntryTotalsParams{AfterID: 0, PageLimit: 2})).
+		Times(1).
+		Return([]db.ListTransferEntryTotalsRow{
+			{ID: 5, FromAccountID: 1, ToAccountID: 2, Amount: 10, ToAmount: 10, FromEntries: -10, ToEntries: 10},
+			{ID: 6, FromAccountID: 2, ToAccountID: 3, Amount: 30, ToAmount: 27, FromEntries: -30},
+		}, nil)
+	store.EXPECT().
+		ListTransferEntryTotals(gomock.Any(), gomock.Eq(db.ListTransferEntryTotalsParams{AfterID: 6, PageLimit: 2})).
+		Times(1).
+		Return([]db.ListTransferEntryTotalsRow{}, nil)
+
+	report, err := reconciler.Run(context.Background(), Options{Incremental: true, Checkpoint: true})
+	require.NoError(t, err)
+	require.True(t, report.Incremental)
+	require.Equal(t, 3, report.AccountsChecked)
+	require.Equal(t, 2, report.TransfersChecked)
+	require.Equal(t, []Discrepancy{
+		{Kind: DiscrepancyAccountBalance, AccountID: 2, Expected: 200, Actual: 250},
+		{Kind: DiscrepancyTransferEntries, AccountID: 3, TransferID: 6, Expected: 27, Actual: 0},
+	}, report.Discrepancies)
+	require.False(t, report.FinishedAt.Before(report.StartedAt))
+}
+
+func TestReconcilerRunError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListAccountLedgerBalances(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+	store.EXPECT().ListTransferEntryTotals(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := NewReconciler(store).Run(context.Background(), Options{})
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
@@ -0,0 +1,84 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Kinds of discrepancy.
+const (
+	// the balance of an account is not what its entries add up to
+	DiscrepancyAccountBalance = "account_balance"
+	// the entries of a transfer don't move what it says to an account
+	DiscrepancyTransferEntries = "transfer_entries"
+)
+
+// Discrepancy is something in the ledger that doesn't add up.
+type Discrepancy struct {
+	Kind      string `json:"kind"`
+	AccountID int64  `json:"accountID"`
+	// transfer whose entries are off, absent for account balances
+	TransferID int64 `json:"transferID,omitempty"`
+	// what the entries add up to for an account balance, or what the transfer
+	// moves for its entries
+	Expected int64 `json:"expected"`
+	Actual   int64 `json:"actual"`
+}
+
+// Report is the outcome of a reconciliation run.
+type Report struct {
+	StartedAt        time.Time     `json:"startedAt"`
+	FinishedAt       time.Time     `json:"finishedAt"`
+	Incremental      bool          `json:"incremental"`
+	AccountsChecked  int           `json:"accountsChecked"`
+	TransfersChecked int           `json:"transfersChecked"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+}
+
+// Formats a report can be written in.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Write writes the report in format. CSV only has room for the
+// discrepancies, one per row.
+func (r Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case FormatCSV:
+		return r.writeCSV(w)
+	}
+
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+func (r Report) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"kind", "account_id", "transfer_id", "expected", "actual"})
+
+	for _, discrepancy := range r.Discrepancies {
+		transferID := ""
+		if discrepancy.TransferID != 0 {
+			transferID = strconv.FormatInt(discrepancy.TransferID, 10)
+		}
+
+		writer.Write([]string{
+			discrepancy.Kind,
+			strconv.FormatInt(discrepancy.AccountID, 10),
+			transferID,
+			strconv.FormatInt(discrepancy.Expected, 10),
+			strconv.FormatInt(discrepancy.Actual, 10),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
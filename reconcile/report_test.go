@@ -0,0 +1,36 @@
+package reconcile
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportWrite(t *testing.T) {
+	report := Report{
+		AccountsChecked:  2,
+		TransfersChecked: 1,
+		Discrepancies: []Discrepancy{
+			{Kind: DiscrepancyAccountBalance, AccountID: 2, Expected: 200, Actual: 250},
+			{Kind: DiscrepancyTransferEntries, AccountID: 3, TransferID: 6, Expected: 27, Actual: 0},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, report.Write(&buf, FormatCSV))
+	require.Equal(t, "kind,account_id,transfer_id,expected,actual\n"+
+		"account_balance,2,,200,250\n"+
+		"transfer_entries,3,6,27,0\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, report.Write(&buf, FormatJSON))
+
+	var decoded Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, report.Discrepancies, decoded.Discrepancies)
+	require.Equal(t, 2, decoded.AccountsChecked)
+
+	require.Error(t, report.Write(&buf, "xml"))
+}
@@ -12,14 +12,26 @@ var (
 	ErrInvalidToken = errors.New("token is invalid")
 )
 
+// TokenType tells what a token may be used for. Access tokens authenticate
+// requests; refresh tokens only renew access tokens, so that a long-lived token
+// can't be used where a short-lived one is expected.
+type TokenType string
+
+const (
+	TokenTypeAccess  TokenType = "access"
+	TokenTypeRefresh TokenType = "refresh"
+)
+
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	Type      TokenType `json:"type"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -27,7 +39,9 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 
 	payload := Payload{
 		ID:        tokenID,
+		Type:      tokenType,
 		Username:  username,
+		Role:      role,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -0,0 +1,276 @@
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
+)
+
+const (
+	defaultSchedulerPollInterval = 30 * time.Second
+	schedulerBatchSize           = 100
+	// runs that fail under the retry policy are tried again after
+	// scheduledRetryDelay, up to scheduledMaxAttempts times in all
+	scheduledRetryDelay  = time.Hour
+	scheduledMaxAttempts = 3
+)
+
+var (
+	errOwnerNotFound    = errors.New("owner of the scheduled transfer not found")
+	errEmailNotVerified = errors.New("email address of the owner must be verified first")
+)
+
+// Scheduler makes the transfers that are scheduled to run. Each run is
+// recorded in the transaction of its transfer, which is undone when another
+// scheduler already ran the same occurrence, so several schedulers can poll
+// the same database. Transfers are made through the Service on behalf of their
+// owner, so that they are checked as if the owner asked for them at the time.
+type Scheduler struct {
+	store        db.Store
+	transfers    *Service
+	pollInterval time.Duration
+}
+
+func NewScheduler(config util.Config, store db.Store) (*Scheduler, error) {
+	var err error
+	var rates exchange.RateProvider = exchange.NewStoreProvider(store)
+	if len(config.ExchangeRatesFile) > 0 {
+		rates, err = exchange.NewFileProvider(config.ExchangeRatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load exchange rates: %w", err)
+		}
+	}
+
+	var currencies *util.CurrencyRegistry
+	if len(config.CurrenciesFile) > 0 {
+		currencies, err = util.LoadCurrencyRegistry(config.CurrenciesFile)
+	} else {
+		currencies, err = db.LoadCurrencyRegistry(context.Background(), store)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	service := NewService(config, store, rates, currencies, worker.NewPGTaskDistributor())
+	return newScheduler(config, store, service), nil
+}
+
+func newScheduler(config util.Config, store db.Store, service *Service) *Scheduler {
+	scheduler := &Scheduler{
+		store:        store,
+		transfers:    service,
+		pollInterval: config.SchedulerPollInterval,
+	}
+
+	if scheduler.pollInterval <= 0 {
+		scheduler.pollInterval = defaultSchedulerPollInterval
+	}
+
+	return scheduler
+}
+
+// Start runs due transfers until ctx is done, checking for them every poll
+// interval.
+func (s *Scheduler) Start(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := s.runDue(ctx); err != nil {
+			log.Printf("cannot run scheduled transfers: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// runDue runs every scheduled transfer that is due. A transfer that can't be
+// run is logged and left for the next poll.
+func (s *Scheduler) runDue(ctx context.Context) error {
+	for {
+		scheduledTransfers, err := s.store.ListDueScheduledTransfers(ctx, schedulerBatchSize)
+		if err != nil {
+			return fmt.Errorf("cannot list due scheduled transfers: %w", err)
+		}
+
+		failed := 0
+		for _, scheduledTransfer := range scheduledTransfers {
+			if err := s.runScheduledTransfer(ctx, scheduledTransfer, time.Now()); err != nil {
+				log.Printf("cannot run scheduled transfer %d: %s", scheduledTransfer.ID, err)
+				failed++
+			}
+		}
+
+		// transfers that failed stay due, so only carry on while the batch made
+		// some progress
+		if len(scheduledTransfers) < schedulerBatchSize || failed == len(scheduledTransfers) {
+			return nil
+		}
+	}
+}
+
+// runScheduledTransfer makes the transfer of a due scheduled transfer and
+// moves it on to its next occurrence. Runs that fail for a reason the owner can
+// fix, such as a lack of funds, an inactive account or a transfer limit, are
+// retried or skipped according to the failure policy. Runs that can never
+// succeed fail the scheduled transfer, and any other error leaves it due for
+// the next poll.
+func (s *Scheduler) runScheduledTransfer(ctx context.Context, scheduledTransfer db.ScheduledTransfer, now time.Time) error {
+	status, nextRunAt, err := nextOccurrence(scheduledTransfer, now)
+	if err != nil {
+		return err
+	}
+
+	err = s.makeTransfer(ctx, scheduledTransfer, func(q db.Querier, result db.TransferTxResult) error {
+		_, err := db.RecordScheduledTransferRun(ctx, q, db.RecordScheduledTransferRunParams{
+			ScheduledTransferID: scheduledTransfer.ID,
+			ScheduledFor:        scheduledTransfer.NextRunAt,
+			Outcome:             db.ScheduledRunSucceeded,
+			TransferID:          sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+			Status:              status,
+			NextRunAt:           nextRunAt,
+		})
+		return err
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, db.ErrScheduledRunTaken):
+		// another scheduler ran this occurrence first
+		return nil
+	case errors.Is(err, errOwnerNotFound), errors.Is(err, ErrAccountNotFound),
+		errors.Is(err, ErrAccountCurrencyMismatch), errors.Is(err, db.ErrCurrencyMismatch), errors.Is(err, db.ErrSystemAccount),
+		errors.Is(err, auth.ErrNotAuthorized), errors.Is(err, auth.ErrUnknownRole):
+		return s.recordPermanentFailure(ctx, scheduledTransfer, err)
+	case errors.Is(err, errEmailNotVerified),
+		errors.Is(err, db.ErrInsufficientFunds), errors.Is(err, db.ErrAccountNotActive),
+		errors.Is(err, db.ErrTransferLimitExceeded), errors.Is(err, db.ErrDailyLimitExceeded), errors.Is(err, db.ErrMonthlyLimitExceeded):
+		return s.recordFailedRun(ctx, scheduledTransfer, now, err)
+	}
+
+	return err
+}
+
+// makeTransfer makes the transfer of a run on behalf of the owner of the
+// scheduled transfer, who must still have a verified email address and be
+// allowed to move the funds of the source account.
+func (s *Scheduler) makeTransfer(ctx context.Context, scheduledTransfer db.ScheduledTransfer, afterTransfer func(q db.Querier, result db.TransferTxResult) error) error {
+	owner, err := s.store.GetUser(ctx, scheduledTransfer.Owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", errOwnerNotFound, scheduledTransfer.Owner)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !owner.IsEmailVerified {
+		return errEmailNotVerified
+	}
+
+	fromAccount, err := s.transfers.loadAccount(ctx, scheduledTransfer.FromAccountID)
+	if err != nil {
+		return err
+	}
+
+	payload := &token.Payload{
+		Type:     token.TokenTypeAccess,
+		Username: owner.Username,
+		Role:     owner.Role,
+	}
+	_, err = s.transfers.Create(ctx, payload, CreateParams{
+		FromAccountID: scheduledTransfer.FromAccountID,
+		ToAccountID:   scheduledTransfer.ToAccountID,
+		Amount:        scheduledTransfer.Amount,
+		Currency:      fromAccount.Currency,
+		AfterTransfer: afterTransfer,
+	})
+	return err
+}
+
+// recordPermanentFailure fails a scheduled transfer whose run can never
+// succeed, such as one whose account is gone or that the owner may no longer
+// move the funds of.
+func (s *Scheduler) recordPermanentFailure(ctx context.Context, scheduledTransfer db.ScheduledTransfer, runErr error) error {
+	_, err := s.store.RecordScheduledTransferRunTx(ctx, db.RecordScheduledTransferRunParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		ScheduledFor:        scheduledTransfer.NextRunAt,
+		Outcome:             db.ScheduledRunFailed,
+		Error:               runErr.Error(),
+		Status:              db.ScheduledTransferFailed,
+		NextRunAt:           scheduledTransfer.NextRunAt,
+	})
+	if errors.Is(err, db.ErrScheduledRunTaken) {
+		return nil
+	}
+	return err
+}
+
+// recordFailedRun retries a run that failed for a reason the owner can fix,
+// or skips it to the next occurrence once it may not be retried any more. A
+// one-off transfer that is skipped fails for good.
+func (s *Scheduler) recordFailedRun(ctx context.Context, scheduledTransfer db.ScheduledTransfer, now time.Time, runErr error) error {
+	arg := db.RecordScheduledTransferRunParams{
+		ScheduledTransferID: scheduledTransfer.ID,
+		ScheduledFor:        scheduledTransfer.NextRunAt,
+		Error:               runErr.Error(),
+	}
+
+	attempts := scheduledTransfer.Attempts + 1
+	if scheduledTransfer.FailurePolicy == db.FailurePolicyRetry && attempts < scheduledMaxAttempts {
+		arg.Outcome = db.ScheduledRunRetrying
+		arg.Status = db.ScheduledTransferActive
+		arg.NextRunAt = now.Add(scheduledRetryDelay)
+		arg.Attempts = attempts
+	} else {
+		status, nextRunAt, err := nextOccurrence(scheduledTransfer, now)
+		if err != nil {
+			return err
+		}
+		if status == db.ScheduledTransferCompleted && len(scheduledTransfer.Schedule) == 0 {
+			status = db.ScheduledTransferFailed
+		}
+
+		arg.Outcome = db.ScheduledRunSkipped
+		arg.Status = status
+		arg.NextRunAt = nextRunAt
+	}
+
+	_, err := s.store.RecordScheduledTransferRunTx(ctx, arg)
+	if errors.Is(err, db.ErrScheduledRunTaken) {
+		return nil
+	}
+	return err
+}
+
+// nextOccurrence tells the status and next run of a scheduled transfer once
+// its current run is done. Recurring transfers move to the first occurrence
+// after now, so occurrences missed while no scheduler was running are not
+// made up for. One-off transfers and schedules with no occurrence left are
+// completed.
+func nextOccurrence(scheduledTransfer db.ScheduledTransfer, now time.Time) (string, time.Time, error) {
+	if len(scheduledTransfer.Schedule) == 0 {
+		return db.ScheduledTransferCompleted, scheduledTransfer.NextRunAt, nil
+	}
+
+	schedule, err := util.ParseCron(scheduledTransfer.Schedule)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	next := schedule.Next(now.UTC())
+	if next.IsZero() {
+		return db.ScheduledTransferCompleted, scheduledTransfer.NextRunAt, nil
+	}
+
+	return db.ScheduledTransferActive, next, nil
+}
@@ -0,0 +1,377 @@
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/leoomi/simplebank/auth"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunScheduledTransfer(t *testing.T) {
+	now := time.Date(2024, time.March, 14, 9, 30, 0, 0, time.UTC)
+	recurring := db.ScheduledTransfer{
+		ID:            4,
+		Owner:         util.RandomOwner(),
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        500,
+		Schedule:      "0 9 * * 1",
+		FailurePolicy: db.FailurePolicyRetry,
+		Status:        db.ScheduledTransferActive,
+		NextRunAt:     now.Add(-30 * time.Minute),
+	}
+	nextMonday := time.Date(2024, time.March, 18, 9, 0, 0, 0, time.UTC)
+
+	oneOff := recurring
+	oneOff.Schedule = ""
+	oneOff.FailurePolicy = db.FailurePolicySkip
+
+	transfer := db.Transfer{ID: 11, FromAccountID: 1, ToAccountID: 2, Amount: 500, ToAmount: 500}
+
+	owner := db.User{Username: recurring.Owner, Role: util.DepositorRole, IsEmailVerified: true}
+	from := db.Account{ID: 1, Owner: recurring.Owner, Currency: util.USD, Status: util.AccountStatusActive, Kind: db.AccountKindCustomer}
+	to := db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.USD, Status: util.AccountStatusActive, Kind: db.AccountKindCustomer}
+
+	// loadOwner stubs the owner and the accounts the transfer is checked
+	// against
+	loadOwner := func(store *mockdb.MockStore) {
+		store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner.Username)).Times(1).Return(owner, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(2).Return(from, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).Times(1).Return(to, nil)
+	}
+
+	// runTransfer stubs TransferTx to make the transfer and run AfterTransfer
+	// with q, like the store does inside its transaction
+	runTransfer := func(store *mockdb.MockStore, q db.Querier, scheduledTransfer db.ScheduledTransfer) {
+		loadOwner(store)
+		store.EXPECT().
+			TransferTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+				require.Equal(t, scheduledTransfer.FromAccountID, arg.FromAccountID)
+				require.Equal(t, scheduledTransfer.ToAccountID, arg.ToAccountID)
+				require.Equal(t, scheduledTransfer.Amount, arg.Amount)
+
+				result := db.TransferTxResult{Transfer: transfer}
+				return result, arg.AfterTransfer(q, result)
+			})
+	}
+
+	testCases := []struct {
+		name              string
+		scheduledTransfer db.ScheduledTransfer
+		buildStubs        func(store *mockdb.MockStore, q *mockdb.MockStore)
+		check             func(t *testing.T, err error)
+	}{
+		{
+			name:              "Recurring",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				runTransfer(store, q, recurring)
+				q.EXPECT().
+					AdvanceScheduledTransfer(gomock.Any(), gomock.Eq(db.AdvanceScheduledTransferParams{
+						Status:       db.ScheduledTransferActive,
+						NextRunAt:    nextMonday,
+						ID:           recurring.ID,
+						ScheduledFor: recurring.NextRunAt,
+					})).
+					Times(1).
+					Return(recurring, nil)
+				q.EXPECT().
+					CreateScheduledTransferRun(gomock.Any(), gomock.Eq(db.CreateScheduledTransferRunParams{
+						ScheduledTransferID: recurring.ID,
+						ScheduledFor:        recurring.NextRunAt,
+						Outcome:             db.ScheduledRunSucceeded,
+						TransferID:          sql.NullInt64{Int64: transfer.ID, Valid: true},
+					})).
+					Times(1)
+				q.EXPECT().
+					CreateTask(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateTaskParams) (db.Task, error) {
+						require.Equal(t, worker.TaskSendTransferNotification, arg.Type)
+						return db.Task{}, nil
+					})
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:              "OneOffCompletes",
+			scheduledTransfer: oneOff,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				runTransfer(store, q, oneOff)
+				q.EXPECT().
+					AdvanceScheduledTransfer(gomock.Any(), gomock.Eq(db.AdvanceScheduledTransferParams{
+						Status:       db.ScheduledTransferCompleted,
+						NextRunAt:    oneOff.NextRunAt,
+						ID:           oneOff.ID,
+						ScheduledFor: oneOff.NextRunAt,
+					})).
+					Times(1).
+					Return(oneOff, nil)
+				q.EXPECT().CreateScheduledTransferRun(gomock.Any(), gomock.Any()).Times(1)
+				q.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(1)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:              "AlreadyRan",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				runTransfer(store, q, recurring)
+				q.EXPECT().
+					AdvanceScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+				q.EXPECT().CreateScheduledTransferRun(gomock.Any(), gomock.Any()).Times(0)
+				// undone along with the transfer
+				q.EXPECT().CreateTask(gomock.Any(), gomock.Any()).Times(1)
+				store.EXPECT().RecordScheduledTransferRunTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:              "InsufficientFundsRetried",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				loadOwner(store)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+				store.EXPECT().
+					RecordScheduledTransferRunTx(gomock.Any(), gomock.Eq(db.RecordScheduledTransferRunParams{
+						ScheduledTransferID: recurring.ID,
+						ScheduledFor:        recurring.NextRunAt,
+						Outcome:             db.ScheduledRunRetrying,
+						Error:               db.ErrInsufficientFunds.Error(),
+						Status:              db.ScheduledTransferActive,
+						NextRunAt:           now.Add(scheduledRetryDelay),
+						Attempts:            1,
+					})).
+					Times(1)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:              "DailyLimitRetried",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				loadOwner(store)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrDailyLimitExceeded)
+				store.EXPECT().
+					RecordScheduledTransferRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RecordScheduledTransferRunParams) (db.ScheduledTransferRun, error) {
+						require.Equal(t, db.ScheduledRunRetrying, arg.Outcome)
+						require.Equal(t, db.ErrDailyLimitExceeded.Error(), arg.Error)
+						return db.ScheduledTransferRun{}, nil
+					})
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "RetriesExhausted",
+			scheduledTransfer: func() db.ScheduledTransfer {
+				scheduledTransfer := recurring
+				scheduledTransfer.Attempts = scheduledMaxAttempts - 1
+				return scheduledTransfer
+			}(),
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				loadOwner(store)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrAccountNotActive)
+				store.EXPECT().
+					RecordScheduledTransferRunTx(gomock.Any(), gomock.Eq(db.RecordScheduledTransferRunParams{
+						ScheduledTransferID: recurring.ID,
+						ScheduledFor:        recurring.NextRunAt,
+						Outcome:             db.ScheduledRunSkipped,
+						Error:               db.ErrAccountNotActive.Error(),
+						Status:              db.ScheduledTransferActive,
+						NextRunAt:           nextMonday,
+					})).
+					Times(1)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:              "OneOffSkippedFails",
+			scheduledTransfer: oneOff,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				loadOwner(store)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+				store.EXPECT().
+					RecordScheduledTransferRunTx(gomock.Any(), gomock.Eq(db.RecordScheduledTransferRunParams{
+						ScheduledTransferID: oneOff.ID,
+						ScheduledFor:        oneOff.NextRunAt,
+						Outcome:             db.ScheduledRunSkipped,
+						Error:               db.ErrInsufficientFunds.Error(),
+						Status:              db.ScheduledTransferFailed,
+						NextRunAt:           oneOff.NextRunAt,
+					})).
+					Times(1)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// the owner can still verify their email address
+			name:              "EmailNotVerifiedRetried",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				unverified := owner
+				unverified.IsEmailVerified = false
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner.Username)).Times(1).Return(unverified, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					RecordScheduledTransferRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RecordScheduledTransferRunParams) (db.ScheduledTransferRun, error) {
+						require.Equal(t, db.ScheduledRunRetrying, arg.Outcome)
+						require.Equal(t, errEmailNotVerified.Error(), arg.Error)
+						return db.ScheduledTransferRun{}, nil
+					})
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:              "NotAuthorizedFails",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				foreign := from
+				foreign.Owner = util.RandomOwner()
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner.Username)).Times(1).Return(owner, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(2).Return(foreign, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					RecordScheduledTransferRunTx(gomock.Any(), gomock.Eq(db.RecordScheduledTransferRunParams{
+						ScheduledTransferID: recurring.ID,
+						ScheduledFor:        recurring.NextRunAt,
+						Outcome:             db.ScheduledRunFailed,
+						Error:               auth.ErrNotAuthorized.Error(),
+						Status:              db.ScheduledTransferFailed,
+						NextRunAt:           recurring.NextRunAt,
+					})).
+					Times(1)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:              "AccountGoneFails",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner.Username)).Times(1).Return(owner, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					RecordScheduledTransferRunTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RecordScheduledTransferRunParams) (db.ScheduledTransferRun, error) {
+						require.Equal(t, db.ScheduledRunFailed, arg.Outcome)
+						require.Equal(t, db.ScheduledTransferFailed, arg.Status)
+						return db.ScheduledTransferRun{}, nil
+					})
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// only the claim of the run tells that another scheduler got there
+			// first; a missing row anywhere else is an error
+			name:              "NoRowsIsNotARace",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				loadOwner(store)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrNoRows)
+				store.EXPECT().RecordScheduledTransferRunTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrNoRows)
+			},
+		},
+		{
+			name:              "DatabaseDown",
+			scheduledTransfer: recurring,
+			buildStubs: func(store *mockdb.MockStore, q *mockdb.MockStore) {
+				loadOwner(store)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrConnDone)
+				store.EXPECT().RecordScheduledTransferRunTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error) {
+				require.True(t, errors.Is(err, sql.ErrConnDone))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			q := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, q)
+
+			scheduler := newTestScheduler(store)
+			err := scheduler.runScheduledTransfer(context.Background(), tc.scheduledTransfer, now)
+			tc.check(t, err)
+		})
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scheduledTransfer := db.ScheduledTransfer{
+		ID:            9,
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        100,
+		FailurePolicy: db.FailurePolicyRetry,
+		Status:        db.ScheduledTransferActive,
+		NextRunAt:     time.Now().Add(-time.Minute),
+	}
+
+	// a transfer that can't run is left for the next poll instead of being
+	// listed again right away
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListDueScheduledTransfers(gomock.Any(), gomock.Eq(int32(schedulerBatchSize))).
+		Times(1).
+		Return([]db.ScheduledTransfer{scheduledTransfer}, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+
+	scheduler := newTestScheduler(store)
+	require.NoError(t, scheduler.runDue(context.Background()))
+}
+
+func newTestScheduler(store db.Store) *Scheduler {
+	service := NewService(util.Config{}, store, nil, util.NewCurrencyRegistry(util.DefaultCurrencies), worker.NewPGTaskDistributor())
+	return newScheduler(util.Config{}, store, service)
+}
@@ -0,0 +1,208 @@
+package transfers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leoomi/simplebank/auth"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
+)
+
+const MaxIdempotencyKeyLength = 255
+
+var (
+	ErrAccountNotFound         = errors.New("account not found")
+	ErrAccountCurrencyMismatch = errors.New("account currency mismatch")
+	ErrIdempotencyKeyTooLong   = fmt.Errorf("idempotency key must have at most %d characters", MaxIdempotencyKeyLength)
+	ErrIdempotencyKeyReused    = errors.New("idempotency key was already used with a different request")
+)
+
+// Service makes the transfers users ask for. Both the HTTP and the gRPC API
+// make transfers through it, so that they check, convert and deduplicate them
+// the same way and a retry through one API replays a transfer made through the
+// other.
+type Service struct {
+	config      util.Config
+	store       db.Store
+	rates       exchange.RateProvider
+	currencies  *util.CurrencyRegistry
+	distributor worker.TaskDistributor
+}
+
+func NewService(config util.Config, store db.Store, rates exchange.RateProvider, currencies *util.CurrencyRegistry, distributor worker.TaskDistributor) *Service {
+	return &Service{
+		config:      config,
+		store:       store,
+		rates:       rates,
+		currencies:  currencies,
+		distributor: distributor,
+	}
+}
+
+// CreateParams is a transfer as a user asks for it.
+type CreateParams struct {
+	FromAccountID int64  `json:"fromAccountID"`
+	ToAccountID   int64  `json:"toAccountID"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// makes retries of the request return the transfer the first one made
+	// instead of making another; left out of the request hash
+	IdempotencyKey string `json:"-"`
+	// called inside the transaction of the transfer once the notification is
+	// enqueued, with queries that run in it; an error undoes the transfer
+	AfterTransfer func(q db.Querier, result db.TransferTxResult) error `json:"-"`
+}
+
+// Hash identifies the request, so that an idempotency key used again with a
+// different request can be told apart from a retry.
+func (arg CreateParams) Hash() (string, error) {
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Create transfers money out of an account of the authenticated user, or of
+// one they may move funds of, converting it at the current rate when the
+// destination account holds another currency. The transfer notification is
+// enqueued along with the transfer.
+func (s *Service) Create(ctx context.Context, payload *token.Payload, arg CreateParams) (db.TransferTxResult, error) {
+	var result db.TransferTxResult
+
+	fromAccount, err := s.loadAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return result, err
+	}
+	if fromAccount.Currency != arg.Currency {
+		return result, fmt.Errorf("%w: account [%d] holds %s, not %s", ErrAccountCurrencyMismatch, fromAccount.ID, fromAccount.Currency, arg.Currency)
+	}
+
+	if err := auth.Authorize(payload, fromAccount.Owner, auth.ActionMoveFunds); err != nil {
+		return result, err
+	}
+
+	// the destination account may hold another currency, in which case the
+	// amount is converted at the current rate
+	toAccount, err := s.loadAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+
+	// checked again under lock when the transfer is made
+	if fromAccount.Kind != db.AccountKindCustomer || toAccount.Kind != db.AccountKindCustomer {
+		return result, db.ErrSystemAccount
+	}
+
+	txArg := db.TransferTxParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		AfterTransfer: func(q db.Querier, result db.TransferTxResult) error {
+			err := s.distributor.DistributeTaskSendTransferNotification(ctx, q, &worker.PayloadSendTransferNotification{
+				TransferID: result.Transfer.ID,
+			})
+			if err != nil || arg.AfterTransfer == nil {
+				return err
+			}
+
+			return arg.AfterTransfer(q, result)
+		},
+	}
+	if toAccount.Currency != fromAccount.Currency {
+		if err := s.convert(ctx, &txArg, fromAccount.Currency, toAccount.Currency); err != nil {
+			return result, err
+		}
+	}
+
+	if len(arg.IdempotencyKey) > 0 {
+		if len(arg.IdempotencyKey) > MaxIdempotencyKeyLength {
+			return result, ErrIdempotencyKeyTooLong
+		}
+
+		requestHash, err := arg.Hash()
+		if err != nil {
+			return result, err
+		}
+
+		if result, found, err := s.replay(ctx, payload.Username, arg.IdempotencyKey, requestHash); found || err != nil {
+			return result, err
+		}
+
+		txArg.Idempotency = &db.IdempotencyParams{
+			Username:    payload.Username,
+			Key:         arg.IdempotencyKey,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(s.config.IdempotencyKeyTTL),
+		}
+	}
+
+	result, err = s.store.TransferTx(ctx, txArg)
+	if err != nil && errors.Is(err, db.ErrIdempotencyKeyInUse) {
+		// a concurrent request with the same key won the race
+		if result, found, err := s.replay(ctx, payload.Username, arg.IdempotencyKey, txArg.Idempotency.RequestHash); found || err != nil {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// replay returns the stored result of a previous request made with the same
+// idempotency key, or found false when there is nothing to replay.
+func (s *Service) replay(ctx context.Context, username, idempotencyKey, requestHash string) (result db.TransferTxResult, found bool, err error) {
+	record, err := s.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username:       username,
+		IdempotencyKey: idempotencyKey,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return result, false, nil
+		}
+		return result, false, err
+	}
+
+	if record.RequestHash != requestHash {
+		return result, true, ErrIdempotencyKeyReused
+	}
+
+	err = json.Unmarshal(record.Response, &result)
+	return result, true, err
+}
+
+// convert fills in the destination amount and the rate it was converted at.
+func (s *Service) convert(ctx context.Context, arg *db.TransferTxParams, fromCurrency, toCurrency string) error {
+	rate, err := s.rates.GetRate(ctx, fromCurrency, toCurrency)
+	if err != nil {
+		return err
+	}
+
+	toAmount, err := rate.Convert(arg.Amount, s.currencies)
+	if err != nil {
+		return err
+	}
+
+	arg.ToAmount = toAmount
+	arg.ExchangeRate = rate.Value
+	arg.RateTimestamp = rate.Timestamp
+	return nil
+}
+
+func (s *Service) loadAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := s.store.GetAccount(ctx, accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account, fmt.Errorf("%w: %d", ErrAccountNotFound, accountID)
+	}
+	return account, err
+}
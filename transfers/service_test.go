@@ -0,0 +1,160 @@
+package transfers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/exchange"
+	"github.com/leoomi/simplebank/token"
+	"github.com/leoomi/simplebank/util"
+	"github.com/leoomi/simplebank/worker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate(t *testing.T) {
+	owner := util.RandomOwner()
+	payload, err := token.NewPayload(owner, util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	from := db.Account{ID: 1, Owner: owner, Currency: util.USD, Status: util.AccountStatusActive, Kind: db.AccountKindCustomer}
+	to := db.Account{ID: 2, Owner: util.RandomOwner(), Currency: util.EUR, Status: util.AccountStatusActive, Kind: db.AccountKindCustomer}
+	arg := CreateParams{
+		FromAccountID:  from.ID,
+		ToAccountID:    to.ID,
+		Amount:         1000,
+		Currency:       util.USD,
+		IdempotencyKey: "key",
+	}
+	requestHash, err := arg.Hash()
+	require.NoError(t, err)
+
+	stored := db.TransferTxResult{Transfer: db.Transfer{ID: 42}}
+	response, err := json.Marshal(stored)
+	require.NoError(t, err)
+
+	keyParams := db.GetIdempotencyKeyParams{Username: owner, IdempotencyKey: arg.IdempotencyKey}
+
+	testCases := []struct {
+		name       string
+		arg        CreateParams
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, result db.TransferTxResult, err error)
+	}{
+		{
+			name: "Converts",
+			arg:  arg,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(keyParams)).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, txArg db.TransferTxParams) (db.TransferTxResult, error) {
+						require.Equal(t, int64(920), txArg.ToAmount)
+						require.Equal(t, "0.92", txArg.ExchangeRate)
+						require.Equal(t, requestHash, txArg.Idempotency.RequestHash)
+						return db.TransferTxResult{Transfer: db.Transfer{ID: 7}}, nil
+					})
+			},
+			check: func(t *testing.T, result db.TransferTxResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, int64(7), result.Transfer.ID)
+			},
+		},
+		{
+			name: "Replays",
+			arg:  arg,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(keyParams)).
+					Times(1).
+					Return(db.IdempotencyKey{RequestHash: requestHash, Response: response}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result db.TransferTxResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, stored.Transfer.ID, result.Transfer.ID)
+			},
+		},
+		{
+			name: "KeyReused",
+			arg:  arg,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetIdempotencyKey(gomock.Any(), gomock.Eq(keyParams)).
+					Times(1).
+					Return(db.IdempotencyKey{RequestHash: "another-hash", Response: response}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result db.TransferTxResult, err error) {
+				require.ErrorIs(t, err, ErrIdempotencyKeyReused)
+			},
+		},
+		{
+			name: "ConcurrentRequest",
+			arg:  arg,
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(keyParams)).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrIdempotencyKeyInUse),
+					store.EXPECT().
+						GetIdempotencyKey(gomock.Any(), gomock.Eq(keyParams)).
+						Times(1).
+						Return(db.IdempotencyKey{RequestHash: requestHash, Response: response}, nil),
+				)
+			},
+			check: func(t *testing.T, result db.TransferTxResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, stored.Transfer.ID, result.Transfer.ID)
+			},
+		},
+		{
+			name: "SystemAccount",
+			arg:  CreateParams{FromAccountID: from.ID, ToAccountID: 3, Amount: 1000, Currency: util.USD},
+			buildStubs: func(store *mockdb.MockStore) {
+				clearing := db.Account{ID: 3, Owner: db.SystemOwner, Currency: util.USD, Status: util.AccountStatusActive, Kind: db.AccountKindClearing}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(clearing.ID)).Times(1).Return(clearing, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result db.TransferTxResult, err error) {
+				require.ErrorIs(t, err, db.ErrSystemAccount)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			arg:  CreateParams{FromAccountID: from.ID, ToAccountID: to.ID, Amount: 1000, Currency: util.CAD},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, result db.TransferTxResult, err error) {
+				require.ErrorIs(t, err, ErrAccountCurrencyMismatch)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.ID)).AnyTimes().Return(from, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.ID)).AnyTimes().Return(to, nil)
+			tc.buildStubs(store)
+
+			rates := exchange.NewStaticProvider(exchange.Rate{Base: util.USD, Quote: util.EUR, Value: "0.92"})
+			config := util.Config{IdempotencyKeyTTL: time.Hour}
+			service := NewService(config, store, rates, util.NewCurrencyRegistry(util.DefaultCurrencies), worker.NewPGTaskDistributor())
+
+			result, err := service.Create(context.Background(), payload, tc.arg)
+			tc.check(t, result, err)
+		})
+	}
+}
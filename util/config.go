@@ -6,12 +6,42 @@ import (
 	"github.com/spf13/viper"
 )
 
+// DefaultIdempotencyKeyTTL is how long idempotency keys are kept when the
+// config leaves it unset. Keys that expire at once would turn idempotency off.
+const DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+// DefaultVerifyEmailResendInterval is how long a user waits between two
+// verification codes when the config leaves it unset.
+const DefaultVerifyEmailResendInterval = time.Minute
+
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                  string        `mapstructure:"DB_DRIVER"`
+	DBSource                  string        `mapstructure:"DB_SOURCE"`
+	ServerAddress             string        `mapstructure:"SERVER_ADDRESS"`
+	GRPCServerAddress         string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TokenSymmetricKey         string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration       time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration      time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	IdempotencyKeyTTL         time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	RevocationCacheTTL        time.Duration `mapstructure:"REVOCATION_CACHE_TTL"`
+	ExchangeRatesFile         string        `mapstructure:"EXCHANGE_RATES_FILE"`
+	CurrenciesFile            string        `mapstructure:"CURRENCIES_FILE"`
+	SMTPAddress               string        `mapstructure:"SMTP_ADDRESS"`
+	EmailSenderName           string        `mapstructure:"EMAIL_SENDER_NAME"`
+	EmailSenderAddress        string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	EmailSenderPassword       string        `mapstructure:"EMAIL_SENDER_PASSWORD"`
+	VerifyEmailURL            string        `mapstructure:"VERIFY_EMAIL_URL"`
+	VerifyEmailDuration       time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	VerifyEmailResendInterval time.Duration `mapstructure:"VERIFY_EMAIL_RESEND_INTERVAL"`
+	TaskWorkers               int           `mapstructure:"TASK_WORKERS"`
+	TaskPollInterval          time.Duration `mapstructure:"TASK_POLL_INTERVAL"`
+	OutboxFile                string        `mapstructure:"OUTBOX_FILE"`
+	OutboxBatchSize           int32         `mapstructure:"OUTBOX_BATCH_SIZE"`
+	OutboxPollInterval        time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	SchedulerPollInterval     time.Duration `mapstructure:"SCHEDULER_POLL_INTERVAL"`
+	SnapshotPollInterval      time.Duration `mapstructure:"SNAPSHOT_POLL_INTERVAL"`
+	FundingSweepInterval      time.Duration `mapstructure:"FUNDING_SWEEP_INTERVAL"`
+	WebhookAllowInsecure      bool          `mapstructure:"WEBHOOK_ALLOW_INSECURE"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -26,6 +56,16 @@ func LoadConfig(path string) (config Config, err error) {
 		return
 	}
 
-	viper.Unmarshal(&config)
+	err = viper.Unmarshal(&config)
+	if err != nil {
+		return
+	}
+
+	if config.IdempotencyKeyTTL <= 0 {
+		config.IdempotencyKeyTTL = DefaultIdempotencyKeyTTL
+	}
+	if config.VerifyEmailResendInterval <= 0 {
+		config.VerifyEmailResendInterval = DefaultVerifyEmailResendInterval
+	}
 	return
 }
@@ -0,0 +1,20 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigIdempotencyKeyTTL(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "app.env"), []byte("IDEMPOTENCY_KEY_TTL=0s\n"), 0o600)
+	require.NoError(t, err)
+
+	// a TTL of zero would expire every key as soon as it is stored
+	config, err := LoadConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, DefaultIdempotencyKeyTTL, config.IdempotencyKeyTTL)
+}
@@ -1,16 +1,116 @@
 package util
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
 )
 
-func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, CAD:
-		return true
+// Currency describes an ISO 4217 currency. Amounts are always kept in minor
+// units, MinorUnit tells how many of those digits follow the decimal separator.
+type Currency struct {
+	Code        string `json:"code"`
+	NumericCode int32  `json:"numericCode"`
+	MinorUnit   int32  `json:"minorUnit"`
+	Symbol      string `json:"symbol"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// DefaultCurrencies are used when no other source of currencies is configured.
+var DefaultCurrencies = []Currency{
+	{Code: CAD, NumericCode: 124, MinorUnit: 2, Symbol: "CA$", Enabled: true},
+	{Code: EUR, NumericCode: 978, MinorUnit: 2, Symbol: "€", Enabled: true},
+	{Code: USD, NumericCode: 840, MinorUnit: 2, Symbol: "$", Enabled: true},
+}
+
+// FormatAmount renders an amount given in minor units as a decimal string,
+// such as "-12.05" for -1205 cents.
+func (c Currency) FormatAmount(amount int64) string {
+	if c.MinorUnit <= 0 {
+		return strconv.FormatInt(amount, 10)
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+
+	digits := strconv.FormatUint(absInt64(amount), 10)
+	exponent := int(c.MinorUnit)
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	point := len(digits) - exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// CurrencyRegistry holds the currencies the bank knows about. Only enabled
+// currencies can be used for new accounts and transfers, disabled ones are
+// kept so that existing amounts can still be rendered.
+type CurrencyRegistry struct {
+	currencies map[string]Currency
+}
+
+func NewCurrencyRegistry(currencies []Currency) *CurrencyRegistry {
+	registry := &CurrencyRegistry{currencies: make(map[string]Currency, len(currencies))}
+	for _, currency := range currencies {
+		registry.currencies[currency.Code] = currency
+	}
+
+	return registry
+}
+
+// LoadCurrencyRegistry reads a JSON array of currencies from path.
+func LoadCurrencyRegistry(path string) (*CurrencyRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read currencies file: %w", err)
+	}
+
+	var currencies []Currency
+	if err := json.Unmarshal(data, &currencies); err != nil {
+		return nil, fmt.Errorf("cannot parse currencies file: %w", err)
+	}
+
+	return NewCurrencyRegistry(currencies), nil
+}
+
+func (r *CurrencyRegistry) Lookup(code string) (Currency, bool) {
+	currency, ok := r.currencies[code]
+	return currency, ok
+}
+
+func (r *CurrencyRegistry) IsSupported(code string) bool {
+	currency, ok := r.currencies[code]
+	return ok && currency.Enabled
+}
+
+// List returns every currency of the registry ordered by code.
+func (r *CurrencyRegistry) List() []Currency {
+	currencies := make([]Currency, 0, len(r.currencies))
+	for _, currency := range r.currencies {
+		currencies = append(currencies, currency)
 	}
 
-	return false
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+	return currencies
 }
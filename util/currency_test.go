@@ -0,0 +1,78 @@
+package util
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	cents := Currency{Code: USD, MinorUnit: 2}
+	yen := Currency{Code: "JPY", MinorUnit: 0}
+	dinar := Currency{Code: "KWD", MinorUnit: 3}
+
+	testCases := []struct {
+		currency Currency
+		amount   int64
+		expected string
+	}{
+		{cents, 0, "0.00"},
+		{cents, 5, "0.05"},
+		{cents, 1205, "12.05"},
+		{cents, -1205, "-12.05"},
+		{cents, -5, "-0.05"},
+		{cents, math.MinInt64, "-92233720368547758.08"},
+		{yen, 1205, "1205"},
+		{yen, -7, "-7"},
+		{dinar, 1205, "1.205"},
+		{dinar, 12, "0.012"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, tc.currency.FormatAmount(tc.amount), "%s %d", tc.currency.Code, tc.amount)
+	}
+}
+
+func TestCurrencyRegistry(t *testing.T) {
+	registry := NewCurrencyRegistry(append(DefaultCurrencies, Currency{
+		Code:        "JPY",
+		NumericCode: 392,
+		MinorUnit:   0,
+		Symbol:      "¥",
+		Enabled:     false,
+	}))
+
+	require.True(t, registry.IsSupported(USD))
+	require.True(t, registry.IsSupported(EUR))
+	require.True(t, registry.IsSupported(CAD))
+	require.False(t, registry.IsSupported("JPY"))
+	require.False(t, registry.IsSupported("XXX"))
+
+	// disabled currencies can still be looked up to render amounts
+	currency, ok := registry.Lookup("JPY")
+	require.True(t, ok)
+	require.Equal(t, int32(0), currency.MinorUnit)
+
+	codes := []string{}
+	for _, c := range registry.List() {
+		codes = append(codes, c.Code)
+	}
+	require.Equal(t, []string{CAD, EUR, "JPY", USD}, codes)
+}
+
+func TestLoadCurrencyRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "currencies.json")
+	data := `[{"code": "GBP", "numericCode": 826, "minorUnit": 2, "symbol": "£", "enabled": true}]`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	registry, err := LoadCurrencyRegistry(path)
+	require.NoError(t, err)
+	require.True(t, registry.IsSupported("GBP"))
+	require.False(t, registry.IsSupported(USD))
+
+	_, err = LoadCurrencyRegistry(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
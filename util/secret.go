@@ -0,0 +1,17 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewSecretCode returns a random code of 32 bytes, hex encoded, that is safe to
+// hand out as proof of receiving a message.
+func NewSecretCode() (string, error) {
+	code := make([]byte, 32)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(code), nil
+}
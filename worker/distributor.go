@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+)
+
+// TaskDistributor enqueues tasks for the TaskProcessor to run in the
+// background. Tasks are written with the queries it is given, so that a task
+// enqueued inside a store transaction only exists if the transaction commits.
+type TaskDistributor interface {
+	DistributeTaskSendVerifyEmail(ctx context.Context, q db.Querier, payload *PayloadSendVerifyEmail) error
+	DistributeTaskSendTransferNotification(ctx context.Context, q db.Querier, payload *PayloadSendTransferNotification) error
+}
+
+// PGTaskDistributor keeps tasks in the tasks table of the database.
+type PGTaskDistributor struct {
+	maxAttempts int32
+}
+
+func NewPGTaskDistributor() TaskDistributor {
+	return &PGTaskDistributor{maxAttempts: defaultMaxAttempts}
+}
+
+func (d *PGTaskDistributor) distribute(ctx context.Context, q db.Querier, taskType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal payload of %s: %w", taskType, err)
+	}
+
+	_, err = q.CreateTask(ctx, db.CreateTaskParams{
+		Type:        taskType,
+		Payload:     data,
+		MaxAttempts: d.maxAttempts,
+		RunAt:       time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot enqueue %s: %w", taskType, err)
+	}
+
+	return nil
+}
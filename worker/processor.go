@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/mail"
+	"github.com/leoomi/simplebank/util"
+)
+
+const (
+	defaultMaxAttempts  = 10
+	defaultPollInterval = time.Second
+	// how long a worker may run a task before another one claims it again
+	taskLease = 5 * time.Minute
+	// failed tasks are retried after retryBaseDelay, doubling with every attempt
+	// up to maxRetryDelay
+	retryBaseDelay = 10 * time.Second
+	maxRetryDelay  = time.Hour
+)
+
+// errSkipRetry marks errors that won't go away by running the task again, such
+// as a payload that can't be decoded. Tasks failing with it are given up on.
+var errSkipRetry = errors.New("task cannot succeed")
+
+type taskHandler func(ctx context.Context, task db.Task) error
+
+// TaskProcessor runs the tasks enqueued by a TaskDistributor.
+type TaskProcessor struct {
+	config     util.Config
+	store      db.Store
+	mailer     mail.Mailer
+	currencies *util.CurrencyRegistry
+	httpClient *http.Client
+	handlers   map[string]taskHandler
+}
+
+func NewTaskProcessor(config util.Config, store db.Store) (*TaskProcessor, error) {
+	processor := &TaskProcessor{
+		config:     config,
+		store:      store,
+		mailer:     mail.NewSMTPMailer(config.SMTPAddress, config.EmailSenderName, config.EmailSenderAddress, config.EmailSenderPassword),
+		httpClient: newWebhookClient(config.WebhookAllowInsecure),
+	}
+
+	var err error
+	if len(config.CurrenciesFile) > 0 {
+		processor.currencies, err = util.LoadCurrencyRegistry(config.CurrenciesFile)
+	} else {
+		processor.currencies, err = db.LoadCurrencyRegistry(context.Background(), store)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	processor.handlers = map[string]taskHandler{
+		TaskSendVerifyEmail:          processor.processTaskSendVerifyEmail,
+		TaskSendTransferNotification: processor.processTaskSendTransferNotification,
+		TaskDeliverWebhook:           processor.processTaskDeliverWebhook,
+	}
+
+	return processor, nil
+}
+
+// Start runs the workers until ctx is done. Each worker keeps claiming due
+// tasks, and waits for the poll interval whenever there are none.
+func (p *TaskProcessor) Start(ctx context.Context) {
+	workers := p.config.TaskWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *TaskProcessor) work(ctx context.Context) {
+	pollInterval := p.config.TaskPollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	for ctx.Err() == nil {
+		processed, err := p.processNext(ctx)
+		if err != nil {
+			log.Printf("cannot process task: %s", err)
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// processNext claims the next due task and runs it. It reports whether there
+// was a task to run.
+func (p *TaskProcessor) processNext(ctx context.Context) (bool, error) {
+	task, err := p.store.ClaimTask(ctx, time.Now().Add(taskLease))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot claim task: %w", err)
+	}
+
+	err = p.run(ctx, task)
+	if err == nil {
+		return true, p.store.CompleteTask(ctx, task.ID)
+	}
+
+	if errors.Is(err, errSkipRetry) || task.Attempts >= task.MaxAttempts {
+		log.Printf("giving up on task %d (%s) after %d attempts: %s", task.ID, task.Type, task.Attempts, err)
+		_, err = p.store.FailTask(ctx, db.FailTaskParams{
+			ID:        task.ID,
+			LastError: err.Error(),
+		})
+		return true, err
+	}
+
+	_, err = p.store.RetryTask(ctx, db.RetryTaskParams{
+		ID:        task.ID,
+		RunAt:     time.Now().Add(retryDelay(task.Attempts)),
+		LastError: err.Error(),
+	})
+	return true, err
+}
+
+func (p *TaskProcessor) run(ctx context.Context, task db.Task) error {
+	// a worker died while running it every time it was claimed
+	if task.Attempts > task.MaxAttempts {
+		return fmt.Errorf("%w: lease ran out %d times", errSkipRetry, task.Attempts-1)
+	}
+
+	handler, ok := p.handlers[task.Type]
+	if !ok {
+		return fmt.Errorf("%w: unknown task type %s", errSkipRetry, task.Type)
+	}
+
+	return handler(ctx, task)
+}
+
+// retryDelay is how long to wait before running a task again after it failed
+// for the given number of attempts.
+func retryDelay(attempts int32) time.Duration {
+	delay := retryBaseDelay
+	for i := int32(1); i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
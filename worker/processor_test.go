@@ -0,0 +1,439 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/mail"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProcessor(t *testing.T, store *mockdb.MockStore, mailer mail.Mailer) *TaskProcessor {
+	// webhooks are delivered to test servers on the loopback address
+	config := util.Config{
+		VerifyEmailURL:       "http://localhost:8080/users/verify_email",
+		WebhookAllowInsecure: true,
+	}
+
+	store.EXPECT().
+		ListCurrencies(gomock.Any()).
+		AnyTimes().
+		Return([]db.Currency{{Code: util.USD, NumericCode: 840, MinorUnit: 2, Symbol: "$", Enabled: true}}, nil)
+
+	processor, err := NewTaskProcessor(config, store)
+	require.NoError(t, err)
+	processor.mailer = mailer
+
+	return processor
+}
+
+func newTask(t *testing.T, taskType string, payload interface{}) db.Task {
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	return db.Task{
+		ID:          util.RandomInt(1, 1000),
+		Type:        taskType,
+		Payload:     data,
+		Status:      "running",
+		Attempts:    1,
+		MaxAttempts: defaultMaxAttempts,
+	}
+}
+
+func TestProcessNext(t *testing.T) {
+	user := db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+	verifyEmailTask := newTask(t, TaskSendVerifyEmail, PayloadSendVerifyEmail{
+		Username:   user.Username,
+		EmailID:    42,
+		SecretCode: util.RandomString(64),
+	})
+
+	testCases := []struct {
+		name          string
+		mailErr       error
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer)
+	}{
+		{
+			name: "NoTask",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ClaimTask(gomock.Any(), gomock.Any()).Times(1).Return(db.Task{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.NoError(t, err)
+				require.False(t, processed)
+			},
+		},
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ClaimTask(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, leaseExpiresAt time.Time) (db.Task, error) {
+						require.WithinDuration(t, time.Now().Add(taskLease), leaseExpiresAt, time.Second)
+						return verifyEmailTask, nil
+					})
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CompleteTask(gomock.Any(), gomock.Eq(verifyEmailTask.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.NoError(t, err)
+				require.True(t, processed)
+
+				sent := mailer.Sent()
+				require.Len(t, sent, 1)
+				require.Equal(t, []string{user.Email}, sent[0].To)
+				require.Contains(t, sent[0].Content, "/users/verify_email?email_id=42")
+			},
+		},
+		{
+			name:    "Retry",
+			mailErr: errors.New("connection refused"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ClaimTask(gomock.Any(), gomock.Any()).Times(1).Return(verifyEmailTask, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CompleteTask(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					RetryTask(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RetryTaskParams) (db.Task, error) {
+						require.Equal(t, verifyEmailTask.ID, arg.ID)
+						require.WithinDuration(t, time.Now().Add(retryBaseDelay), arg.RunAt, time.Second)
+						require.Equal(t, "connection refused", arg.LastError)
+						return db.Task{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.NoError(t, err)
+				require.True(t, processed)
+			},
+		},
+		{
+			name:    "LastAttempt",
+			mailErr: errors.New("connection refused"),
+			buildStubs: func(store *mockdb.MockStore) {
+				task := verifyEmailTask
+				task.Attempts = task.MaxAttempts
+				store.EXPECT().ClaimTask(gomock.Any(), gomock.Any()).Times(1).Return(task, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().RetryTask(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					FailTask(gomock.Any(), gomock.Eq(db.FailTaskParams{ID: task.ID, LastError: "connection refused"})).
+					Times(1).
+					Return(db.Task{}, nil)
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.NoError(t, err)
+				require.True(t, processed)
+			},
+		},
+		{
+			name: "LeaseRanOut",
+			buildStubs: func(store *mockdb.MockStore) {
+				task := verifyEmailTask
+				task.Attempts = task.MaxAttempts + 1
+				store.EXPECT().ClaimTask(gomock.Any(), gomock.Any()).Times(1).Return(task, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().FailTask(gomock.Any(), gomock.Any()).Times(1).Return(db.Task{}, nil)
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.NoError(t, err)
+				require.Empty(t, mailer.Sent())
+			},
+		},
+		{
+			name: "UnknownType",
+			buildStubs: func(store *mockdb.MockStore) {
+				task := newTask(t, "task:unknown", struct{}{})
+				store.EXPECT().ClaimTask(gomock.Any(), gomock.Any()).Times(1).Return(task, nil)
+				store.EXPECT().RetryTask(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().FailTask(gomock.Any(), gomock.Any()).Times(1).Return(db.Task{}, nil)
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.NoError(t, err)
+				require.True(t, processed)
+			},
+		},
+		{
+			name: "UserNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ClaimTask(gomock.Any(), gomock.Any()).Times(1).Return(verifyEmailTask, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().RetryTask(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().FailTask(gomock.Any(), gomock.Any()).Times(1).Return(db.Task{}, nil)
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.NoError(t, err)
+				require.Empty(t, mailer.Sent())
+			},
+		},
+		{
+			name: "ClaimError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ClaimTask(gomock.Any(), gomock.Any()).Times(1).Return(db.Task{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, processed bool, err error, mailer *mail.FakeMailer) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.False(t, processed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			mailer := mail.NewFakeMailer()
+			mailer.Err = tc.mailErr
+			processor := newTestProcessor(t, store, mailer)
+
+			processed, err := processor.processNext(context.Background())
+			tc.checkResponse(t, processed, err, mailer)
+		})
+	}
+}
+
+func TestProcessTaskSendTransferNotification(t *testing.T) {
+	user := db.User{
+		Username:        util.RandomOwner(),
+		FullName:        util.RandomOwner(),
+		Email:           util.RandomEmail(),
+		IsEmailVerified: true,
+	}
+	account := db.Account{ID: 7, Owner: user.Username, Currency: util.USD}
+	transfer := db.Transfer{ID: 3, FromAccountID: 5, ToAccountID: account.ID, Amount: 1250, ToAmount: 1250}
+	task := newTask(t, TaskSendTransferNotification, PayloadSendTransferNotification{TransferID: transfer.ID})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	mailer := mail.NewFakeMailer()
+	processor := newTestProcessor(t, store, mailer)
+
+	err := processor.run(context.Background(), task)
+	require.NoError(t, err)
+
+	sent := mailer.Sent()
+	require.Len(t, sent, 1)
+	require.Equal(t, []string{user.Email}, sent[0].To)
+	require.Contains(t, sent[0].Content, "12.50 USD was transferred to your account #7")
+
+	// owners who haven't verified their email address aren't told
+	user.IsEmailVerified = false
+	store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	err = processor.run(context.Background(), task)
+	require.NoError(t, err)
+	require.Len(t, mailer.Sent(), 1)
+}
+
+func TestProcessTaskDeliverWebhook(t *testing.T) {
+	webhook := db.Webhook{
+		ID:         4,
+		Owner:      util.RandomOwner(),
+		Secret:     util.RandomString(32),
+		EventTypes: []string{db.EventTransferCreated},
+	}
+	delivery := db.WebhookDelivery{
+		ID:        11,
+		WebhookID: webhook.ID,
+		EventType: db.EventTransferCreated,
+		Payload:   json.RawMessage(`{"type":"transfer.created","data":{"id":3}}`),
+		Status:    db.WebhookDeliveryPending,
+	}
+	task := newTask(t, TaskDeliverWebhook, db.PayloadDeliverWebhook{DeliveryID: delivery.ID})
+
+	var requests, responseCode int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, string(delivery.Payload), string(body))
+		require.Equal(t, delivery.EventType, r.Header.Get(WebhookEventHeader))
+		require.Equal(t, strconv.FormatInt(delivery.ID, 10), r.Header.Get(WebhookDeliveryHeader))
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		require.NoError(t, err)
+		require.Equal(t, SignWebhook(webhook.Secret, timestamp, body), r.Header.Get(WebhookSignatureHeader))
+
+		w.WriteHeader(responseCode)
+	}))
+	defer server.Close()
+	webhook.Url = server.URL
+
+	testCases := []struct {
+		name          string
+		responseCode  int
+		attempts      int32
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, err error, requests int)
+	}{
+		{
+			name:         "OK",
+			responseCode: http.StatusNoContent,
+			attempts:     1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().
+					CreateWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateWebhookDeliveryAttemptParams) (db.WebhookDeliveryAttempt, error) {
+						require.Equal(t, delivery.ID, arg.DeliveryID)
+						require.Equal(t, int32(http.StatusNoContent), arg.ResponseStatus)
+						require.Empty(t, arg.Error)
+						return db.WebhookDeliveryAttempt{}, nil
+					})
+				store.EXPECT().
+					UpdateWebhookDeliveryStatus(gomock.Any(), gomock.Eq(db.UpdateWebhookDeliveryStatusParams{
+						ID:     delivery.ID,
+						Status: db.WebhookDeliverySucceeded,
+					})).
+					Times(1).
+					Return(db.WebhookDelivery{}, nil)
+			},
+			checkResponse: func(t *testing.T, err error, requests int) {
+				require.NoError(t, err)
+				require.Equal(t, 1, requests)
+			},
+		},
+		{
+			name:         "Retry",
+			responseCode: http.StatusServiceUnavailable,
+			attempts:     1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().
+					CreateWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateWebhookDeliveryAttemptParams) (db.WebhookDeliveryAttempt, error) {
+						require.Equal(t, int32(http.StatusServiceUnavailable), arg.ResponseStatus)
+						require.Equal(t, "webhook answered with status 503", arg.Error)
+						return db.WebhookDeliveryAttempt{}, nil
+					})
+				store.EXPECT().UpdateWebhookDeliveryStatus(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, err error, requests int) {
+				require.EqualError(t, err, "webhook answered with status 503")
+				require.NotErrorIs(t, err, errSkipRetry)
+			},
+		},
+		{
+			name:         "LastAttempt",
+			responseCode: http.StatusInternalServerError,
+			attempts:     defaultMaxAttempts,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).Times(1).Return(webhook, nil)
+				store.EXPECT().CreateWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.WebhookDeliveryAttempt{}, nil)
+				store.EXPECT().
+					UpdateWebhookDeliveryStatus(gomock.Any(), gomock.Eq(db.UpdateWebhookDeliveryStatusParams{
+						ID:     delivery.ID,
+						Status: db.WebhookDeliveryFailed,
+					})).
+					Times(1).
+					Return(db.WebhookDelivery{}, nil)
+			},
+			checkResponse: func(t *testing.T, err error, requests int) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:     "AlreadySucceeded",
+			attempts: 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				succeeded := delivery
+				succeeded.Status = db.WebhookDeliverySucceeded
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(succeeded, nil)
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateWebhookDeliveryAttempt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, err error, requests int) {
+				require.NoError(t, err)
+				require.Zero(t, requests)
+			},
+		},
+		{
+			name:     "DeliveryNotFound",
+			attempts: 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(db.WebhookDelivery{}, sql.ErrNoRows)
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, err error, requests int) {
+				require.NoError(t, err)
+				require.Zero(t, requests)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			processor := newTestProcessor(t, store, mail.NewFakeMailer())
+
+			requests, responseCode = 0, tc.responseCode
+			deliverTask := task
+			deliverTask.Attempts = tc.attempts
+			err := processor.run(context.Background(), deliverTask)
+			tc.checkResponse(t, err, requests)
+		})
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+
+	signature := SignWebhook("secret", 1700000000, body)
+	require.Equal(t, "sha256=086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54", signature)
+	require.NotEqual(t, signature, SignWebhook("secret", 1700000001, body))
+	require.NotEqual(t, signature, SignWebhook("other", 1700000000, body))
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, retryBaseDelay, retryDelay(1))
+	require.Equal(t, 2*retryBaseDelay, retryDelay(2))
+	require.Equal(t, 4*retryBaseDelay, retryDelay(3))
+	require.Equal(t, maxRetryDelay, retryDelay(20))
+	require.Equal(t, maxRetryDelay, retryDelay(1000))
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+)
+
+const (
+	defaultSnapshotPollInterval = time.Hour
+	// days are only snapshotted this long after they closed, so that
+	// transactions that started before midnight have committed by then
+	snapshotGrace = 5 * time.Minute
+)
+
+// Snapshotter writes the closing balance of every account at the end of each
+// day in UTC, so that past balances don't need every entry ever written added
+// up. Days missed while it wasn't running are caught up in order. Snapshots
+// are only ever written once, so several snapshotters can poll the same
+// database.
+type Snapshotter struct {
+	store        db.Store
+	pollInterval time.Duration
+}
+
+func NewSnapshotter(config util.Config, store db.Store) *Snapshotter {
+	snapshotter := &Snapshotter{
+		store:        store,
+		pollInterval: config.SnapshotPollInterval,
+	}
+
+	if snapshotter.pollInterval <= 0 {
+		snapshotter.pollInterval = defaultSnapshotPollInterval
+	}
+
+	return snapshotter
+}
+
+// Start snapshots the days that have closed until ctx is done, checking for
+// them every poll interval.
+func (s *Snapshotter) Start(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := s.snapshotDue(ctx, time.Now()); err != nil {
+			log.Printf("cannot snapshot balances: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// snapshotDue snapshots every day that closed by now and hasn't been
+// snapshotted yet. The first run only snapshots the day before.
+func (s *Snapshotter) snapshotDue(ctx context.Context, now time.Time) error {
+	lastDay := startOfDay(now.UTC().Add(-snapshotGrace)).AddDate(0, 0, -1)
+
+	day := lastDay
+	latest, err := s.store.GetLatestBalanceSnapshotDay(ctx)
+	switch {
+	case err == nil:
+		day = startOfDay(latest).AddDate(0, 0, 1)
+	case err != sql.ErrNoRows:
+		return fmt.Errorf("cannot get latest snapshot: %w", err)
+	}
+
+	for ; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		snapshots, err := s.store.CreateBalanceSnapshots(ctx, db.CreateBalanceSnapshotsParams{
+			Day:       day,
+			ClosingAt: day.AddDate(0, 0, 1),
+		})
+		if err != nil {
+			return fmt.Errorf("cannot snapshot %s: %w", day.Format(time.DateOnly), err)
+		}
+
+		log.Printf("snapshotted %d balances for %s", snapshots, day.Format(time.DateOnly))
+	}
+
+	return nil
+}
+
+// startOfDay is midnight at the start of the day of t, in UTC.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/leoomi/simplebank/db/mock"
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotDue(t *testing.T) {
+	now := time.Date(2024, time.March, 14, 9, 30, 0, 0, time.UTC)
+	march := func(day int) time.Time {
+		return time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	// expectSnapshots stubs CreateBalanceSnapshots to be called for days, in
+	// order
+	expectSnapshots := func(store *mockdb.MockStore, days ...time.Time) {
+		calls := make([]*gomock.Call, len(days))
+		for i, day := range days {
+			calls[i] = store.EXPECT().
+				CreateBalanceSnapshots(gomock.Any(), gomock.Eq(db.CreateBalanceSnapshotsParams{
+					Day:       day,
+					ClosingAt: day.AddDate(0, 0, 1),
+				})).
+				Times(1).
+				Return(int64(3), nil)
+		}
+		gomock.InOrder(calls...)
+	}
+
+	testCases := []struct {
+		name       string
+		now        time.Time
+		buildStubs func(store *mockdb.MockStore)
+		check      func(t *testing.T, err error)
+	}{
+		{
+			name: "FirstRun",
+			now:  now,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotDay(gomock.Any()).Times(1).Return(time.Time{}, sql.ErrNoRows)
+				expectSnapshots(store, march(13))
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "CatchUp",
+			now:  now,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotDay(gomock.Any()).Times(1).Return(march(10), nil)
+				expectSnapshots(store, march(11), march(12), march(13))
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "UpToDate",
+			now:  now,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotDay(gomock.Any()).Times(1).Return(march(13), nil)
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			// the day that just closed waits for the grace period to pass
+			name: "WithinGrace",
+			now:  march(14).Add(time.Minute),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotDay(gomock.Any()).Times(1).Return(march(12), nil)
+				store.EXPECT().CreateBalanceSnapshots(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "DatabaseDown",
+			now:  now,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLatestBalanceSnapshotDay(gomock.Any()).Times(1).Return(march(11), nil)
+				store.EXPECT().
+					CreateBalanceSnapshots(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			check: func(t *testing.T, err error) {
+				require.True(t, errors.Is(err, sql.ErrConnDone))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			snapshotter := NewSnapshotter(util.Config{}, store)
+			err := snapshotter.snapshotDue(context.Background(), tc.now)
+			tc.check(t, err)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+)
+
+const TaskDeliverWebhook = db.TaskDeliverWebhook
+
+// webhookTimeout bounds how long a receiver may take to answer a delivery.
+const webhookTimeout = 10 * time.Second
+
+// Headers sent with every delivery. The signature covers the timestamp and the
+// body, so that receivers can reject both forged and replayed requests.
+const (
+	WebhookEventHeader     = "Webhook-Event"
+	WebhookDeliveryHeader  = "Webhook-Delivery"
+	WebhookTimestampHeader = "Webhook-Timestamp"
+	WebhookSignatureHeader = "Webhook-Signature"
+)
+
+// SignWebhook computes the signature of a delivery: the hex encoded
+// HMAC-SHA256, keyed with the secret of the webhook, of the timestamp in Unix
+// seconds, a dot and the body.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// processTaskDeliverWebhook posts a delivery to its webhook and records the
+// attempt. Any response other than 2xx fails the task, so that the queue retries
+// it with backoff; the delivery is marked as failed once it runs out of
+// attempts.
+func (p *TaskProcessor) processTaskDeliverWebhook(ctx context.Context, task db.Task) error {
+	var payload db.PayloadDeliverWebhook
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: cannot unmarshal payload: %s", errSkipRetry, err)
+	}
+
+	delivery, err := p.store.GetWebhookDelivery(ctx, payload.DeliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// the webhook was deleted along with its deliveries
+			return nil
+		}
+		return fmt.Errorf("cannot get delivery: %w", err)
+	}
+
+	if delivery.Status == db.WebhookDeliverySucceeded {
+		return nil
+	}
+
+	webhook, err := p.store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("cannot get webhook: %w", err)
+	}
+
+	start := time.Now()
+	responseStatus, deliveryErr := p.postWebhook(ctx, webhook, delivery)
+
+	attempt := db.CreateWebhookDeliveryAttemptParams{
+		DeliveryID:     delivery.ID,
+		ResponseStatus: int32(responseStatus),
+		DurationMs:     time.Since(start).Milliseconds(),
+	}
+	if deliveryErr != nil {
+		attempt.Error = deliveryErr.Error()
+	}
+	if _, err := p.store.CreateWebhookDeliveryAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("cannot record attempt: %w", err)
+	}
+
+	status := db.WebhookDeliverySucceeded
+	if deliveryErr != nil {
+		if task.Attempts < task.MaxAttempts {
+			return deliveryErr
+		}
+		status = db.WebhookDeliveryFailed
+	}
+
+	_, err = p.store.UpdateWebhookDeliveryStatus(ctx, db.UpdateWebhookDeliveryStatusParams{
+		ID:     delivery.ID,
+		Status: status,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot update delivery: %w", err)
+	}
+
+	return deliveryErr
+}
+
+// postWebhook sends the payload of the delivery to the URL of the webhook. It
+// returns the status of the response, or zero if there was none. Redirects
+// count as failures.
+func (p *TaskProcessor) postWebhook(ctx context.Context, webhook db.Webhook, delivery db.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		log.Printf("cannot create request for delivery %d: %s", delivery.ID, err)
+		return 0, errWebhookUnreachable
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		// the error is recorded for the owner of the webhook to read, so the
+		// details only go to the log
+		log.Printf("cannot post delivery %d: %s", delivery.ID, err)
+		return 0, webhookError(err)
+	}
+	defer res.Body.Close()
+
+	// drain a bit of the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("webhook answered with status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/mail"
+)
+
+const TaskSendTransferNotification = "task:send_transfer_notification"
+
+type PayloadSendTransferNotification struct {
+	TransferID int64 `json:"transferID"`
+}
+
+func (d *PGTaskDistributor) DistributeTaskSendTransferNotification(ctx context.Context, q db.Querier, payload *PayloadSendTransferNotification) error {
+	return d.distribute(ctx, q, TaskSendTransferNotification, payload)
+}
+
+// processTaskSendTransferNotification tells the owner of the destination
+// account of a transfer that they received money. Owners whose email address
+// isn't verified are not told.
+func (p *TaskProcessor) processTaskSendTransferNotification(ctx context.Context, task db.Task) error {
+	var payload PayloadSendTransferNotification
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: cannot unmarshal payload: %s", errSkipRetry, err)
+	}
+
+	transfer, err := p.store.GetTransfer(ctx, payload.TransferID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: transfer %d doesn't exist", errSkipRetry, payload.TransferID)
+		}
+		return fmt.Errorf("cannot get transfer: %w", err)
+	}
+
+	account, err := p.store.GetAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		return fmt.Errorf("cannot get account: %w", err)
+	}
+
+	user, err := p.store.GetUser(ctx, account.Owner)
+	if err != nil {
+		return fmt.Errorf("cannot get user: %w", err)
+	}
+
+	if !user.IsEmailVerified {
+		return nil
+	}
+
+	amount := strconv.FormatInt(transfer.ToAmount, 10)
+	if currency, ok := p.currencies.Lookup(account.Currency); ok {
+		amount = currency.FormatAmount(transfer.ToAmount)
+	}
+
+	return mail.SendTransferNotification(p.mailer, user.FullName, user.Email, amount+" "+account.Currency, account.ID)
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	db "github.com/leoomi/simplebank/db/sqlc"
+	"github.com/leoomi/simplebank/mail"
+)
+
+const TaskSendVerifyEmail = "task:send_verify_email"
+
+type PayloadSendVerifyEmail struct {
+	Username   string `json:"username"`
+	EmailID    int64  `json:"emailID"`
+	SecretCode string `json:"secretCode"`
+}
+
+func (d *PGTaskDistributor) DistributeTaskSendVerifyEmail(ctx context.Context, q db.Querier, payload *PayloadSendVerifyEmail) error {
+	return d.distribute(ctx, q, TaskSendVerifyEmail, payload)
+}
+
+// processTaskSendVerifyEmail sends a new user the link that verifies their
+// email address.
+func (p *TaskProcessor) processTaskSendVerifyEmail(ctx context.Context, task db.Task) error {
+	var payload PayloadSendVerifyEmail
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("%w: cannot unmarshal payload: %s", errSkipRetry, err)
+	}
+
+	user, err := p.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: user %s doesn't exist", errSkipRetry, payload.Username)
+		}
+		return fmt.Errorf("cannot get user: %w", err)
+	}
+
+	if user.IsEmailVerified {
+		return nil
+	}
+
+	link := mail.VerifyEmailLink(p.config.VerifyEmailURL, payload.EmailID, payload.SecretCode)
+	return mail.SendVerifyEmail(p.mailer, user.FullName, user.Email, link)
+}
@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWebhookInsecure = errors.New("webhook URL must use https")
+	ErrWebhookAddress  = errors.New("webhook address is not allowed")
+)
+
+// Errors recorded for deliveries that got no response. Owners of webhooks can
+// read them, so they never carry the error of the dial itself, which could tell
+// them about the network the worker runs in.
+var (
+	errWebhookTimeout     = errors.New("webhook did not answer in time")
+	errWebhookUnreachable = errors.New("cannot reach webhook")
+)
+
+// ValidateWebhookURL checks a webhook URL before it is stored. It must use
+// https and must not name a host that is known to be internal, unless
+// allowInsecure is set for local development. Hosts are checked again on
+// every delivery once they are resolved, since their addresses may change.
+func ValidateWebhookURL(rawURL string, allowInsecure bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if allowInsecure {
+		return nil
+	}
+
+	if u.Scheme != "https" {
+		return ErrWebhookInsecure
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return ErrWebhookAddress
+	}
+
+	return nil
+}
+
+// reservedNetworks are the special purpose ranges that net.IP doesn't tell
+// apart but that can still lead into the network of the worker. IPv4 ranges
+// also match the IPv4-mapped forms of their addresses.
+var reservedNetworks = parseCIDRs(
+	"0.0.0.0/8",     // this network
+	"100.64.0.0/10", // shared address space of carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"::/96",         // IPv4-compatible IPv6
+	"64:ff9b::/96",  // NAT64, which reaches any IPv4 address
+	"64:ff9b:1::/48",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
+
+// isPublicIP tells whether an address may be reached by webhooks, which must
+// not be used to reach the worker itself or the network it runs in.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// newWebhookClient creates the client that posts deliveries. Unless
+// allowInsecure is set, it only connects to public addresses, checking the ones
+// the host resolves to right before dialing them so that a host can't resolve
+// to a public address when the webhook is created and to an internal one when
+// it is delivered to. Redirects are never followed, since they could lead
+// anywhere, and proxies from the environment are ignored for the same reason.
+func newWebhookClient(allowInsecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   webhookTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	if !allowInsecure {
+		transport.DialContext = publicDialContext(dialer)
+	}
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicDialContext resolves the host itself and dials one of its addresses,
+// provided all of them are public.
+func publicDialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no addresses found for %s", host)
+		}
+
+		for _, addr := range addrs {
+			if !isPublicIP(addr.IP) {
+				return nil, fmt.Errorf("%w: %s", ErrWebhookAddress, addr.IP)
+			}
+		}
+
+		for _, addr := range addrs {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// webhookError turns the error of a delivery that got no response into one
+// that may be shown to the owner of the webhook.
+func webhookError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrWebhookAddress):
+		return ErrWebhookAddress
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errWebhookTimeout
+	}
+	return errWebhookUnreachable
+}
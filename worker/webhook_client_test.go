@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	testCases := []struct {
+		name          string
+		url           string
+		allowInsecure bool
+		err           error
+	}{
+		{name: "OK", url: "https://example.com/hooks"},
+		{name: "PlainHTTP", url: "http://example.com/hooks", err: ErrWebhookInsecure},
+		{name: "Localhost", url: "https://localhost:8080/hooks", err: ErrWebhookAddress},
+		{name: "Loopback", url: "https://127.0.0.1/hooks", err: ErrWebhookAddress},
+		{name: "LoopbackIPv6", url: "https://[::1]/hooks", err: ErrWebhookAddress},
+		{name: "Private", url: "https://10.0.0.7/hooks", err: ErrWebhookAddress},
+		{name: "LinkLocal", url: "https://169.254.169.254/latest/meta-data", err: ErrWebhookAddress},
+		{name: "Unspecified", url: "https://0.0.0.0/hooks", err: ErrWebhookAddress},
+		{name: "MappedIPv4", url: "https://[::ffff:192.168.1.1]/hooks", err: ErrWebhookAddress},
+		{name: "SharedAddressSpace", url: "https://100.100.100.200/latest/meta-data", err: ErrWebhookAddress},
+		{name: "NAT64", url: "https://[64:ff9b::a9fe:a9fe]/latest/meta-data", err: ErrWebhookAddress},
+		{name: "AllowInsecure", url: "http://localhost:8080/hooks", allowInsecure: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateWebhookURL(tc.url, tc.allowInsecure)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	testCases := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "93.184.216.34", public: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", public: true},
+		{ip: "::ffff:93.184.216.34", public: true},
+		{ip: "127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "0.1.2.3"},
+		{ip: "100.64.0.1"},
+		{ip: "100.127.255.254"},
+		{ip: "192.0.0.8"},
+		{ip: "198.18.0.1"},
+		{ip: "198.19.255.254"},
+		{ip: "::ffff:100.64.0.1"},
+		{ip: "::ffff:198.18.0.1"},
+		{ip: "::ffff:0.1.2.3"},
+		{ip: "::10.1.2.3"},
+		{ip: "64:ff9b::a01:203"},
+		{ip: "64:ff9b::93.184.216.34"},
+		{ip: "64:ff9b:1::a01:203"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.ip, func(t *testing.T) {
+			ip := net.ParseIP(tc.ip)
+			require.NotNil(t, ip)
+			require.Equal(t, tc.public, isPublicIP(ip))
+		})
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+
+	post := func(client *http.Client) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, nil)
+		require.NoError(t, err)
+		return client.Do(req)
+	}
+
+	// the test server listens on the loopback address, which is refused once
+	// it is resolved, whatever name it was reached by
+	_, err := post(newWebhookClient(false))
+	require.ErrorIs(t, err, ErrWebhookAddress)
+	require.Equal(t, ErrWebhookAddress, webhookError(err))
+	require.Zero(t, requests)
+
+	// redirects are returned instead of followed
+	res, err := post(newWebhookClient(true))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+	require.Equal(t, 1, requests)
+}